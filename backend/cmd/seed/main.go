@@ -173,6 +173,10 @@ func seedTrips(ctx context.Context, repo domain.TripRepository, riders []*domain
 			if status != domain.TripStatusRequested {
 				trip.DriverID = &driver.ID
 			}
+			if status == domain.TripStatusCompleted {
+				// Seeded rides are treated as already paid for.
+				trip.FareChargedAt = &trip.UpdatedAt
+			}
 			if err := repo.CreateTrip(trip); err != nil {
 				return err
 			}
@@ -67,12 +67,19 @@ func (c *TripClient) GetTrip(id string) (*domain.Trip, error) {
 	return &trip, nil
 }
 
-// UpdateTripStatus forwards the status change to the trip-service.
-func (c *TripClient) UpdateTripStatus(id string, status domain.TripStatus) error {
+// TransitionTrip asks the trip-service to apply a lifecycle transition on
+// behalf of the actor recorded in the change.
+func (c *TripClient) TransitionTrip(id string, change domain.TripStatusChange) error {
 	if c.baseURL == "" {
 		return errors.New("trip service url not configured")
 	}
-	body, _ := json.Marshal(map[string]string{"status": string(status)})
+	body, _ := json.Marshal(map[string]string{
+		"status":  string(change.To),
+		"from":    string(change.From),
+		"actor":   string(change.Actor),
+		"actorId": change.ActorID,
+		"reason":  change.Reason,
+	})
 	endpoint := fmt.Sprintf("%s/internal/trips/%s/status", c.baseURL, id)
 	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
@@ -87,8 +94,49 @@ func (c *TripClient) UpdateTripStatus(id string, status domain.TripStatus) error
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
 		return domain.ErrTripNotFound
+	case http.StatusConflict:
+		return domain.ErrInvalidTransition
+	case http.StatusForbidden:
+		return domain.ErrTransitionNotPermitted
+	}
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("trip service error: %s", strings.TrimSpace(string(payload)))
+	}
+	return nil
+}
+
+// MarkTripStopReached asks the trip-service to record arrival at a stop. The
+// trip-service also pushes the arrival to the trip's WebSocket subscribers.
+func (c *TripClient) MarkTripStopReached(id string, index int, at time.Time) error {
+	if c.baseURL == "" {
+		return errors.New("trip service url not configured")
+	}
+	body, _ := json.Marshal(map[string]time.Time{"arrivedAt": at})
+	endpoint := fmt.Sprintf("%s/internal/trips/%s/stops/%d/arrive", c.baseURL, id, index)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.applyHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The driver service checks trip, order and ride state first, so these
+	// only surface races with a concurrent update.
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return domain.ErrStopNotFound
+	case http.StatusConflict:
+		return domain.ErrStopAlreadyReached
 	}
 	if resp.StatusCode >= 300 {
 		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
@@ -128,6 +176,69 @@ func (c *TripClient) SetTripDriver(id string, driverID *string) error {
 	return nil
 }
 
+// SaveTripProgress records lifecycle timestamps and fares on the trip-service.
+func (c *TripClient) SaveTripProgress(id string, progress domain.TripProgress) error {
+	if c.baseURL == "" {
+		return errors.New("trip service url not configured")
+	}
+	body, _ := json.Marshal(progress)
+	endpoint := fmt.Sprintf("%s/internal/trips/%s/progress", c.baseURL, id)
+	req, err := http.NewRequest(http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.applyHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return domain.ErrTripNotFound
+	}
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("trip service error: %s", strings.TrimSpace(string(payload)))
+	}
+	return nil
+}
+
+// AppendTripEvent records a driver-side event on the trip history.
+func (c *TripClient) AppendTripEvent(event domain.TripEvent) error {
+	if c.baseURL == "" {
+		return errors.New("trip service url not configured")
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/internal/trips/%s/events", c.baseURL, event.TripID)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.applyHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return domain.ErrTripNotFound
+	}
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("trip service error: %s", strings.TrimSpace(string(payload)))
+	}
+	return nil
+}
+
 func (c *TripClient) applyHeaders(req *http.Request) {
 	if c.apiKey != "" {
 		req.Header.Set("X-Internal-Token", c.apiKey)
@@ -86,14 +86,28 @@ func (h *DriverTripHandler) updateStatus(c *gin.Context) {
 	}
 	tripID := c.Param("id")
 	var req struct {
-		Status string `json:"status" binding:"required"`
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+		Stop   *int   `json:"stop"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Stop != nil {
+		if _, err := h.driverService.ReachStop(c.Request.Context(), tripID, driver.ID, *req.Stop); err != nil {
+			c.JSON(driverErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		h.proxyTripResponse(c, tripID, driver.UserID)
+		return
+	}
 	status := domain.TripStatus(strings.ToLower(strings.TrimSpace(req.Status)))
-	if _, err := h.driverService.UpdateTripStatus(c.Request.Context(), tripID, driver.ID, status); err != nil {
+	if status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status or stop required"})
+		return
+	}
+	if _, err := h.driverService.UpdateTripStatus(c.Request.Context(), tripID, driver.ID, status, strings.TrimSpace(req.Reason)); err != nil {
 		c.JSON(driverErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
@@ -173,10 +187,13 @@ func driverErrorStatus(err error) int {
 	switch err {
 	case domain.ErrTripNotFound:
 		return http.StatusNotFound
-	case domain.ErrDriverNotFound, domain.ErrTripAssignmentNotFound:
+	case domain.ErrDriverNotFound, domain.ErrTripAssignmentNotFound, domain.ErrStopNotFound:
 		return http.StatusNotFound
-	case domain.ErrDriverOffline, domain.ErrAssignmentConflict:
+	case domain.ErrDriverOffline, domain.ErrAssignmentConflict, domain.ErrAssignmentExpired, domain.ErrInvalidTransition,
+		domain.ErrStopOutOfOrder, domain.ErrStopAlreadyReached:
 		return http.StatusConflict
+	case domain.ErrTransitionNotPermitted, domain.ErrDriverIneligible:
+		return http.StatusForbidden
 	case domain.ErrInvalidStatus:
 		return http.StatusBadRequest
 	default:
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,12 +16,15 @@ import (
 	"uitgo/backend/internal/config"
 	dbrepo "uitgo/backend/internal/db"
 	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/heatmap"
 	"uitgo/backend/internal/http/handlers"
 	"uitgo/backend/internal/http/middleware"
 	"uitgo/backend/internal/location"
 	"uitgo/backend/internal/matching"
 	"uitgo/backend/internal/notification"
 	"uitgo/backend/internal/observability"
+	"uitgo/backend/internal/realtime"
+	"uitgo/backend/internal/routing"
 )
 
 const driverServiceName = "driver-service"
@@ -30,7 +34,9 @@ type Server struct {
 	engine      *gin.Engine
 	cfg         *config.Config
 	queue       matching.Queue
-	queueCancel context.CancelFunc
+	loopsCancel context.CancelFunc
+	backplane   realtime.Backplane
+	demand      *heatmap.DemandLog
 }
 
 // setupRouter creates and configures the gin router with middleware.
@@ -57,7 +63,7 @@ func setupRouter(cfg *config.Config, db *gorm.DB) *gin.Engine {
 }
 
 // createDriverService initializes the driver service with all dependencies.
-func createDriverService(cfg *config.Config, db *gorm.DB) (*domain.DriverService, error) {
+func createDriverService(cfg *config.Config, db *gorm.DB, trips domain.TripSyncRepository, sessions *handlers.DriverSessions, locator domain.DriverLocationIndex) *domain.DriverService {
 	driverRepo := dbrepo.NewDriverRepository(db)
 	assignmentRepo := dbrepo.NewTripAssignmentRepository(db)
 	notificationRepo := dbrepo.NewNotificationRepository(db)
@@ -69,24 +75,74 @@ func createDriverService(cfg *config.Config, db *gorm.DB) (*domain.DriverService
 	}
 	notificationSvc := notification.NewService(notificationRepo, deviceTokenRepo, pushSender)
 
-	locator, err := location.NewGeoIndex(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, "driver")
+	options := []domain.DriverServiceOption{
+		domain.WithDispatchConfig(domain.DispatchConfig{
+			InitialRadiusMeters:     cfg.DispatchInitialRadius,
+			MaxRadiusMeters:         cfg.DispatchMaxRadius,
+			RadiusGrowthFactor:      cfg.DispatchRadiusGrowth,
+			CandidateLimit:          cfg.DispatchCandidateLimit,
+			StalenessPenaltyMeters:  cfg.DispatchStalenessMeters,
+			StalenessPenaltySeconds: cfg.DispatchDriveStaleness,
+			OfferTimeout:            cfg.DispatchOfferTimeout,
+			MaxOfferAttempts:        cfg.DispatchMaxAttempts,
+		}),
+		domain.WithOfferPublisher(sessions),
+		domain.WithPresence(domain.PresenceConfig{
+			StaleAfter:   cfg.DriverStaleAfter,
+			OfflineAfter: cfg.DriverOfflineAfter,
+		}),
+	}
+	if cfg.DispatchDriveTime {
+		routeProvider, err := routing.NewProviderFromConfig(cfg)
+		if err != nil {
+			log.Printf("warn: drive-time dispatch disabled, ranking by distance: %v", err)
+		} else {
+			options = append(options, domain.WithDriveTimeRanking(routeProvider))
+		}
+	}
+	return domain.NewDriverService(driverRepo, assignmentRepo, trips, notificationSvc, locator, options...)
+}
+
+// createHeatmap aggregates the GEO index and the shared trip request log. The
+// heatmap shows supply only when the request log is unavailable.
+func createHeatmap(cfg *config.Config, supply heatmap.SupplySource) (*heatmap.Service, *heatmap.DemandLog) {
+	demand, err := heatmap.NewDemandLog(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, cfg.HeatmapRetention)
 	if err != nil {
-		return nil, fmt.Errorf("init redis geo index: %w", err)
+		log.Printf("warn: heatmap demand log unavailable: %v", err)
+		return heatmap.NewService(supply, nil), nil
 	}
+	return heatmap.NewService(supply, demand), demand
+}
 
-	return domain.NewDriverService(driverRepo, assignmentRepo, nil, notificationSvc, locator), nil
+// createSessionBackplane picks how offers reach driver sessions on other replicas.
+func createSessionBackplane(cfg *config.Config) realtime.Backplane {
+	if cfg.HubBackplane == "redis" {
+		backplane, err := realtime.NewRedisBackplane(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, "driver:ws:", cfg.HubReplayBuffer)
+		if err == nil {
+			return backplane
+		}
+		log.Printf("warn: driver session backplane unavailable, offers stay on this replica: %v", err)
+	}
+	return realtime.NewMemoryBackplane(cfg.HubReplayBuffer)
 }
 
+// dispatchVisibilityMargin covers the lookups around a trip's offers when
+// sizing how long a queued trip stays hidden from other consumers.
+const dispatchVisibilityMargin = time.Minute
+
 // createMatchQueue initializes the matching queue.
 func createMatchQueue(cfg *config.Config) matching.Queue {
+	// Keep a trip hidden from other consumers while its offers run.
+	visibility := time.Duration(cfg.DispatchMaxAttempts)*cfg.DispatchOfferTimeout + dispatchVisibilityMargin
 	queue, err := matching.NewQueue(context.Background(), matching.QueueOptions{
-		Backend:       cfg.MatchQueueBackend,
-		RedisAddr:     cfg.MatchQueueAddr,
-		RedisPassword: cfg.RedisPassword,
-		RedisDB:       cfg.MatchQueueDB,
-		QueueName:     cfg.MatchQueueName,
-		SQSQueueURL:   cfg.MatchQueueSQSURL,
-		SQSRegion:     cfg.AWSRegion,
+		Backend:              cfg.MatchQueueBackend,
+		RedisAddr:            cfg.MatchQueueAddr,
+		RedisPassword:        cfg.RedisPassword,
+		RedisDB:              cfg.MatchQueueDB,
+		QueueName:            cfg.MatchQueueName,
+		SQSQueueURL:          cfg.MatchQueueSQSURL,
+		SQSRegion:            cfg.AWSRegion,
+		SQSVisibilityTimeout: visibility,
 	})
 	if err != nil {
 		log.Printf("warn: init trip queue failed: %v", err)
@@ -96,46 +152,88 @@ func createMatchQueue(cfg *config.Config) matching.Queue {
 }
 
 // New builds the server with driver/profile routes and internal hooks.
-func New(cfg *config.Config, db *gorm.DB, _ domain.TripSyncRepository) (*Server, error) {
+func New(cfg *config.Config, db *gorm.DB, trips domain.TripSyncRepository) (*Server, error) {
 	router := setupRouter(cfg, db)
 
-	driverService, err := createDriverService(cfg, db)
+	locator, err := location.NewGeoIndex(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, "driver")
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("init redis geo index: %w", err)
 	}
+	backplane := createSessionBackplane(cfg)
+	sessions := handlers.NewDriverSessions(backplane)
+	driverService := createDriverService(cfg, db, trips, sessions, locator)
+	heatmaps, demand := createHeatmap(cfg, locator)
 
 	handlers.RegisterDriverRoutes(router, driverService)
+	handlers.RegisterDriverSessionRoutes(router, driverService, sessions)
 
 	tripHandler := NewDriverTripHandler(driverService, cfg.TripServiceURL, cfg.InternalAPIKey)
 	tripHandler.Register(router.Group("/v1"))
 
 	registerInternalRoutes(router, cfg, driverService)
 
+	adminGroup := router.Group("/admin")
+	adminGroup.Use(middleware.RequireRoles("admin"))
+	handlers.RegisterHeatmapRoutes(adminGroup, heatmaps)
+	handlers.RegisterDriverAdminRoutes(adminGroup, driverService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go sweepStaleDrivers(ctx, driverService, cfg.DriverSweepInterval)
 	matchQueue := createMatchQueue(cfg)
-	var cancel context.CancelFunc
 	if matchQueue != nil {
-		ctx, c := context.WithCancel(context.Background())
-		cancel = c
-		go consumeTripQueue(ctx, matchQueue, driverService)
+		go consumeTripQueue(ctx, matchQueue, driverService, heatmaps, cfg.DispatchConcurrency)
 	}
 
-	return &Server{engine: router, cfg: cfg, queue: matchQueue, queueCancel: cancel}, nil
+	return &Server{engine: router, cfg: cfg, queue: matchQueue, loopsCancel: cancel, backplane: backplane, demand: demand}, nil
 }
 
 // Run starts the HTTP listener.
 func (s *Server) Run() error {
 	addr := fmt.Sprintf(":%s", s.cfg.Port)
 	defer func() {
-		if s.queueCancel != nil {
-			s.queueCancel()
+		if s.loopsCancel != nil {
+			s.loopsCancel()
 		}
 		if s.queue != nil {
 			_ = s.queue.Close()
 		}
+		if s.backplane != nil {
+			_ = s.backplane.Close()
+		}
+		if s.demand != nil {
+			_ = s.demand.Close()
+		}
 	}()
 	return s.engine.Run(addr)
 }
 
+// sweepStaleDrivers periodically retires drivers who stopped sending
+// locations: stale ones leave the GEO index and silent ones go offline.
+func sweepStaleDrivers(ctx context.Context, drivers *domain.DriverService, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Println("stale driver sweep loop started")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sweep, err := drivers.SweepStaleDrivers(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("sweep stale drivers failed: %v", err)
+			continue
+		}
+		if len(sweep.Evicted) > 0 || len(sweep.WentOffline) > 0 {
+			log.Printf("stale driver sweep: evicted %d, switched %d offline", len(sweep.Evicted), len(sweep.WentOffline))
+		}
+	}
+}
+
 func registerInternalRoutes(router gin.IRouter, cfg *config.Config, service *domain.DriverService) {
 	group := router.Group("/internal")
 	group.Use(middleware.InternalOnly(cfg.InternalAPIKey))
@@ -256,23 +354,64 @@ func clearAssignmentsHandler(service *domain.DriverService) gin.HandlerFunc {
 	}
 }
 
-func consumeTripQueue(ctx context.Context, queue matching.TripConsumer, driverService *domain.DriverService) {
+// consumeTripQueue records each queued trip on the heatmap and runs its
+// sequential offer flow. Offers wait on driver responses, so concurrency
+// consumers dispatch trips side by side; a trip delivered again while its
+// offers are still running here is dropped. On SQS a trip is deleted only once
+// its dispatch has finished, so trips interrupted by a failure or a shutdown
+// are delivered again. The Redis queue pops trips before they are handled, so
+// there such trips are lost and stay requested until re-queued.
+func consumeTripQueue(ctx context.Context, queue matching.TripConsumer, driverService *domain.DriverService, heatmaps *heatmap.Service, concurrency int) {
 	if queue == nil || driverService == nil {
 		return
 	}
-	log.Println("trip queue consumer started")
-	if err := queue.Consume(ctx, func(ctx context.Context, event *matching.TripEvent) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var (
+		mu          sync.Mutex
+		dispatching = make(map[string]struct{})
+	)
+	handler := func(ctx context.Context, event *matching.TripEvent) error {
 		if event == nil || event.TripID == "" {
 			return nil
 		}
-		_, err := driverService.AssignNextAvailableDriver(ctx, event.TripID)
+		mu.Lock()
+		if _, busy := dispatching[event.TripID]; busy {
+			mu.Unlock()
+			return nil
+		}
+		dispatching[event.TripID] = struct{}{}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			delete(dispatching, event.TripID)
+			mu.Unlock()
+		}()
+
+		if heatmaps != nil {
+			if err := heatmaps.RecordRequest(ctx, event); err != nil {
+				log.Printf("record trip %s demand failed: %v", event.TripID, err)
+			}
+		}
+		_, err := driverService.DispatchTrip(ctx, event.TripID)
 		if err != nil && !errors.Is(err, domain.ErrNoDriversAvailable) {
 			return err
 		}
 		return nil
-	}); err != nil && !errors.Is(err, context.Canceled) {
-		log.Printf("trip queue consumer stopped: %v", err)
-	} else {
-		log.Println("trip queue consumer stopped")
 	}
+
+	log.Println("trip queue consumer started")
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := queue.Consume(ctx, handler); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("trip queue consumer stopped: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	log.Println("trip queue consumer stopped")
 }
@@ -0,0 +1,6 @@
+ALTER TABLE vehicles
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS capacity INT NOT NULL DEFAULT 0;
+
+ALTER TABLE drivers
+    ADD COLUMN IF NOT EXISTS eligible_services JSONB;
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"uitgo/backend/internal/domain"
 )
 
 // Config holds runtime configuration for the API server.
@@ -30,6 +33,8 @@ type Config struct {
 	RedisPassword           string
 	RedisDB                 int
 	HomeCacheTTL            time.Duration
+	HubBackplane            string
+	HubReplayBuffer         int
 	MatchQueueBackend       string
 	MatchQueueAddr          string
 	MatchQueueDB            int
@@ -43,7 +48,45 @@ type Config struct {
 	LogFormat               string
 	Environment             string
 	IsProduction            bool
+	RoutingProvider         string
 	RoutingBaseURL          string
+	RoutingAPIKey           string
+	RoutingProfile          string
+	RoutingRoadFactor       float64
+	RoutingAverageSpeedKmh  float64
+	RoutingCacheSize        int
+	RoutingCacheRedis       bool
+	DispatchInitialRadius   float64
+	DispatchMaxRadius       float64
+	DispatchRadiusGrowth    float64
+	DispatchCandidateLimit  int
+	DispatchStalenessMeters float64
+	DispatchDriveTime       bool
+	DispatchDriveStaleness  float64
+	DispatchOfferTimeout    time.Duration
+	DispatchMaxAttempts     int
+	DispatchConcurrency     int
+	DriverStaleAfter        time.Duration
+	DriverOfflineAfter      time.Duration
+	DriverSweepInterval     time.Duration
+	HeatmapRetention        time.Duration
+	SurgeEnabled            bool
+	SurgeMaxMultiplier      float64
+	SurgeDemandWindow       time.Duration
+	SurgeQuoteTTL           time.Duration
+	ServiceAreaRefresh      time.Duration
+	FareDistanceTolerance   float64
+	FareRules               map[string]domain.FareRule
+	CancellationGrace       time.Duration
+	CancellationFee         int64
+	ScheduleMaxAhead        time.Duration
+	ScheduleLeadTime        time.Duration
+	SchedulerInterval       time.Duration
+	GeofencePickupRadius    float64
+	GeofenceDestRadius      float64
+	GeofenceExitMargin      float64
+	GeofenceConfirmPings    int
+	ETARefreshInterval      time.Duration
 	AdminEmail              string
 	AdminPassword           string
 	AdminName               string
@@ -73,6 +116,14 @@ func Load() (*Config, error) {
 	redisPassword := os.Getenv("REDIS_PASSWORD")
 	redisDB := parseIntEnv(os.Getenv("REDIS_DB"), 0)
 	homeCacheTTL := parseDuration(os.Getenv("HOME_CACHE_TTL_SECONDS"), 300*time.Second, time.Second)
+	hubBackplane := strings.TrimSpace(strings.ToLower(os.Getenv("WS_BACKPLANE")))
+	if hubBackplane == "" {
+		hubBackplane = "memory"
+		if redisAddr != "" {
+			hubBackplane = "redis"
+		}
+	}
+	hubReplayBuffer := parseIntEnv(os.Getenv("WS_REPLAY_BUFFER"), 100)
 
 	matchQueueBackend := strings.TrimSpace(strings.ToLower(os.Getenv("QUEUE_BACKEND")))
 	if matchQueueBackend == "" {
@@ -110,10 +161,55 @@ func Load() (*Config, error) {
 
 	awsRegion := strings.TrimSpace(os.Getenv("AWS_REGION"))
 
+	routingProvider := strings.TrimSpace(strings.ToLower(os.Getenv("ROUTING_PROVIDER")))
+	if routingProvider == "" {
+		routingProvider = "osrm"
+	}
 	routingBaseURL := strings.TrimSpace(os.Getenv("ROUTING_BASE_URL"))
-	if routingBaseURL == "" {
+	if routingBaseURL == "" && routingProvider == "osrm" {
 		routingBaseURL = "https://routing.openstreetmap.de/routed-bike"
 	}
+	routingAPIKey := strings.TrimSpace(os.Getenv("ROUTING_API_KEY"))
+	routingProfile := strings.TrimSpace(os.Getenv("ROUTING_PROFILE"))
+	routingRoadFactor := parseFloatEnv(os.Getenv("ROUTING_ROAD_FACTOR"), 1.3)
+	routingAverageSpeed := parseFloatEnv(os.Getenv("ROUTING_AVERAGE_SPEED_KMH"), 40)
+	routingCacheSize := parseIntEnv(os.Getenv("ROUTING_CACHE_SIZE"), 10000)
+	routingCacheRedis := parseBoolEnv(os.Getenv("ROUTING_CACHE_REDIS"), false)
+
+	dispatchInitialRadius := parseFloatEnv(os.Getenv("DISPATCH_INITIAL_RADIUS_METERS"), 1000)
+	dispatchMaxRadius := parseFloatEnv(os.Getenv("DISPATCH_MAX_RADIUS_METERS"), 8000)
+	dispatchRadiusGrowth := parseFloatEnv(os.Getenv("DISPATCH_RADIUS_GROWTH"), 2)
+	dispatchCandidateLimit := parseIntEnv(os.Getenv("DISPATCH_CANDIDATE_LIMIT"), 10)
+	dispatchStalenessMeters := parseFloatEnv(os.Getenv("DISPATCH_STALENESS_PENALTY_METERS"), 5)
+	dispatchDriveTime := parseBoolEnv(os.Getenv("DISPATCH_RANK_BY_DRIVE_TIME"), true)
+	dispatchDriveStaleness := parseFloatEnv(os.Getenv("DISPATCH_STALENESS_PENALTY_SECONDS"), 0.5)
+	dispatchOfferTimeout := parseDuration(os.Getenv("DISPATCH_OFFER_TIMEOUT_SECONDS"), 20*time.Second, time.Second)
+	dispatchMaxAttempts := parseIntEnv(os.Getenv("DISPATCH_MAX_ATTEMPTS"), 5)
+	dispatchConcurrency := parseIntEnv(os.Getenv("DISPATCH_CONCURRENCY"), 16)
+	driverStaleAfter := parseDuration(os.Getenv("DRIVER_STALE_AFTER_SECONDS"), 3*time.Minute, time.Second)
+	driverOfflineAfter := parseDuration(os.Getenv("DRIVER_OFFLINE_AFTER_SECONDS"), 10*time.Minute, time.Second)
+	driverSweepInterval := parseDuration(os.Getenv("DRIVER_SWEEP_INTERVAL_SECONDS"), 30*time.Second, time.Second)
+	heatmapRetention := parseDuration(os.Getenv("HEATMAP_RETENTION_MINUTES"), time.Hour, time.Minute)
+	surgeEnabled := parseBoolEnv(os.Getenv("SURGE_ENABLED"), true)
+	surgeMaxMultiplier := parseFloatEnv(os.Getenv("SURGE_MAX_MULTIPLIER"), 2.0)
+	surgeDemandWindow := parseDuration(os.Getenv("SURGE_DEMAND_WINDOW_SECONDS"), 5*time.Minute, time.Second)
+	surgeQuoteTTL := parseDuration(os.Getenv("SURGE_QUOTE_TTL_SECONDS"), 2*time.Minute, time.Second)
+	serviceAreaRefresh := parseDuration(os.Getenv("SERVICE_AREA_REFRESH_SECONDS"), time.Minute, time.Second)
+	fareDistanceTolerance := parseFloatEnv(os.Getenv("FARE_DISTANCE_TOLERANCE"), 0.15)
+	fareRules, err := parseFareRules(os.Getenv("FARE_RULES"))
+	if err != nil {
+		return nil, fmt.Errorf("parse FARE_RULES: %w", err)
+	}
+	cancellationGrace := parseDuration(os.Getenv("CANCELLATION_GRACE_SECONDS"), 2*time.Minute, time.Second)
+	cancellationFee := int64(parseIntEnv(os.Getenv("CANCELLATION_FEE"), 10000))
+	scheduleMaxAhead := parseDuration(os.Getenv("SCHEDULE_MAX_AHEAD_HOURS"), 7*24*time.Hour, time.Hour)
+	scheduleLeadTime := parseDuration(os.Getenv("SCHEDULE_LEAD_MINUTES"), 15*time.Minute, time.Minute)
+	schedulerInterval := parseDuration(os.Getenv("SCHEDULER_INTERVAL_SECONDS"), 30*time.Second, time.Second)
+	geofencePickupRadius := parseFloatEnv(os.Getenv("GEOFENCE_PICKUP_RADIUS_METERS"), 150)
+	geofenceDestRadius := parseFloatEnv(os.Getenv("GEOFENCE_DESTINATION_RADIUS_METERS"), 100)
+	geofenceExitMargin := parseFloatEnv(os.Getenv("GEOFENCE_EXIT_MARGIN_METERS"), 75)
+	geofenceConfirmPings := parseIntEnv(os.Getenv("GEOFENCE_CONFIRM_PINGS"), 2)
+	etaRefreshInterval := parseDuration(os.Getenv("ETA_REFRESH_SECONDS"), 15*time.Second, time.Second)
 
 	adminEmail := strings.TrimSpace(os.Getenv("ADMIN_EMAIL"))
 	adminPassword := strings.TrimSpace(os.Getenv("ADMIN_PASSWORD"))
@@ -187,6 +283,8 @@ func Load() (*Config, error) {
 		RedisPassword:           redisPassword,
 		RedisDB:                 redisDB,
 		HomeCacheTTL:            homeCacheTTL,
+		HubBackplane:            hubBackplane,
+		HubReplayBuffer:         hubReplayBuffer,
 		MatchQueueBackend:       matchQueueBackend,
 		MatchQueueAddr:          matchQueueAddr,
 		MatchQueueDB:            matchQueueDB,
@@ -200,7 +298,45 @@ func Load() (*Config, error) {
 		LogFormat:               logFormat,
 		Environment:             appEnv,
 		IsProduction:            isProd,
+		RoutingProvider:         routingProvider,
 		RoutingBaseURL:          routingBaseURL,
+		RoutingAPIKey:           routingAPIKey,
+		RoutingProfile:          routingProfile,
+		RoutingRoadFactor:       routingRoadFactor,
+		RoutingAverageSpeedKmh:  routingAverageSpeed,
+		RoutingCacheSize:        routingCacheSize,
+		RoutingCacheRedis:       routingCacheRedis,
+		DispatchInitialRadius:   dispatchInitialRadius,
+		DispatchMaxRadius:       dispatchMaxRadius,
+		DispatchRadiusGrowth:    dispatchRadiusGrowth,
+		DispatchCandidateLimit:  dispatchCandidateLimit,
+		DispatchStalenessMeters: dispatchStalenessMeters,
+		DispatchDriveTime:       dispatchDriveTime,
+		DispatchDriveStaleness:  dispatchDriveStaleness,
+		DispatchOfferTimeout:    dispatchOfferTimeout,
+		DispatchMaxAttempts:     dispatchMaxAttempts,
+		DispatchConcurrency:     dispatchConcurrency,
+		DriverStaleAfter:        driverStaleAfter,
+		DriverOfflineAfter:      driverOfflineAfter,
+		DriverSweepInterval:     driverSweepInterval,
+		HeatmapRetention:        heatmapRetention,
+		SurgeEnabled:            surgeEnabled,
+		SurgeMaxMultiplier:      surgeMaxMultiplier,
+		SurgeDemandWindow:       surgeDemandWindow,
+		SurgeQuoteTTL:           surgeQuoteTTL,
+		ServiceAreaRefresh:      serviceAreaRefresh,
+		FareDistanceTolerance:   fareDistanceTolerance,
+		FareRules:               fareRules,
+		CancellationGrace:       cancellationGrace,
+		CancellationFee:         cancellationFee,
+		ScheduleMaxAhead:        scheduleMaxAhead,
+		ScheduleLeadTime:        scheduleLeadTime,
+		SchedulerInterval:       schedulerInterval,
+		GeofencePickupRadius:    geofencePickupRadius,
+		GeofenceDestRadius:      geofenceDestRadius,
+		GeofenceExitMargin:      geofenceExitMargin,
+		GeofenceConfirmPings:    geofenceConfirmPings,
+		ETARefreshInterval:      etaRefreshInterval,
 		AdminEmail:              adminEmail,
 		AdminPassword:           adminPassword,
 		AdminName:               adminName,
@@ -219,6 +355,29 @@ func parseDuration(value string, defaultValue time.Duration, unit time.Duration)
 	return time.Duration(parsed) * unit
 }
 
+// parseFareRules overlays value, a JSON object of fare rules keyed by service
+// ID, on the default rules. Fields a service leaves out keep their default.
+func parseFareRules(value string) (map[string]domain.FareRule, error) {
+	rules := domain.DefaultFareRules()
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return rules, nil
+	}
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, err
+	}
+	for serviceID, raw := range overrides {
+		serviceID = strings.ToLower(strings.TrimSpace(serviceID))
+		rule := rules[serviceID]
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			return nil, fmt.Errorf("%s: %w", serviceID, err)
+		}
+		rules[serviceID] = rule
+	}
+	return rules, nil
+}
+
 func parseBoolEnv(value string, defaultValue bool) bool {
 	value = strings.TrimSpace(strings.ToLower(value))
 	if value == "" {
@@ -246,6 +405,18 @@ func parseIntEnv(value string, defaultValue int) int {
 	return parsed
 }
 
+func parseFloatEnv(value string, defaultValue float64) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		return defaultValue
+	}
+	return parsed
+}
+
 func appendOriginIfMissing(origins []string, candidate string) []string {
 	candidate = strings.TrimSpace(candidate)
 	if candidate == "" {
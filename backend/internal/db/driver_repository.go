@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,16 +31,17 @@ func NewDriverRepository(db *gorm.DB) domain.DriverRepository {
 }
 
 type driverModel struct {
-	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
-	UserID        uuid.UUID `gorm:"type:uuid;uniqueIndex"`
-	FullName      string
-	Phone         string
-	LicenseNumber string
-	AvatarURL     *string
-	Rating        float64
-	CreatedAt     time.Time     `gorm:"autoCreateTime"`
-	UpdatedAt     time.Time     `gorm:"autoUpdateTime"`
-	Vehicle       *vehicleModel `gorm:"foreignKey:DriverID"`
+	ID               uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID           uuid.UUID `gorm:"type:uuid;uniqueIndex"`
+	FullName         string
+	Phone            string
+	LicenseNumber    string
+	AvatarURL        *string
+	Rating           float64
+	EligibleServices []byte        `gorm:"type:jsonb"`
+	CreatedAt        time.Time     `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time     `gorm:"autoUpdateTime"`
+	Vehicle          *vehicleModel `gorm:"foreignKey:DriverID"`
 }
 
 func (driverModel) TableName() string {
@@ -53,6 +56,8 @@ type vehicleModel struct {
 	Color       string
 	Year        int
 	PlateNumber string
+	Category    string
+	Capacity    int
 	CreatedAt   time.Time `gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
 }
@@ -171,11 +176,11 @@ func (r *driverRepository) DeleteByID(ctx context.Context, driverID string) erro
 	return nil
 }
 
-func (r *driverRepository) FindAvailable(ctx context.Context) (*domain.Driver, error) {
+func (r *driverRepository) ListAvailable(ctx context.Context, limit int) ([]*domain.Driver, error) {
 	type candidate struct {
 		ID uuid.UUID
 	}
-	var row candidate
+	var rows []candidate
 	activeStatuses := []string{
 		string(domain.TripAssignmentPending),
 		string(domain.TripAssignmentAccepted),
@@ -187,15 +192,23 @@ func (r *driverRepository) FindAvailable(ctx context.Context) (*domain.Driver, e
 		Joins("LEFT JOIN trip_assignments ta ON ta.driver_id = d.id AND ta.status IN ?", activeStatuses).
 		Where("ta.id IS NULL").
 		Order("ds.updated_at ASC").
-		Limit(1).
-		Take(&row).Error
+		Limit(limit).
+		Find(&rows).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return r.FindByID(ctx, row.ID.String())
+	drivers := make([]*domain.Driver, 0, len(rows))
+	for _, row := range rows {
+		driver, err := r.FindByID(ctx, row.ID.String())
+		if errors.Is(err, domain.ErrDriverNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		drivers = append(drivers, driver)
+	}
+	return drivers, nil
 }
 
 func (r *driverRepository) Update(ctx context.Context, driver *domain.Driver) error {
@@ -253,6 +266,8 @@ func (r *driverRepository) createVehicle(ctx context.Context, driverUID uuid.UUI
 		Color:       vehicle.Color,
 		Year:        vehicle.Year,
 		PlateNumber: vehicle.PlateNumber,
+		Category:    string(vehicle.Category),
+		Capacity:    vehicle.Capacity,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -270,6 +285,8 @@ func (r *driverRepository) updateVehicle(ctx context.Context, driverUID uuid.UUI
 		"color":        vehicle.Color,
 		"year":         vehicle.Year,
 		"plate_number": vehicle.PlateNumber,
+		"category":     string(vehicle.Category),
+		"capacity":     vehicle.Capacity,
 		"updated_at":   now,
 	}
 	if err := r.db.WithContext(ctx).Model(&vehicleModel{}).
@@ -306,6 +323,31 @@ func (r *driverRepository) FindVehicle(ctx context.Context, driverID string) (*d
 	return toVehicleDomain(&model), nil
 }
 
+func (r *driverRepository) SetEligibleServices(ctx context.Context, driverID string, services []string) error {
+	uid, err := uuid.Parse(driverID)
+	if err != nil {
+		return domain.ErrDriverNotFound
+	}
+	var eligible any
+	if len(services) > 0 {
+		encoded, err := json.Marshal(services)
+		if err != nil {
+			return err
+		}
+		eligible = gorm.Expr("?::jsonb", string(encoded))
+	}
+	res := r.db.WithContext(ctx).Model(&driverModel{}).
+		Where(queryByID, uid).
+		Updates(map[string]any{"eligible_services": eligible, "updated_at": time.Now().UTC()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrDriverNotFound
+	}
+	return nil
+}
+
 func (r *driverRepository) SetAvailability(ctx context.Context, driverID string, availability domain.DriverAvailability) (*domain.DriverStatus, error) {
 	driverUID, err := uuid.Parse(driverID)
 	if err != nil {
@@ -412,6 +454,11 @@ func toDriverDomain(model *driverModel) *domain.Driver {
 		CreatedAt:     model.CreatedAt,
 		UpdatedAt:     model.UpdatedAt,
 	}
+	if len(model.EligibleServices) > 0 {
+		if err := json.Unmarshal(model.EligibleServices, &driver.EligibleServices); err != nil {
+			log.Printf("decode eligible services of driver %s: %v", model.ID, err)
+		}
+	}
 	if model.Vehicle != nil {
 		driver.Vehicle = toVehicleDomain(model.Vehicle)
 	}
@@ -430,6 +477,8 @@ func toVehicleDomain(model *vehicleModel) *domain.Vehicle {
 		Color:       model.Color,
 		Year:        model.Year,
 		PlateNumber: model.PlateNumber,
+		Category:    domain.VehicleCategory(model.Category),
+		Capacity:    model.Capacity,
 		CreatedAt:   model.CreatedAt,
 		UpdatedAt:   model.UpdatedAt,
 	}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"uitgo/backend/internal/domain"
+)
+
+type serviceAreaRepository struct {
+	db *gorm.DB
+}
+
+// NewServiceAreaRepository wires CRUD operations for the service_areas table.
+func NewServiceAreaRepository(db *gorm.DB) domain.ServiceAreaRepository {
+	return &serviceAreaRepository{db: db}
+}
+
+type serviceAreaModel struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ServiceID    string    `gorm:"index"`
+	Name         string
+	Kind         string
+	Polygon      []byte `gorm:"type:jsonb"`
+	Surcharge    int64
+	PickupPoints []byte `gorm:"type:jsonb"`
+	Active       bool
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (serviceAreaModel) TableName() string {
+	return "service_areas"
+}
+
+func (r *serviceAreaRepository) ListServiceAreas(ctx context.Context) ([]*domain.ServiceArea, error) {
+	var rows []serviceAreaModel
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	areas := make([]*domain.ServiceArea, 0, len(rows))
+	for _, row := range rows {
+		area := &domain.ServiceArea{
+			ID:        row.ID.String(),
+			ServiceID: row.ServiceID,
+			Name:      row.Name,
+			Kind:      domain.ServiceAreaKind(row.Kind),
+			Surcharge: row.Surcharge,
+			Active:    row.Active,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}
+		if err := json.Unmarshal(row.Polygon, &area.Polygon); err != nil {
+			return nil, err
+		}
+		if len(row.PickupPoints) > 0 {
+			if err := json.Unmarshal(row.PickupPoints, &area.PickupPoints); err != nil {
+				return nil, err
+			}
+		}
+		areas = append(areas, area)
+	}
+	return areas, nil
+}
+
+func (r *serviceAreaRepository) CreateServiceArea(ctx context.Context, area *domain.ServiceArea) error {
+	model, err := newServiceAreaModel(area)
+	if err != nil {
+		return err
+	}
+	if area.ID == "" {
+		model.ID = uuid.New()
+	}
+	if err := r.db.WithContext(ctx).Create(&model).Error; err != nil {
+		return err
+	}
+	area.ID = model.ID.String()
+	area.CreatedAt = model.CreatedAt
+	area.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
+func (r *serviceAreaRepository) UpdateServiceArea(ctx context.Context, area *domain.ServiceArea) error {
+	model, err := newServiceAreaModel(area)
+	if err != nil {
+		return err
+	}
+	res := r.db.WithContext(ctx).Model(&serviceAreaModel{}).
+		Where("id = ?", model.ID).
+		Updates(map[string]any{
+			"service_id":    model.ServiceID,
+			"name":          model.Name,
+			"kind":          model.Kind,
+			"polygon":       gorm.Expr("?::jsonb", string(model.Polygon)),
+			"surcharge":     model.Surcharge,
+			"pickup_points": gorm.Expr("?::jsonb", string(model.PickupPoints)),
+			"active":        model.Active,
+			"updated_at":    time.Now().UTC(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrServiceAreaNotFound
+	}
+	return nil
+}
+
+func (r *serviceAreaRepository) DeleteServiceArea(ctx context.Context, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return domain.ErrServiceAreaNotFound
+	}
+	res := r.db.WithContext(ctx).Where("id = ?", uid).Delete(&serviceAreaModel{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrServiceAreaNotFound
+	}
+	return nil
+}
+
+// newServiceAreaModel encodes area for storage. An empty ID is left as the
+// zero UUID for the caller to fill.
+func newServiceAreaModel(area *domain.ServiceArea) (serviceAreaModel, error) {
+	var id uuid.UUID
+	if area.ID != "" {
+		parsed, err := uuid.Parse(area.ID)
+		if err != nil {
+			return serviceAreaModel{}, domain.ErrServiceAreaNotFound
+		}
+		id = parsed
+	}
+	polygon, err := json.Marshal(area.Polygon)
+	if err != nil {
+		return serviceAreaModel{}, err
+	}
+	pickupPoints := area.PickupPoints
+	if pickupPoints == nil {
+		pickupPoints = []domain.PickupPoint{}
+	}
+	points, err := json.Marshal(pickupPoints)
+	if err != nil {
+		return serviceAreaModel{}, err
+	}
+	return serviceAreaModel{
+		ID:           id,
+		ServiceID:    area.ServiceID,
+		Name:         area.Name,
+		Kind:         string(area.Kind),
+		Polygon:      polygon,
+		Surcharge:    area.Surcharge,
+		PickupPoints: points,
+		Active:       area.Active,
+	}, nil
+}
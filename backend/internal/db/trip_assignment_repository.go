@@ -123,6 +123,39 @@ func (r *tripAssignmentRepository) UpdateStatus(ctx context.Context, tripID, dri
 	return toTripAssignmentDomain(&model), nil
 }
 
+func (r *tripAssignmentRepository) ResolvePending(ctx context.Context, tripID, driverID string, status domain.TripAssignmentStatus, respondedAt *time.Time) (*domain.TripAssignment, error) {
+	tripUID, err := uuid.Parse(tripID)
+	if err != nil {
+		return nil, err
+	}
+	driverUID, err := uuid.Parse(driverID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if respondedAt == nil {
+		respondedAt = &now
+	}
+	res := r.db.WithContext(ctx).Model(&tripAssignmentModel{}).
+		Where("trip_id = ? AND driver_id = ? AND status = ?", tripUID, driverUID, string(domain.TripAssignmentPending)).
+		Updates(map[string]any{
+			"status":       string(status),
+			"responded_at": *respondedAt,
+			"updated_at":   now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, domain.ErrTripAssignmentNotFound
+	}
+	var model tripAssignmentModel
+	if err := r.db.WithContext(ctx).First(&model, "trip_id = ?", tripUID).Error; err != nil {
+		return nil, err
+	}
+	return toTripAssignmentDomain(&model), nil
+}
+
 func (r *tripAssignmentRepository) GetByTripID(ctx context.Context, tripID string) (*domain.TripAssignment, error) {
 	tripUID, err := uuid.Parse(tripID)
 	if err != nil {
@@ -3,6 +3,8 @@ package db
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -45,19 +47,37 @@ func (r *tripRepository) reader() *gorm.DB {
 }
 
 type tripModel struct {
-	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
-	RiderID    string
-	DriverID   *string
-	ServiceID  string
-	OriginText string
-	DestText   string
-	OriginLat  *float64
-	OriginLng  *float64
-	DestLat    *float64
-	DestLng    *float64
-	Status     string
-	CreatedAt  time.Time `gorm:"autoCreateTime"`
-	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+	ID                   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RiderID              string
+	DriverID             *string
+	ServiceID            string
+	OriginText           string
+	DestText             string
+	OriginLat            *float64
+	OriginLng            *float64
+	DestLat              *float64
+	DestLng              *float64
+	Stops                []byte `gorm:"type:jsonb"`
+	QuotedFare           *int64
+	FinalFare            *int64
+	Currency             string
+	RouteDistanceMeters  *float64
+	RouteDurationSeconds *float64
+	ActualDistanceMeters *float64
+	FareBasis            string
+	SurgeMultiplier      *float64
+	ZoneSurcharge        *int64
+	CancellationFee      *int64
+	ScheduledAt          *time.Time
+	AcceptedAt           *time.Time
+	StartedAt            *time.Time
+	CompletedAt          *time.Time
+	FareChargedAt        *time.Time
+	ETA                  []byte `gorm:"column:eta;type:jsonb"`
+	MatchedTrail         []byte `gorm:"type:jsonb"`
+	Status               string
+	CreatedAt            time.Time `gorm:"autoCreateTime"`
+	UpdatedAt            time.Time `gorm:"autoUpdateTime"`
 }
 
 func (tripModel) TableName() string {
@@ -69,6 +89,7 @@ type tripEventModel struct {
 	TripID    uuid.UUID
 	Type      string
 	Payload   []byte    `gorm:"type:jsonb"`
+	Seq       int64     `gorm:"->"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 }
 
@@ -92,20 +113,53 @@ func (r *tripRepository) CreateTrip(trip *domain.Trip) error {
 	if now.IsZero() {
 		now = time.Now().UTC()
 	}
+	var stops []byte
+	if len(trip.Stops) > 0 {
+		encoded, err := json.Marshal(trip.Stops)
+		if err != nil {
+			return err
+		}
+		stops = encoded
+	}
+	var eta []byte
+	if trip.ETA != nil {
+		encoded, err := json.Marshal(trip.ETA)
+		if err != nil {
+			return err
+		}
+		eta = encoded
+	}
 	model := tripModel{
-		ID:         id,
-		RiderID:    trip.RiderID,
-		DriverID:   trip.DriverID,
-		ServiceID:  trip.ServiceID,
-		OriginText: trip.OriginText,
-		DestText:   trip.DestText,
-		OriginLat:  trip.OriginLat,
-		OriginLng:  trip.OriginLng,
-		DestLat:    trip.DestLat,
-		DestLng:    trip.DestLng,
-		Status:     string(trip.Status),
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		ID:                   id,
+		RiderID:              trip.RiderID,
+		DriverID:             trip.DriverID,
+		ServiceID:            trip.ServiceID,
+		OriginText:           trip.OriginText,
+		DestText:             trip.DestText,
+		OriginLat:            trip.OriginLat,
+		OriginLng:            trip.OriginLng,
+		DestLat:              trip.DestLat,
+		DestLng:              trip.DestLng,
+		Stops:                stops,
+		QuotedFare:           trip.QuotedFare,
+		FinalFare:            trip.FinalFare,
+		Currency:             trip.Currency,
+		RouteDistanceMeters:  trip.RouteDistanceMeters,
+		RouteDurationSeconds: trip.RouteDurationSeconds,
+		ActualDistanceMeters: trip.ActualDistanceMeters,
+		FareBasis:            string(trip.FareBasis),
+		SurgeMultiplier:      trip.SurgeMultiplier,
+		ZoneSurcharge:        trip.ZoneSurcharge,
+		CancellationFee:      trip.CancellationFee,
+		ScheduledAt:          trip.ScheduledAt,
+		AcceptedAt:           trip.AcceptedAt,
+		StartedAt:            trip.StartedAt,
+		CompletedAt:          trip.CompletedAt,
+		FareChargedAt:        trip.FareChargedAt,
+		ETA:                  eta,
+		Status:               string(trip.Status),
+		CreatedAt:            now,
+		UpdatedAt:            now,
 	}
 
 	if err := r.db.Create(&model).Error; err != nil {
@@ -132,41 +186,109 @@ func (r *tripRepository) GetTrip(id string) (*domain.Trip, error) {
 		return nil, err
 	}
 
+	return toTripDomain(&model), nil
+}
+
+func toTripDomain(model *tripModel) *domain.Trip {
+	var stops []domain.TripStop
+	if len(model.Stops) > 0 {
+		if err := json.Unmarshal(model.Stops, &stops); err != nil {
+			log.Printf("decode stops of trip %s: %v", model.ID, err)
+		}
+	}
+	var eta *domain.TripETA
+	if len(model.ETA) > 0 {
+		if err := json.Unmarshal(model.ETA, &eta); err != nil {
+			log.Printf("decode eta of trip %s: %v", model.ID, err)
+		}
+	}
+	var matchedTrail *domain.MatchedTrail
+	if len(model.MatchedTrail) > 0 {
+		if err := json.Unmarshal(model.MatchedTrail, &matchedTrail); err != nil {
+			log.Printf("decode matched trail of trip %s: %v", model.ID, err)
+		}
+	}
 	return &domain.Trip{
-		ID:         model.ID.String(),
-		RiderID:    model.RiderID,
-		DriverID:   model.DriverID,
-		ServiceID:  model.ServiceID,
-		OriginText: model.OriginText,
-		DestText:   model.DestText,
-		OriginLat:  model.OriginLat,
-		OriginLng:  model.OriginLng,
-		DestLat:    model.DestLat,
-		DestLng:    model.DestLng,
-		Status:     domain.TripStatus(model.Status),
-		CreatedAt:  model.CreatedAt,
-		UpdatedAt:  model.UpdatedAt,
-	}, nil
-}
-
-func (r *tripRepository) UpdateTripStatus(id string, status domain.TripStatus) error {
+		ID:                   model.ID.String(),
+		RiderID:              model.RiderID,
+		DriverID:             model.DriverID,
+		ServiceID:            model.ServiceID,
+		OriginText:           model.OriginText,
+		DestText:             model.DestText,
+		OriginLat:            model.OriginLat,
+		OriginLng:            model.OriginLng,
+		DestLat:              model.DestLat,
+		DestLng:              model.DestLng,
+		Stops:                stops,
+		QuotedFare:           model.QuotedFare,
+		FinalFare:            model.FinalFare,
+		Currency:             model.Currency,
+		RouteDistanceMeters:  model.RouteDistanceMeters,
+		RouteDurationSeconds: model.RouteDurationSeconds,
+		ActualDistanceMeters: model.ActualDistanceMeters,
+		FareBasis:            domain.FareBasis(model.FareBasis),
+		SurgeMultiplier:      model.SurgeMultiplier,
+		ZoneSurcharge:        model.ZoneSurcharge,
+		CancellationFee:      model.CancellationFee,
+		ScheduledAt:          model.ScheduledAt,
+		AcceptedAt:           model.AcceptedAt,
+		StartedAt:            model.StartedAt,
+		CompletedAt:          model.CompletedAt,
+		FareChargedAt:        model.FareChargedAt,
+		ETA:                  eta,
+		Status:               domain.TripStatus(model.Status),
+		CreatedAt:            model.CreatedAt,
+		UpdatedAt:            model.UpdatedAt,
+		MatchedTrail:         matchedTrail,
+	}
+}
+
+// TransitionTrip moves the trip from change.From to change.To, stores
+// change.Progress in the same update and records the change as a "status" trip
+// event. The update only applies while the trip is still in change.From, so
+// concurrent transitions cannot both win.
+func (r *tripRepository) TransitionTrip(id string, change domain.TripStatusChange) error {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return err
 	}
-
-	res := r.db.Model(&tripModel{}).Where("id = ?", uid).
-		Updates(map[string]any{
-			"status":     string(status),
-			"updated_at": time.Now().UTC(),
-		})
-	if res.Error != nil {
-		return res.Error
+	if change.At.IsZero() {
+		change.At = time.Now().UTC()
 	}
-	if res.RowsAffected == 0 {
-		return domain.ErrTripNotFound
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	updates := progressUpdates(change.Progress)
+	updates["status"] = string(change.To)
+	updates["updated_at"] = change.At
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&tripModel{}).
+			Where("id = ? AND status = ?", uid, string(change.From)).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&tripModel{}).Where("id = ?", uid).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return domain.ErrTripNotFound
+			}
+			return domain.ErrInvalidTransition
+		}
+		return tx.Create(&tripEventModel{
+			ID:        uuid.New(),
+			TripID:    uid,
+			Type:      string(domain.TripEventStatus),
+			Payload:   payload,
+			CreatedAt: change.At,
+		}).Error
+	})
 }
 
 func (r *tripRepository) SetTripDriver(id string, driverID *string) error {
@@ -196,6 +318,154 @@ func (r *tripRepository) SetTripDriver(id string, driverID *string) error {
 	return nil
 }
 
+func (r *tripRepository) SaveTripProgress(id string, progress domain.TripProgress) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	updates := progressUpdates(progress)
+	updates["updated_at"] = time.Now().UTC()
+	res := r.db.Model(&tripModel{}).Where("id = ?", uid).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrTripNotFound
+	}
+	return nil
+}
+
+// progressUpdates maps progress onto trip columns. COALESCE keeps the first
+// recorded value so retried transitions are harmless.
+func progressUpdates(progress domain.TripProgress) map[string]any {
+	updates := map[string]any{}
+	if progress.FinalFare != nil {
+		updates["final_fare"] = gorm.Expr("COALESCE(final_fare, ?)", *progress.FinalFare)
+	}
+	if progress.ActualDistanceMeters != nil {
+		updates["actual_distance_meters"] = gorm.Expr("COALESCE(actual_distance_meters, ?)", *progress.ActualDistanceMeters)
+	}
+	if progress.FareBasis != "" {
+		updates["fare_basis"] = gorm.Expr("COALESCE(NULLIF(fare_basis, ''), ?)", string(progress.FareBasis))
+	}
+	if progress.CancellationFee != nil {
+		updates["cancellation_fee"] = gorm.Expr("COALESCE(cancellation_fee, ?)", *progress.CancellationFee)
+	}
+	if progress.AcceptedAt != nil {
+		updates["accepted_at"] = gorm.Expr("COALESCE(accepted_at, ?)", *progress.AcceptedAt)
+	}
+	if progress.StartedAt != nil {
+		updates["started_at"] = gorm.Expr("COALESCE(started_at, ?)", *progress.StartedAt)
+	}
+	if progress.CompletedAt != nil {
+		updates["completed_at"] = gorm.Expr("COALESCE(completed_at, ?)", *progress.CompletedAt)
+	}
+	if progress.FareChargedAt != nil {
+		updates["fare_charged_at"] = gorm.Expr("COALESCE(fare_charged_at, ?)", *progress.FareChargedAt)
+	}
+	return updates
+}
+
+// SaveTripETA stores the trip's latest ETA, or clears it when eta is nil. The
+// ETA is not a change to the trip itself, so updated_at is left alone.
+func (r *tripRepository) SaveTripETA(id string, eta *domain.TripETA) error {
+	if eta == nil {
+		return r.saveJSONColumn(id, "eta", nil)
+	}
+	return r.saveJSONColumn(id, "eta", eta)
+}
+
+// SaveMatchedTrail stores the trip's trail as snapped to roads at completion.
+func (r *tripRepository) SaveMatchedTrail(id string, trail *domain.MatchedTrail) error {
+	if trail == nil {
+		return r.saveJSONColumn(id, "matched_trail", nil)
+	}
+	return r.saveJSONColumn(id, "matched_trail", trail)
+}
+
+// saveJSONColumn writes value to a jsonb column, or NULL when value is nil,
+// without touching updated_at.
+func (r *tripRepository) saveJSONColumn(id, column string, value any) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	expr := gorm.Expr("NULL")
+	if value != nil {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		expr = gorm.Expr("?::jsonb", string(encoded))
+	}
+	res := r.db.Model(&tripModel{}).Where("id = ?", uid).UpdateColumn(column, expr)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrTripNotFound
+	}
+	return nil
+}
+
+// MarkTripStopReached stamps the arrival time on a stop and records it as a
+// "stop" trip event. A stop that already has an arrival is left untouched.
+func (r *tripRepository) MarkTripStopReached(id string, index int, at time.Time) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return domain.ErrStopNotFound
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	path := fmt.Sprintf("{%d,arrivedAt}", index)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&tripModel{}).
+			Where("id = ? AND jsonb_array_length(COALESCE(stops, '[]'::jsonb)) > ? AND stops -> CAST(? AS int) ->> 'arrivedAt' IS NULL", uid, index, index).
+			Updates(map[string]any{
+				"stops":      gorm.Expr("jsonb_set(stops, ?::text[], to_jsonb(?::text))", path, at.UTC().Format(time.RFC3339Nano)),
+				"updated_at": at,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var model tripModel
+			if err := tx.Select("id", "stops").First(&model, "id = ?", uid).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return domain.ErrTripNotFound
+				}
+				return err
+			}
+			if _, ok := toTripDomain(&model).Stop(index); !ok {
+				return domain.ErrStopNotFound
+			}
+			return domain.ErrStopAlreadyReached
+		}
+
+		var model tripModel
+		if err := tx.Select("id", "stops").First(&model, "id = ?", uid).Error; err != nil {
+			return err
+		}
+		stop, _ := toTripDomain(&model).Stop(index)
+		payload, err := json.Marshal(stop)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&tripEventModel{
+			ID:        uuid.New(),
+			TripID:    uid,
+			Type:      string(domain.TripEventStop),
+			Payload:   payload,
+			CreatedAt: at,
+		}).Error
+	})
+}
+
 func (r *tripRepository) SaveLocation(tripID string, update domain.LocationUpdate) error {
 	uid, err := uuid.Parse(tripID)
 	if err != nil {
@@ -214,7 +484,7 @@ func (r *tripRepository) SaveLocation(tripID string, update domain.LocationUpdat
 	event := tripEventModel{
 		ID:        uuid.New(),
 		TripID:    uid,
-		Type:      "location",
+		Type:      string(domain.TripEventLocation),
 		Payload:   payload,
 		CreatedAt: update.Timestamp,
 	}
@@ -234,7 +504,7 @@ func (r *tripRepository) GetLatestLocation(tripID string) (*domain.LocationUpdat
 
 	var event tripEventModel
 	err = r.reader().
-		Where("trip_id = ? AND type = ?", uid, "location").
+		Where("trip_id = ? AND type = ?", uid, string(domain.TripEventLocation)).
 		Order("created_at DESC").
 		First(&event).Error
 	if err != nil {
@@ -255,6 +525,91 @@ func (r *tripRepository) GetLatestLocation(tripID string) (*domain.LocationUpdat
 	return &update, nil
 }
 
+// ListLocations returns the location trail recorded between from and to, oldest
+// first. It reads from the primary so the latest pings are never missed.
+func (r *tripRepository) ListLocations(tripID string, from, to time.Time) ([]domain.LocationUpdate, error) {
+	uid, err := uuid.Parse(tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []tripEventModel
+	if err := r.db.
+		Where("trip_id = ? AND type = ? AND created_at BETWEEN ? AND ?", uid, string(domain.TripEventLocation), from, to).
+		Order("created_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	updates := make([]domain.LocationUpdate, 0, len(events))
+	for _, event := range events {
+		var update domain.LocationUpdate
+		if err := json.Unmarshal(event.Payload, &update); err != nil {
+			return nil, err
+		}
+		if update.Timestamp.IsZero() {
+			update.Timestamp = event.CreatedAt
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// AppendTripEvent stores a typed event on the trip's history.
+func (r *tripRepository) AppendTripEvent(event domain.TripEvent) error {
+	uid, err := uuid.Parse(event.TripID)
+	if err != nil {
+		return err
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	return r.db.Create(&tripEventModel{
+		ID:        uuid.New(),
+		TripID:    uid,
+		Type:      string(event.Type),
+		Payload:   event.Payload,
+		CreatedAt: event.CreatedAt,
+	}).Error
+}
+
+// ListTripEvents returns the trip's history oldest first, optionally limited to
+// some event types. Events recorded at the same instant keep insertion order.
+func (r *tripRepository) ListTripEvents(tripID string, types []domain.TripEventType, limit int) ([]domain.TripEvent, error) {
+	uid, err := uuid.Parse(tripID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+
+	query := r.reader().Where("trip_id = ?", uid)
+	if len(types) > 0 {
+		names := make([]string, 0, len(types))
+		for _, eventType := range types {
+			names = append(names, string(eventType))
+		}
+		query = query.Where("type IN ?", names)
+	}
+	var models []tripEventModel
+	if err := query.Order("created_at ASC, seq ASC").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]domain.TripEvent, 0, len(models))
+	for _, model := range models {
+		events = append(events, domain.TripEvent{
+			ID:        model.ID.String(),
+			TripID:    model.TripID.String(),
+			Type:      domain.TripEventType(model.Type),
+			Payload:   model.Payload,
+			CreatedAt: model.CreatedAt,
+		})
+	}
+	return events, nil
+}
+
 func (r *tripRepository) ListTrips(userID string, role string, limit, offset int) ([]*domain.Trip, int64, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20
@@ -287,27 +642,116 @@ func (r *tripRepository) ListTrips(userID string, role string, limit, offset int
 
 	trips := make([]*domain.Trip, 0, len(models))
 	for _, model := range models {
-		trip := &domain.Trip{
-			ID:         model.ID.String(),
-			RiderID:    model.RiderID,
-			DriverID:   model.DriverID,
-			ServiceID:  model.ServiceID,
-			OriginText: model.OriginText,
-			DestText:   model.DestText,
-			OriginLat:  model.OriginLat,
-			OriginLng:  model.OriginLng,
-			DestLat:    model.DestLat,
-			DestLng:    model.DestLng,
-			Status:     domain.TripStatus(model.Status),
-			CreatedAt:  model.CreatedAt,
-			UpdatedAt:  model.UpdatedAt,
-		}
-		trips = append(trips, trip)
+		trips = append(trips, toTripDomain(&model))
 	}
 
 	return trips, total, nil
 }
 
+// ListScheduledTrips returns the rider's scheduled trips, soonest pickup first.
+func (r *tripRepository) ListScheduledTrips(riderID string, limit int) ([]*domain.Trip, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	var models []tripModel
+	if err := r.reader().
+		Where("rider_id = ? AND status = ?", riderID, string(domain.TripStatusScheduled)).
+		Order("scheduled_at ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return toTripDomains(models), nil
+}
+
+// ListDueScheduledTrips returns scheduled trips with a pickup at or before the
+// given time. It reads the primary so just-cancelled trips are not released.
+func (r *tripRepository) ListDueScheduledTrips(before time.Time, limit int) ([]*domain.Trip, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var models []tripModel
+	if err := r.db.
+		Where("status = ? AND scheduled_at <= ?", string(domain.TripStatusScheduled), before).
+		Order("scheduled_at ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return toTripDomains(models), nil
+}
+
+// ClaimFareCharge stamps fare_charged_at on a completed trip not charged yet.
+func (r *tripRepository) ClaimFareCharge(id string, at time.Time) (bool, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return false, err
+	}
+	res := r.db.Model(&tripModel{}).
+		Where("id = ? AND status = ? AND fare_charged_at IS NULL", uid, string(domain.TripStatusCompleted)).
+		Update("fare_charged_at", at)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ReleaseFareCharge clears fare_charged_at so the trip is charged again.
+func (r *tripRepository) ReleaseFareCharge(id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&tripModel{}).Where("id = ?", uid).Update("fare_charged_at", nil).Error
+}
+
+// ListUnchargedTrips returns completed trips whose fare has not been charged,
+// oldest first. It reads the primary so trips just charged are not retried.
+func (r *tripRepository) ListUnchargedTrips(limit int) ([]*domain.Trip, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var models []tripModel
+	if err := r.db.
+		Where("status = ? AND fare_charged_at IS NULL", string(domain.TripStatusCompleted)).
+		Order("completed_at ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return toTripDomains(models), nil
+}
+
+// BusyDrivers returns which of the given drivers are on an accepted or ongoing
+// trip.
+func (r *tripRepository) BusyDrivers(driverIDs []string) (map[string]struct{}, error) {
+	busy := make(map[string]struct{})
+	if len(driverIDs) == 0 {
+		return busy, nil
+	}
+	var ids []string
+	if err := r.reader().Model(&tripModel{}).
+		Where("driver_id IN ? AND status IN ?", driverIDs, []string{
+			string(domain.TripStatusAccepted), string(domain.TripStatusArriving), string(domain.TripStatusInRide),
+		}).
+		Distinct().
+		Pluck("driver_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		busy[id] = struct{}{}
+	}
+	return busy, nil
+}
+
+func toTripDomains(models []tripModel) []*domain.Trip {
+	trips := make([]*domain.Trip, 0, len(models))
+	for i := range models {
+		trips = append(trips, toTripDomain(&models[i]))
+	}
+	return trips
+}
+
 // PurgeAll deletes all trips and related events (dev/demo only).
 func (r *tripRepository) PurgeAll() error {
 	if r.db == nil {
@@ -0,0 +1,376 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// DispatchConfig tunes how trips are matched with nearby drivers.
+type DispatchConfig struct {
+	InitialRadiusMeters float64
+	MaxRadiusMeters     float64
+	RadiusGrowthFactor  float64
+	CandidateLimit      int
+	// StalenessPenaltyMeters is added to a candidate's distance for every second
+	// since its last location ping, so fresh positions win over stale ones.
+	StalenessPenaltyMeters float64
+	// StalenessPenaltySeconds plays the same role, in seconds of drive time,
+	// when candidates are ranked by drive time.
+	StalenessPenaltySeconds float64
+	// OfferTimeout is how long a driver has to answer an offer before it expires.
+	OfferTimeout      time.Duration
+	OfferPollInterval time.Duration
+	MaxOfferAttempts  int
+}
+
+// DefaultDispatchConfig returns the baseline nearest-driver search settings.
+func DefaultDispatchConfig() DispatchConfig {
+	return DispatchConfig{
+		InitialRadiusMeters:     1000,
+		MaxRadiusMeters:         8000,
+		RadiusGrowthFactor:      2,
+		CandidateLimit:          10,
+		StalenessPenaltyMeters:  5,
+		StalenessPenaltySeconds: 0.5,
+		OfferTimeout:            20 * time.Second,
+		OfferPollInterval:       time.Second,
+		MaxOfferAttempts:        5,
+	}
+}
+
+// DriverServiceOption customises driver service behaviour.
+type DriverServiceOption func(*DriverService)
+
+// WithDispatchConfig overrides the nearest-driver search settings.
+func WithDispatchConfig(cfg DispatchConfig) DriverServiceOption {
+	return func(s *DriverService) {
+		if cfg.InitialRadiusMeters > 0 {
+			s.dispatch.InitialRadiusMeters = cfg.InitialRadiusMeters
+		}
+		if cfg.MaxRadiusMeters > 0 {
+			s.dispatch.MaxRadiusMeters = cfg.MaxRadiusMeters
+		}
+		if s.dispatch.MaxRadiusMeters < s.dispatch.InitialRadiusMeters {
+			s.dispatch.MaxRadiusMeters = s.dispatch.InitialRadiusMeters
+		}
+		if cfg.RadiusGrowthFactor > 1 {
+			s.dispatch.RadiusGrowthFactor = cfg.RadiusGrowthFactor
+		}
+		if cfg.CandidateLimit > 0 {
+			s.dispatch.CandidateLimit = cfg.CandidateLimit
+		}
+		if cfg.StalenessPenaltyMeters >= 0 {
+			s.dispatch.StalenessPenaltyMeters = cfg.StalenessPenaltyMeters
+		}
+		if cfg.StalenessPenaltySeconds > 0 {
+			s.dispatch.StalenessPenaltySeconds = cfg.StalenessPenaltySeconds
+		}
+		if cfg.OfferTimeout > 0 {
+			s.dispatch.OfferTimeout = cfg.OfferTimeout
+		}
+		if cfg.OfferPollInterval > 0 {
+			s.dispatch.OfferPollInterval = cfg.OfferPollInterval
+		}
+		if cfg.MaxOfferAttempts > 0 {
+			s.dispatch.MaxOfferAttempts = cfg.MaxOfferAttempts
+		}
+	}
+}
+
+// MatrixRouteEstimator looks up driving estimates from many sources to many
+// destinations at once, indexed [source][destination]. Nil entries mark pairs
+// with no route.
+type MatrixRouteEstimator interface {
+	EstimateMatrix(ctx context.Context, sources, destinations []Waypoint) ([][]*RouteEstimate, error)
+}
+
+// WithDriveTimeRanking ranks dispatch candidates by their drive time to the
+// pickup instead of the straight-line distance.
+func WithDriveTimeRanking(estimator MatrixRouteEstimator) DriverServiceOption {
+	return func(s *DriverService) {
+		s.driveTimes = estimator
+	}
+}
+
+// DispatchCandidate is an eligible driver ranked for a pickup point.
+// DriveSeconds is set when candidates are ranked by drive time.
+type DispatchCandidate struct {
+	Driver         *Driver
+	DistanceMeters float64
+	DriveSeconds   *float64
+	LocationAge    time.Duration
+	Score          float64
+}
+
+// RankDispatchCandidates searches the GEO index around the trip's pickup with a
+// growing radius and returns drivers eligible for the trip's service ordered by
+// distance and freshness. Drivers listed in exclude are skipped.
+func (s *DriverService) RankDispatchCandidates(ctx context.Context, trip *Trip, exclude map[string]struct{}) ([]*DispatchCandidate, error) {
+	if trip == nil || trip.OriginLat == nil || trip.OriginLng == nil {
+		return nil, ErrNoDriversAvailable
+	}
+	if s.locator == nil {
+		return nil, ErrNoDriversAvailable
+	}
+	cfg := s.dispatch
+	// Drivers rejected in a smaller ring stay rejected in the larger ones.
+	rejected := make(map[string]struct{})
+	radius := cfg.InitialRadiusMeters
+	for {
+		// Widen the result window by the drivers already rejected so they do not
+		// crowd out eligible ones further away.
+		limit := cfg.CandidateLimit + len(rejected) + len(exclude)
+		locations, err := s.locator.Nearby(ctx, *trip.OriginLat, *trip.OriginLng, radius, limit, s.presence.StaleAfter)
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]*DispatchCandidate, 0, len(locations))
+		now := time.Now().UTC()
+		for _, loc := range locations {
+			if loc == nil || loc.DriverID == "" {
+				continue
+			}
+			if _, skip := exclude[loc.DriverID]; skip {
+				continue
+			}
+			if _, skip := rejected[loc.DriverID]; skip {
+				continue
+			}
+			candidate, err := s.dispatchCandidate(ctx, trip, loc, now)
+			if err != nil {
+				return nil, err
+			}
+			if candidate == nil {
+				rejected[loc.DriverID] = struct{}{}
+				continue
+			}
+			candidates = append(candidates, candidate)
+		}
+		if len(candidates) > 0 {
+			s.rankByDriveTime(ctx, trip, candidates)
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return candidates[i].Score < candidates[j].Score
+			})
+			return candidates, nil
+		}
+		if radius >= cfg.MaxRadiusMeters {
+			return nil, ErrNoDriversAvailable
+		}
+		radius *= cfg.RadiusGrowthFactor
+		if radius > cfg.MaxRadiusMeters {
+			radius = cfg.MaxRadiusMeters
+		}
+	}
+}
+
+// rankByDriveTime rescores candidates by their drive time to the pickup plus
+// the staleness penalty, all in one matrix lookup. Candidates with no route go
+// last. If the lookup fails the distance ranking stands.
+func (s *DriverService) rankByDriveTime(ctx context.Context, trip *Trip, candidates []*DispatchCandidate) {
+	if s.driveTimes == nil {
+		return
+	}
+	sources := make([]Waypoint, 0, len(candidates))
+	for _, candidate := range candidates {
+		sources = append(sources, Waypoint{Lat: candidate.Driver.Location.Latitude, Lng: candidate.Driver.Location.Longitude})
+	}
+	pickup := []Waypoint{{Lat: *trip.OriginLat, Lng: *trip.OriginLng}}
+	estimates, err := s.driveTimes.EstimateMatrix(ctx, sources, pickup)
+	if err != nil {
+		log.Printf("rank candidates for trip %s by drive time: %v", trip.ID, err)
+		return
+	}
+	for i, candidate := range candidates {
+		if i >= len(estimates) || len(estimates[i]) == 0 || estimates[i][0] == nil {
+			candidate.Score = math.Inf(1)
+			continue
+		}
+		drive := estimates[i][0].DurationSeconds
+		candidate.DriveSeconds = &drive
+		candidate.Score = drive + candidate.LocationAge.Seconds()*s.dispatch.StalenessPenaltySeconds
+	}
+}
+
+// dispatchCandidate returns nil when the driver cannot serve the trip's
+// service, is offline or is busy with another trip.
+func (s *DriverService) dispatchCandidate(ctx context.Context, trip *Trip, loc *DriverLocation, now time.Time) (*DispatchCandidate, error) {
+	driver, err := s.drivers.FindByID(ctx, loc.DriverID)
+	if err != nil {
+		if err == ErrDriverNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !s.CanServe(driver, trip.ServiceID) {
+		return nil, nil
+	}
+	status, err := s.drivers.GetAvailability(ctx, driver.ID)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil || status.Availability != DriverOnline {
+		return nil, nil
+	}
+	active, err := s.assignments.FindActiveByDriver(ctx, driver.ID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil && active.TripID != trip.ID {
+		return nil, nil
+	}
+
+	distance := 0.0
+	if loc.DistanceMeters != nil {
+		distance = *loc.DistanceMeters
+	}
+	age := time.Duration(0)
+	if !loc.RecordedAt.IsZero() && now.After(loc.RecordedAt) {
+		age = now.Sub(loc.RecordedAt)
+	}
+	locationCopy := *loc
+	driver.Status = status
+	driver.Location = &locationCopy
+	return &DispatchCandidate{
+		Driver:         driver,
+		DistanceMeters: distance,
+		LocationAge:    age,
+		Score:          distance + age.Seconds()*s.dispatch.StalenessPenaltyMeters,
+	}, nil
+}
+
+// DispatchTrip offers the trip to one driver at a time, best-ranked first. Each
+// driver gets OfferTimeout to accept; declined and expired offers move on to the
+// next candidate, never re-offering the same driver. After MaxOfferAttempts the
+// trip is marked no_driver_found and ErrNoDriversAvailable is returned. A nil
+// driver with a nil error means the trip left the requested state elsewhere.
+func (s *DriverService) DispatchTrip(ctx context.Context, tripID string) (*Driver, error) {
+	if tripID == "" {
+		return nil, errors.New("trip id required")
+	}
+	if s.trips == nil {
+		return nil, errors.New("trip repository not configured")
+	}
+	tried := make(map[string]struct{})
+	for attempt := 0; attempt < s.dispatch.MaxOfferAttempts; attempt++ {
+		trip, err := s.trips.GetTrip(tripID)
+		if err != nil {
+			return nil, err
+		}
+		if trip.Status != TripStatusRequested {
+			return nil, nil
+		}
+		driver, err := s.nextDispatchDriver(ctx, trip, tried)
+		if errors.Is(err, ErrNoDriversAvailable) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tried[driver.ID] = struct{}{}
+		if _, err := s.AssignTrip(ctx, tripID, driver.ID); err != nil {
+			if errors.Is(err, ErrDriverOffline) || errors.Is(err, ErrAssignmentConflict) || errors.Is(err, ErrDriverIneligible) {
+				continue
+			}
+			return nil, err
+		}
+		expiresAt := time.Now().UTC().Add(s.dispatch.OfferTimeout)
+		s.publishOffer(ctx, DriverOffer{TripID: tripID, DriverID: driver.ID, Status: TripAssignmentPending, Trip: trip, ExpiresAt: &expiresAt})
+		status, err := s.awaitOffer(ctx, tripID, driver.ID)
+		if err != nil {
+			return nil, err
+		}
+		s.publishOffer(ctx, DriverOffer{TripID: tripID, DriverID: driver.ID, Status: status})
+		switch status {
+		case TripAssignmentAccepted:
+			return driver, nil
+		case TripAssignmentCancelled:
+			// The offer was superseded, e.g. an operator assigned another driver.
+			return nil, nil
+		}
+		if err := s.trips.SetTripDriver(tripID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	trip, err := s.trips.GetTrip(tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip.Status != TripStatusRequested {
+		return nil, nil
+	}
+	if err := s.transitionTrip(trip, TripStatusChange{To: TripStatusNoDriverFound, Actor: ActorSystem, Reason: "no driver accepted the trip"}); err != nil {
+		return nil, err
+	}
+	return nil, ErrNoDriversAvailable
+}
+
+// nextDispatchDriver picks the best driver not in exclude. Without a GEO index or
+// pickup coordinates it falls back to the next online driver, if they can serve
+// the trip's service.
+func (s *DriverService) nextDispatchDriver(ctx context.Context, trip *Trip, exclude map[string]struct{}) (*Driver, error) {
+	if s.locator != nil && trip != nil && trip.OriginLat != nil && trip.OriginLng != nil {
+		candidates, err := s.RankDispatchCandidates(ctx, trip, exclude)
+		if err != nil {
+			return nil, err
+		}
+		return candidates[0].Driver, nil
+	}
+	serviceID := ""
+	if trip != nil {
+		serviceID = trip.ServiceID
+	}
+	return s.FindAvailableDriver(ctx, serviceID, exclude)
+}
+
+// awaitOffer waits for the driver to answer a pending offer and expires it once
+// OfferTimeout elapses.
+func (s *DriverService) awaitOffer(ctx context.Context, tripID, driverID string) (TripAssignmentStatus, error) {
+	deadline := time.NewTimer(s.dispatch.OfferTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(s.dispatch.OfferPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-deadline.C:
+			now := time.Now().UTC()
+			assignment, err := s.assignments.ResolvePending(ctx, tripID, driverID, TripAssignmentExpired, &now)
+			if errors.Is(err, ErrTripAssignmentNotFound) {
+				// The driver answered right before the deadline.
+				return s.offerStatus(ctx, tripID, driverID)
+			}
+			if err != nil {
+				return "", err
+			}
+			s.recordTripEvent(tripID, TripEventAssignment, TripAssignmentEvent{DriverID: driverID, Status: assignment.Status})
+			return assignment.Status, nil
+		case <-ticker.C:
+			status, err := s.offerStatus(ctx, tripID, driverID)
+			if err != nil {
+				return "", err
+			}
+			if status != TripAssignmentPending {
+				return status, nil
+			}
+		}
+	}
+}
+
+// offerStatus reports the driver's offer state, treating an offer that moved to
+// another driver as cancelled.
+func (s *DriverService) offerStatus(ctx context.Context, tripID, driverID string) (TripAssignmentStatus, error) {
+	assignment, err := s.assignments.GetByTripID(ctx, tripID)
+	if err != nil {
+		return "", err
+	}
+	if assignment == nil || assignment.DriverID != driverID {
+		return TripAssignmentCancelled, nil
+	}
+	return assignment.Status, nil
+}
@@ -13,33 +13,38 @@ const (
 	DriverOnline  DriverAvailability = "online"
 )
 
-// Driver captures driver profile & vehicle snapshot.
+// Driver captures driver profile & vehicle snapshot. EligibleServices is the
+// admin-managed list of service IDs the driver may serve; when empty,
+// eligibility follows the vehicle.
 type Driver struct {
-	ID            string          `json:"id"`
-	UserID        string          `json:"userId"`
-	FullName      string          `json:"fullName"`
-	Phone         string          `json:"phone"`
-	LicenseNumber string          `json:"licenseNumber"`
-	AvatarURL     *string         `json:"avatarUrl,omitempty"`
-	Rating        float64         `json:"rating"`
-	CreatedAt     time.Time       `json:"createdAt"`
-	UpdatedAt     time.Time       `json:"updatedAt"`
-	Vehicle       *Vehicle        `json:"vehicle,omitempty"`
-	Status        *DriverStatus   `json:"status,omitempty"`
-	Location      *DriverLocation `json:"location,omitempty"`
+	ID               string          `json:"id"`
+	UserID           string          `json:"userId"`
+	FullName         string          `json:"fullName"`
+	Phone            string          `json:"phone"`
+	LicenseNumber    string          `json:"licenseNumber"`
+	AvatarURL        *string         `json:"avatarUrl,omitempty"`
+	Rating           float64         `json:"rating"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+	Vehicle          *Vehicle        `json:"vehicle,omitempty"`
+	EligibleServices []string        `json:"eligibleServices,omitempty"`
+	Status           *DriverStatus   `json:"status,omitempty"`
+	Location         *DriverLocation `json:"location,omitempty"`
 }
 
-// Vehicle stores driver vehicle information.
+// Vehicle stores driver vehicle information. Capacity counts rider seats.
 type Vehicle struct {
-	ID          string    `json:"id"`
-	DriverID    string    `json:"driverId"`
-	Make        string    `json:"make"`
-	Model       string    `json:"model"`
-	Color       string    `json:"color"`
-	Year        int       `json:"year"`
-	PlateNumber string    `json:"plateNumber"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          string          `json:"id"`
+	DriverID    string          `json:"driverId"`
+	Make        string          `json:"make"`
+	Model       string          `json:"model"`
+	Color       string          `json:"color"`
+	Year        int             `json:"year"`
+	PlateNumber string          `json:"plateNumber"`
+	Category    VehicleCategory `json:"category,omitempty"`
+	Capacity    int             `json:"capacity,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
 }
 
 // DriverStatus records online/offline state.
@@ -70,6 +75,7 @@ const (
 	TripAssignmentAccepted  TripAssignmentStatus = "accepted"
 	TripAssignmentDeclined  TripAssignmentStatus = "declined"
 	TripAssignmentCancelled TripAssignmentStatus = "cancelled"
+	TripAssignmentExpired   TripAssignmentStatus = "expired"
 )
 
 // TripAssignment links a driver to a trip.
@@ -90,26 +96,37 @@ type DriverRepository interface {
 	Update(ctx context.Context, driver *Driver) error
 	FindByID(ctx context.Context, id string) (*Driver, error)
 	FindByUserID(ctx context.Context, userID string) (*Driver, error)
-	FindAvailable(ctx context.Context) (*Driver, error)
+	// ListAvailable returns up to limit online drivers without an active
+	// assignment, longest idle first.
+	ListAvailable(ctx context.Context, limit int) ([]*Driver, error)
 	SaveVehicle(ctx context.Context, vehicle *Vehicle) (*Vehicle, error)
 	FindVehicle(ctx context.Context, driverID string) (*Vehicle, error)
+	SetEligibleServices(ctx context.Context, driverID string, services []string) error
 	SetAvailability(ctx context.Context, driverID string, availability DriverAvailability) (*DriverStatus, error)
 	GetAvailability(ctx context.Context, driverID string) (*DriverStatus, error)
 	RecordLocation(ctx context.Context, driverID string, location *DriverLocation) error
 	LatestLocation(ctx context.Context, driverID string) (*DriverLocation, error)
 }
 
-// DriverLocationIndex stores and queries geospatial coordinates.
+// DriverLocationIndex stores and queries geospatial coordinates. Nearby skips
+// locations older than maxAge unless it is zero. Evict drops a driver from
+// searches but keeps their last location time for Stale, which lists drivers
+// whose last location was recorded before olderThan.
 type DriverLocationIndex interface {
 	Upsert(ctx context.Context, driverID string, location *DriverLocation) error
 	Remove(ctx context.Context, driverID string) error
-	Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int) ([]*DriverLocation, error)
+	Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int, maxAge time.Duration) ([]*DriverLocation, error)
+	Evict(ctx context.Context, driverID string) (bool, error)
+	Stale(ctx context.Context, olderThan time.Time) ([]*DriverLocation, error)
 }
 
 // TripAssignmentRepository handles driver-trip links.
 type TripAssignmentRepository interface {
 	Assign(ctx context.Context, tripID, driverID string) (*TripAssignment, error)
 	UpdateStatus(ctx context.Context, tripID, driverID string, status TripAssignmentStatus, respondedAt *time.Time) (*TripAssignment, error)
+	// ResolvePending moves a pending offer to status and returns ErrTripAssignmentNotFound
+	// when the driver no longer holds a pending offer for the trip.
+	ResolvePending(ctx context.Context, tripID, driverID string, status TripAssignmentStatus, respondedAt *time.Time) (*TripAssignment, error)
 	GetByTripID(ctx context.Context, tripID string) (*TripAssignment, error)
 	FindActiveByDriver(ctx context.Context, driverID string) (*TripAssignment, error)
 	Clear(ctx context.Context, tripID string) error
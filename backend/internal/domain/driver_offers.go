@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DriverOffer tells a driver about a trip offered by dispatch and, later, how
+// the offer was resolved. Pending offers carry the trip and their expiry.
+type DriverOffer struct {
+	TripID    string
+	DriverID  string
+	Status    TripAssignmentStatus
+	Trip      *Trip
+	ExpiresAt *time.Time
+}
+
+// DriverOfferPublisher pushes offers to a driver's live session.
+type DriverOfferPublisher interface {
+	PublishDriverOffer(ctx context.Context, offer DriverOffer) error
+}
+
+// WithOfferPublisher pushes dispatch offers to connected drivers through
+// publisher, alongside the push notification.
+func WithOfferPublisher(publisher DriverOfferPublisher) DriverServiceOption {
+	return func(s *DriverService) {
+		s.offers = publisher
+	}
+}
+
+func (s *DriverService) publishOffer(ctx context.Context, offer DriverOffer) {
+	if s.offers == nil {
+		return
+	}
+	if err := s.offers.PublishDriverOffer(ctx, offer); err != nil {
+		log.Printf("publish offer for trip %s to driver %s: %v", offer.TripID, offer.DriverID, err)
+	}
+}
+
+// PendingOffer returns the offer the driver has yet to answer, or nil. Its
+// expiry is counted from when the offer was made.
+func (s *DriverService) PendingOffer(ctx context.Context, driverID string) (*DriverOffer, error) {
+	assignment, err := s.assignments.FindActiveByDriver(ctx, driverID)
+	if err != nil || assignment == nil || assignment.Status != TripAssignmentPending {
+		return nil, err
+	}
+	offeredAt := assignment.UpdatedAt
+	if offeredAt.IsZero() {
+		offeredAt = assignment.CreatedAt
+	}
+	expiresAt := offeredAt.Add(s.dispatch.OfferTimeout)
+	if !time.Now().Before(expiresAt) {
+		return nil, nil
+	}
+	offer := &DriverOffer{
+		TripID:    assignment.TripID,
+		DriverID:  driverID,
+		Status:    TripAssignmentPending,
+		ExpiresAt: &expiresAt,
+	}
+	if s.trips != nil {
+		if trip, err := s.trips.GetTrip(assignment.TripID); err == nil {
+			offer.Trip = trip
+		}
+	}
+	return offer, nil
+}
+
+// RecordOnlineLocation records a location ping from a driver session. Pings
+// are only taken while the driver is online so offline drivers drop out of
+// the GEO index.
+func (s *DriverService) RecordOnlineLocation(ctx context.Context, driverID string, location *DriverLocation) error {
+	status, err := s.drivers.GetAvailability(ctx, driverID)
+	if err != nil {
+		return err
+	}
+	if status == nil || status.Availability != DriverOnline {
+		return ErrDriverOffline
+	}
+	return s.RecordLocation(ctx, driverID, location)
+}
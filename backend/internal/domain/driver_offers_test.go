@@ -0,0 +1,91 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type recordingOfferPublisher struct {
+	offers []domain.DriverOffer
+}
+
+func (p *recordingOfferPublisher) PublishDriverOffer(ctx context.Context, offer domain.DriverOffer) error {
+	p.offers = append(p.offers, offer)
+	return nil
+}
+
+func TestDispatchTripPublishesOffersAndOutcomes(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+
+	drivers.addDriver("silent", domain.DriverOnline)
+	drivers.addDriver("eager", domain.DriverOnline)
+	locator.add("silent", 100, now)
+	locator.add("eager", 300, now)
+	assignments.onAssign = func(assignment *domain.TripAssignment) {
+		if assignment.DriverID == "eager" {
+			assignment.Status = domain.TripAssignmentAccepted
+		}
+	}
+
+	publisher := &recordingOfferPublisher{}
+	service := domain.NewDriverService(drivers, assignments, trips, nil, locator, fastOffers(3), domain.WithOfferPublisher(publisher))
+	_, err := service.DispatchTrip(context.Background(), trip.ID)
+	require.NoError(t, err)
+
+	require.Len(t, publisher.offers, 4)
+	first := publisher.offers[0]
+	require.Equal(t, "silent", first.DriverID)
+	require.Equal(t, domain.TripAssignmentPending, first.Status)
+	require.Equal(t, trip.ID, first.Trip.ID)
+	require.NotNil(t, first.ExpiresAt)
+	require.Equal(t, domain.TripAssignmentExpired, publisher.offers[1].Status)
+	require.Equal(t, "eager", publisher.offers[2].DriverID)
+	require.Equal(t, domain.TripAssignmentAccepted, publisher.offers[3].Status)
+}
+
+func TestPendingOfferCountsDownFromAssignment(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	trip := newDispatchTrip(trips)
+	drivers.addDriver("d1", domain.DriverOnline)
+
+	service := domain.NewDriverService(drivers, assignments, trips, nil, nil,
+		domain.WithDispatchConfig(domain.DispatchConfig{OfferTimeout: time.Minute}))
+	offer, err := service.PendingOffer(context.Background(), "d1")
+	require.NoError(t, err)
+	require.Nil(t, offer)
+
+	_, err = service.AssignTrip(context.Background(), trip.ID, "d1")
+	require.NoError(t, err)
+	offer, err = service.PendingOffer(context.Background(), "d1")
+	require.NoError(t, err)
+	require.Equal(t, trip.ID, offer.TripID)
+	require.WithinDuration(t, time.Now().Add(time.Minute), *offer.ExpiresAt, 5*time.Second)
+
+	assignments.byTrip[trip.ID].CreatedAt = time.Now().Add(-2 * time.Minute)
+	offer, err = service.PendingOffer(context.Background(), "d1")
+	require.NoError(t, err)
+	require.Nil(t, offer)
+}
+
+func TestRecordOnlineLocationRejectsOfflineDrivers(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	drivers.addDriver("on", domain.DriverOnline)
+	drivers.addDriver("off", domain.DriverOffline)
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), newStubRepo(), nil, nil)
+
+	location := &domain.DriverLocation{Latitude: 10.87, Longitude: 106.80, RecordedAt: time.Now().UTC()}
+	require.NoError(t, service.RecordOnlineLocation(context.Background(), "on", location))
+	require.ErrorIs(t, service.RecordOnlineLocation(context.Background(), "off", location), domain.ErrDriverOffline)
+}
@@ -33,17 +33,32 @@ type DriverService struct {
 	trips       TripSyncRepository
 	notifier    TripEventNotifier
 	locator     DriverLocationIndex
+	offers      DriverOfferPublisher
+	driveTimes  MatrixRouteEstimator
+	dispatch    DispatchConfig
+	presence    PresenceConfig
+	// requirements maps service IDs to the vehicle they are dispatched to.
+	requirements map[string]VehicleRequirement
 }
 
 // NewDriverService wires repositories for driver operations.
-func NewDriverService(drivers DriverRepository, assignments TripAssignmentRepository, trips TripSyncRepository, notifier TripEventNotifier, locator DriverLocationIndex) *DriverService {
-	return &DriverService{
-		drivers:     drivers,
-		assignments: assignments,
-		trips:       trips,
-		notifier:    notifier,
-		locator:     locator,
+func NewDriverService(drivers DriverRepository, assignments TripAssignmentRepository, trips TripSyncRepository, notifier TripEventNotifier, locator DriverLocationIndex, opts ...DriverServiceOption) *DriverService {
+	service := &DriverService{
+		drivers:      drivers,
+		assignments:  assignments,
+		trips:        trips,
+		notifier:     notifier,
+		locator:      locator,
+		dispatch:     DefaultDispatchConfig(),
+		presence:     DefaultPresenceConfig(),
+		requirements: DefaultVehicleRequirements(),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(service)
+		}
 	}
+	return service
 }
 
 // Register creates a driver profile for the authenticated user.
@@ -55,6 +70,10 @@ func (s *DriverService) Register(ctx context.Context, userID string, input Drive
 	if fullName == "" {
 		return nil, errors.New("full name required")
 	}
+	vehicle := sanitizeVehicle(input.Vehicle)
+	if err := validateVehicle(vehicle); err != nil {
+		return nil, err
+	}
 	if existing, err := s.drivers.FindByUserID(ctx, userID); err == nil && existing != nil {
 		return nil, ErrDriverAlreadyExists
 	} else if err != nil && err != ErrDriverNotFound {
@@ -74,10 +93,10 @@ func (s *DriverService) Register(ctx context.Context, userID string, input Drive
 		return nil, err
 	}
 
-	if input.Vehicle != nil {
-		input.Vehicle.DriverID = driver.ID
-		if vehicle, err := s.drivers.SaveVehicle(ctx, sanitizeVehicle(input.Vehicle)); err == nil {
-			driver.Vehicle = vehicle
+	if vehicle != nil {
+		vehicle.DriverID = driver.ID
+		if saved, err := s.drivers.SaveVehicle(ctx, vehicle); err == nil {
+			driver.Vehicle = saved
 		} else {
 			// rollback the driver record to avoid orphan if vehicle fails
 			_ = s.drivers.DeleteByID(ctx, driver.ID)
@@ -110,6 +129,10 @@ func (s *DriverService) UpdateProfile(ctx context.Context, userID string, update
 	if userID == "" {
 		return nil, errors.New("user id required")
 	}
+	vehicle := sanitizeVehicle(updates.Vehicle)
+	if err := validateVehicle(vehicle); err != nil {
+		return nil, err
+	}
 	driver, err := s.drivers.FindByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -134,13 +157,13 @@ func (s *DriverService) UpdateProfile(ctx context.Context, userID string, update
 		return nil, err
 	}
 
-	if updates.Vehicle != nil {
-		updates.Vehicle.DriverID = driver.ID
-		vehicle, err := s.drivers.SaveVehicle(ctx, sanitizeVehicle(updates.Vehicle))
+	if vehicle != nil {
+		vehicle.DriverID = driver.ID
+		saved, err := s.drivers.SaveVehicle(ctx, vehicle)
 		if err != nil {
 			return nil, err
 		}
-		driver.Vehicle = vehicle
+		driver.Vehicle = saved
 	} else if driver.Vehicle == nil {
 		driver.Vehicle, _ = s.drivers.FindVehicle(ctx, driver.ID)
 	}
@@ -191,22 +214,38 @@ func (s *DriverService) RecordLocation(ctx context.Context, driverID string, loc
 	return nil
 }
 
-// FindAvailableDriver returns the next online driver without an active assignment.
-func (s *DriverService) FindAvailableDriver(ctx context.Context) (*Driver, error) {
-	driver, err := s.drivers.FindAvailable(ctx)
+// FindAvailableDriver returns the next online driver without an active
+// assignment who can serve serviceID. Drivers listed in exclude are skipped.
+func (s *DriverService) FindAvailableDriver(ctx context.Context, serviceID string, exclude map[string]struct{}) (*Driver, error) {
+	drivers, err := s.drivers.ListAvailable(ctx, s.dispatch.CandidateLimit+len(exclude))
 	if err != nil {
 		return nil, err
 	}
-	if driver == nil {
-		return nil, ErrNoDriversAvailable
+	for _, driver := range drivers {
+		if _, skip := exclude[driver.ID]; skip {
+			continue
+		}
+		if !s.CanServe(driver, serviceID) {
+			continue
+		}
+		enrichDriver(ctx, s.drivers, driver)
+		return driver, nil
 	}
-	enrichDriver(ctx, s.drivers, driver)
-	return driver, nil
+	return nil, ErrNoDriversAvailable
 }
 
-// AssignNextAvailableDriver finds an available driver and assigns the trip.
+// AssignNextAvailableDriver assigns the trip to the best-ranked driver near the
+// pickup point. Trips without pickup coordinates, or services running without a
+// GEO index, fall back to the next online driver who can serve the trip.
 func (s *DriverService) AssignNextAvailableDriver(ctx context.Context, tripID string) (*Driver, error) {
-	driver, err := s.FindAvailableDriver(ctx)
+	if tripID == "" {
+		return nil, errors.New("trip id required")
+	}
+	trip, err := s.trips.GetTrip(tripID)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := s.nextDispatchDriver(ctx, trip, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -229,6 +268,9 @@ func (s *DriverService) AssignTrip(ctx context.Context, tripID, driverID string)
 	if err != nil {
 		return nil, err
 	}
+	if !s.CanServe(driver, trip.ServiceID) {
+		return nil, ErrDriverIneligible
+	}
 	status, err := s.drivers.GetAvailability(ctx, driverID)
 	if err != nil {
 		return nil, err
@@ -249,6 +291,7 @@ func (s *DriverService) AssignTrip(ctx context.Context, tripID, driverID string)
 	if err := s.trips.SetTripDriver(tripID, &driverID); err != nil {
 		return nil, err
 	}
+	s.recordTripEvent(tripID, TripEventAssignment, TripAssignmentEvent{DriverID: driverID, Status: assignment.Status})
 	if s.notifier != nil {
 		if err := s.notifier.NotifyDriverTripAssigned(ctx, driver, trip); err != nil {
 			log.Printf("notify driver assignment: %v", err)
@@ -257,12 +300,13 @@ func (s *DriverService) AssignTrip(ctx context.Context, tripID, driverID string)
 	return assignment, nil
 }
 
-// SearchNearbyDrivers finds online drivers near the provided coordinate.
+// SearchNearbyDrivers finds online drivers near the provided coordinate whose
+// location is not stale.
 func (s *DriverService) SearchNearbyDrivers(ctx context.Context, lat, lng, radiusMeters float64, limit int) ([]*Driver, error) {
 	if s.locator == nil {
 		return nil, errors.New("driver locator not configured")
 	}
-	locations, err := s.locator.Nearby(ctx, lat, lng, radiusMeters, limit)
+	locations, err := s.locator.Nearby(ctx, lat, lng, radiusMeters, limit, s.presence.StaleAfter)
 	if err != nil {
 		return nil, err
 	}
@@ -300,41 +344,83 @@ func (s *DriverService) SearchNearbyDrivers(ctx context.Context, lat, lng, radiu
 	return results, nil
 }
 
-// AcceptTrip marks the assignment accepted and updates trip status.
+// AcceptTrip marks the assignment accepted and updates trip status. Offers that
+// expired or were declined can no longer be accepted.
 func (s *DriverService) AcceptTrip(ctx context.Context, tripID, driverID string) (*TripAssignment, error) {
 	now := time.Now().UTC()
-	assignment, err := s.assignments.UpdateStatus(ctx, tripID, driverID, TripAssignmentAccepted, &now)
+	assignment, err := s.assignments.GetByTripID(ctx, tripID)
 	if err != nil {
+		return nil, err
+	}
+	if assignment == nil || assignment.DriverID != driverID {
+		if assignment != nil && (assignment.Status == TripAssignmentPending || assignment.Status == TripAssignmentAccepted) {
+			return nil, ErrAssignmentConflict
+		}
+		// If the trip wasn't assigned yet, assign it to this driver first.
+		if err := s.checkEligible(ctx, tripID, driverID); err != nil {
+			return nil, err
+		}
+		if assignment, err = s.assignments.Assign(ctx, tripID, driverID); err != nil {
+			return nil, err
+		}
+		if err := s.trips.SetTripDriver(tripID, &driverID); err != nil {
+			return nil, err
+		}
+	}
+	switch assignment.Status {
+	case TripAssignmentAccepted:
+	case TripAssignmentPending:
+		assignment, err = s.assignments.ResolvePending(ctx, tripID, driverID, TripAssignmentAccepted, &now)
 		if errors.Is(err, ErrTripAssignmentNotFound) {
-			// If the trip wasn't assigned yet, assign it to this driver first.
-			if _, err := s.assignments.Assign(ctx, tripID, driverID); err != nil {
-				return nil, err
-			}
-			if err := s.trips.SetTripDriver(tripID, &driverID); err != nil {
-				return nil, err
-			}
-			assignment, err = s.assignments.UpdateStatus(ctx, tripID, driverID, TripAssignmentAccepted, &now)
-			if err != nil {
-				return nil, err
-			}
-		} else {
+			return nil, ErrAssignmentExpired
+		}
+		if err != nil {
 			return nil, err
 		}
+	default:
+		return nil, ErrAssignmentExpired
 	}
-	if err := s.trips.UpdateTripStatus(tripID, TripStatusAccepted); err != nil {
+	s.recordTripEvent(tripID, TripEventAssignment, TripAssignmentEvent{DriverID: driverID, Status: TripAssignmentAccepted})
+	trip, err := s.trips.GetTrip(tripID)
+	if err != nil {
 		return nil, err
 	}
+	if trip.Status != TripStatusAccepted {
+		if err := s.transitionTrip(trip, TripStatusChange{To: TripStatusAccepted, Actor: ActorDriver, ActorID: driverID, Reason: "offer accepted"}); err != nil {
+			return nil, err
+		}
+	}
 	return assignment, nil
 }
 
+// checkEligible returns ErrDriverIneligible unless the driver can serve the
+// trip's service.
+func (s *DriverService) checkEligible(ctx context.Context, tripID, driverID string) error {
+	trip, err := s.trips.GetTrip(tripID)
+	if err != nil {
+		return err
+	}
+	driver, err := s.drivers.FindByID(ctx, driverID)
+	if err != nil {
+		return err
+	}
+	if !s.CanServe(driver, trip.ServiceID) {
+		return ErrDriverIneligible
+	}
+	return nil
+}
+
 // DeclineTrip releases the driver from the assignment.
 func (s *DriverService) DeclineTrip(ctx context.Context, tripID, driverID string) (*TripAssignment, error) {
 	now := time.Now().UTC()
 	assignment, err := s.assignments.UpdateStatus(ctx, tripID, driverID, TripAssignmentDeclined, &now)
 	if err != nil {
 		if errors.Is(err, ErrTripAssignmentNotFound) {
-			// Gracefully handle missing assignment (e.g., trip not actually assigned).
-			_ = s.trips.SetTripDriver(tripID, nil)
+			// Gracefully handle missing assignment (e.g., trip not actually assigned
+			// or already offered to someone else); only unlink this driver.
+			if trip, err := s.trips.GetTrip(tripID); err == nil && trip.DriverID != nil && *trip.DriverID == driverID {
+				_ = s.trips.SetTripDriver(tripID, nil)
+			}
 			return nil, nil
 		}
 		return nil, err
@@ -342,11 +428,14 @@ func (s *DriverService) DeclineTrip(ctx context.Context, tripID, driverID string
 	if err := s.trips.SetTripDriver(tripID, nil); err != nil {
 		return nil, err
 	}
+	s.recordTripEvent(tripID, TripEventDecline, TripAssignmentEvent{DriverID: driverID, Status: TripAssignmentDeclined})
 	return assignment, nil
 }
 
-// UpdateTripStatus allows drivers to move trip through arriving/in_ride/completed/cancelled.
-func (s *DriverService) UpdateTripStatus(ctx context.Context, tripID, driverID string, next TripStatus) (*Trip, error) {
+// UpdateTripStatus allows drivers to move trip through arriving/in_ride/completed/cancelled
+// as far as the trip lifecycle table lets a driver. Only the driver holding the
+// trip's accepted assignment may do so.
+func (s *DriverService) UpdateTripStatus(ctx context.Context, tripID, driverID string, next TripStatus, reason string) (*Trip, error) {
 	if next != TripStatusArriving && next != TripStatusInRide && next != TripStatusCompleted && next != TripStatusCancelled {
 		return nil, ErrInvalidStatus
 	}
@@ -354,37 +443,20 @@ func (s *DriverService) UpdateTripStatus(ctx context.Context, tripID, driverID s
 	if err != nil {
 		return nil, err
 	}
-	// Auto-assign driver if missing/mismatched to keep flows working in demo.
-	if trip.DriverID == nil || *trip.DriverID != driverID {
-		if _, err := s.assignments.Assign(ctx, tripID, driverID); err != nil {
-			return nil, err
-		}
-		if err := s.trips.SetTripDriver(tripID, &driverID); err != nil {
-			return nil, err
-		}
-		trip.DriverID = &driverID
-	}
-	if !allowedDriverTransition(trip.Status, next) {
-		return nil, ErrInvalidStatus
+	if err := ValidateTransition(trip.Status, next, ActorDriver); err != nil {
+		return nil, err
 	}
+	// Only the driver who accepted the trip may move it on.
 	assignment, err := s.assignments.GetByTripID(ctx, tripID)
 	if err != nil {
 		return nil, err
 	}
-	if assignment == nil || assignment.DriverID != driverID {
-		if assignment, err = s.assignments.Assign(ctx, tripID, driverID); err != nil {
-			return nil, err
-		}
-	}
-	// Promote assignment to accepted so driver can progress directly.
-	if next != TripStatusCancelled && assignment.Status != TripAssignmentAccepted {
-		if _, err := s.assignments.UpdateStatus(ctx, tripID, driverID, TripAssignmentAccepted, nil); err != nil {
-			return nil, err
-		}
-		assignment.Status = TripAssignmentAccepted
+	if trip.DriverID == nil || *trip.DriverID != driverID ||
+		assignment == nil || assignment.DriverID != driverID || assignment.Status != TripAssignmentAccepted {
+		return nil, ErrTransitionNotPermitted
 	}
 
-	if err := s.trips.UpdateTripStatus(tripID, next); err != nil {
+	if err := s.transitionTrip(trip, TripStatusChange{To: next, Actor: ActorDriver, ActorID: driverID, Reason: reason}); err != nil {
 		return nil, err
 	}
 
@@ -392,7 +464,6 @@ func (s *DriverService) UpdateTripStatus(ctx context.Context, tripID, driverID s
 		_, _ = s.assignments.UpdateStatus(ctx, tripID, driverID, TripAssignmentCancelled, nil)
 	}
 
-	trip.Status = next
 	if s.notifier != nil {
 		if err := s.notifier.NotifyRiderStatusChange(ctx, trip, next); err != nil {
 			log.Printf("notify rider status: %v", err)
@@ -409,6 +480,63 @@ func (s *DriverService) ClearAssignments(ctx context.Context) error {
 	return s.assignments.ClearAll(ctx)
 }
 
+// transitionTrip validates the change against the trip lifecycle, applies it
+// from the trip's current status and records the timestamps it implies.
+func (s *DriverService) transitionTrip(trip *Trip, change TripStatusChange) error {
+	change.From = trip.Status
+	if err := ValidateTransition(change.From, change.To, change.Actor); err != nil {
+		return err
+	}
+	if change.At.IsZero() {
+		change.At = time.Now().UTC()
+	}
+	if err := s.trips.TransitionTrip(trip.ID, change); err != nil {
+		return err
+	}
+	if progress := driverTripProgress(trip, change.To); !progress.IsZero() {
+		if err := s.trips.SaveTripProgress(trip.ID, progress); err != nil {
+			return err
+		}
+		progress.ApplyTo(trip)
+	}
+	trip.Status = change.To
+	return nil
+}
+
+// recordTripEvent appends to the trip history, logging rather than failing the
+// operation when the history cannot be written.
+func (s *DriverService) recordTripEvent(tripID string, eventType TripEventType, payload any) {
+	event, err := NewTripEvent(tripID, eventType, payload, time.Now().UTC())
+	if err == nil {
+		err = s.trips.AppendTripEvent(event)
+	}
+	if err != nil {
+		log.Printf("record %s event for trip %s: %v", eventType, tripID, err)
+	}
+}
+
+// driverTripProgress stamps acceptance, ride start and completion. The final
+// fare is left to the trip service, which settles and charges it.
+func driverTripProgress(trip *Trip, next TripStatus) TripProgress {
+	now := time.Now().UTC()
+	var progress TripProgress
+	switch next {
+	case TripStatusAccepted:
+		if trip.AcceptedAt == nil {
+			progress.AcceptedAt = &now
+		}
+	case TripStatusInRide:
+		if trip.StartedAt == nil {
+			progress.StartedAt = &now
+		}
+	case TripStatusCompleted:
+		if trip.CompletedAt == nil {
+			progress.CompletedAt = &now
+		}
+	}
+	return progress
+}
+
 func enrichDriver(ctx context.Context, repo DriverRepository, driver *Driver) {
 	if driver == nil {
 		return
@@ -432,20 +560,3 @@ func sanitizeVehicle(vehicle *Vehicle) *Vehicle {
 	clone.PlateNumber = strings.ToUpper(strings.TrimSpace(clone.PlateNumber))
 	return &clone
 }
-
-func allowedDriverTransition(current, next TripStatus) bool {
-	switch current {
-	case TripStatusAccepted:
-		return next == TripStatusArriving || next == TripStatusCancelled || next == TripStatusCompleted
-	case TripStatusArriving:
-		return next == TripStatusInRide || next == TripStatusCancelled || next == TripStatusCompleted
-	case TripStatusInRide:
-		return next == TripStatusCompleted || next == TripStatusCancelled
-	case TripStatusRequested:
-		return next == TripStatusCancelled || next == TripStatusCompleted
-	case TripStatusCancelled, TripStatusCompleted:
-		return false
-	default:
-		return false
-	}
-}
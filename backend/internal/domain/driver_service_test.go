@@ -0,0 +1,575 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type fakeDriverRepo struct {
+	drivers  map[string]*domain.Driver
+	statuses map[string]domain.DriverAvailability
+}
+
+var _ domain.DriverRepository = (*fakeDriverRepo)(nil)
+
+func newFakeDriverRepo() *fakeDriverRepo {
+	return &fakeDriverRepo{
+		drivers:  make(map[string]*domain.Driver),
+		statuses: make(map[string]domain.DriverAvailability),
+	}
+}
+
+func (f *fakeDriverRepo) addDriver(id string, availability domain.DriverAvailability) {
+	f.drivers[id] = &domain.Driver{
+		ID: id, UserID: "user-" + id, FullName: "Driver " + id,
+		Vehicle: &domain.Vehicle{Category: domain.VehicleMotorbike, Capacity: 1},
+	}
+	f.statuses[id] = availability
+}
+
+func (f *fakeDriverRepo) Create(ctx context.Context, driver *domain.Driver) error {
+	f.drivers[driver.ID] = driver
+	return nil
+}
+
+func (f *fakeDriverRepo) DeleteByID(ctx context.Context, driverID string) error {
+	delete(f.drivers, driverID)
+	return nil
+}
+
+func (f *fakeDriverRepo) Update(ctx context.Context, driver *domain.Driver) error {
+	f.drivers[driver.ID] = driver
+	return nil
+}
+
+func (f *fakeDriverRepo) FindByID(ctx context.Context, id string) (*domain.Driver, error) {
+	driver, ok := f.drivers[id]
+	if !ok {
+		return nil, domain.ErrDriverNotFound
+	}
+	clone := *driver
+	return &clone, nil
+}
+
+func (f *fakeDriverRepo) FindByUserID(ctx context.Context, userID string) (*domain.Driver, error) {
+	for _, driver := range f.drivers {
+		if driver.UserID == userID {
+			clone := *driver
+			return &clone, nil
+		}
+	}
+	return nil, domain.ErrDriverNotFound
+}
+
+func (f *fakeDriverRepo) ListAvailable(ctx context.Context, limit int) ([]*domain.Driver, error) {
+	ids := make([]string, 0, len(f.drivers))
+	for id := range f.drivers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var available []*domain.Driver
+	for _, id := range ids {
+		if f.statuses[id] == domain.DriverOnline && len(available) < limit {
+			available = append(available, f.drivers[id])
+		}
+	}
+	return available, nil
+}
+
+func (f *fakeDriverRepo) SaveVehicle(ctx context.Context, vehicle *domain.Vehicle) (*domain.Vehicle, error) {
+	return vehicle, nil
+}
+
+func (f *fakeDriverRepo) FindVehicle(ctx context.Context, driverID string) (*domain.Vehicle, error) {
+	return nil, nil
+}
+
+func (f *fakeDriverRepo) SetEligibleServices(ctx context.Context, driverID string, services []string) error {
+	driver, ok := f.drivers[driverID]
+	if !ok {
+		return domain.ErrDriverNotFound
+	}
+	driver.EligibleServices = services
+	return nil
+}
+
+func (f *fakeDriverRepo) SetAvailability(ctx context.Context, driverID string, availability domain.DriverAvailability) (*domain.DriverStatus, error) {
+	f.statuses[driverID] = availability
+	return &domain.DriverStatus{DriverID: driverID, Availability: availability, UpdatedAt: time.Now().UTC()}, nil
+}
+
+func (f *fakeDriverRepo) GetAvailability(ctx context.Context, driverID string) (*domain.DriverStatus, error) {
+	availability, ok := f.statuses[driverID]
+	if !ok {
+		return nil, nil
+	}
+	return &domain.DriverStatus{DriverID: driverID, Availability: availability}, nil
+}
+
+func (f *fakeDriverRepo) RecordLocation(ctx context.Context, driverID string, location *domain.DriverLocation) error {
+	return nil
+}
+
+func (f *fakeDriverRepo) LatestLocation(ctx context.Context, driverID string) (*domain.DriverLocation, error) {
+	return nil, nil
+}
+
+type fakeAssignmentRepo struct {
+	byTrip   map[string]*domain.TripAssignment
+	onAssign func(assignment *domain.TripAssignment)
+}
+
+var _ domain.TripAssignmentRepository = (*fakeAssignmentRepo)(nil)
+
+func newFakeAssignmentRepo() *fakeAssignmentRepo {
+	return &fakeAssignmentRepo{byTrip: make(map[string]*domain.TripAssignment)}
+}
+
+func (f *fakeAssignmentRepo) Assign(ctx context.Context, tripID, driverID string) (*domain.TripAssignment, error) {
+	assignment := &domain.TripAssignment{
+		ID:        "assignment-" + tripID,
+		TripID:    tripID,
+		DriverID:  driverID,
+		Status:    domain.TripAssignmentPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	f.byTrip[tripID] = assignment
+	if f.onAssign != nil {
+		f.onAssign(assignment)
+	}
+	clone := *assignment
+	return &clone, nil
+}
+
+func (f *fakeAssignmentRepo) UpdateStatus(ctx context.Context, tripID, driverID string, status domain.TripAssignmentStatus, respondedAt *time.Time) (*domain.TripAssignment, error) {
+	assignment, ok := f.byTrip[tripID]
+	if !ok || assignment.DriverID != driverID {
+		return nil, domain.ErrTripAssignmentNotFound
+	}
+	assignment.Status = status
+	assignment.RespondedAt = respondedAt
+	clone := *assignment
+	return &clone, nil
+}
+
+func (f *fakeAssignmentRepo) ResolvePending(ctx context.Context, tripID, driverID string, status domain.TripAssignmentStatus, respondedAt *time.Time) (*domain.TripAssignment, error) {
+	assignment, ok := f.byTrip[tripID]
+	if !ok || assignment.DriverID != driverID || assignment.Status != domain.TripAssignmentPending {
+		return nil, domain.ErrTripAssignmentNotFound
+	}
+	return f.UpdateStatus(ctx, tripID, driverID, status, respondedAt)
+}
+
+func (f *fakeAssignmentRepo) GetByTripID(ctx context.Context, tripID string) (*domain.TripAssignment, error) {
+	assignment, ok := f.byTrip[tripID]
+	if !ok {
+		return nil, nil
+	}
+	clone := *assignment
+	return &clone, nil
+}
+
+func (f *fakeAssignmentRepo) FindActiveByDriver(ctx context.Context, driverID string) (*domain.TripAssignment, error) {
+	for _, assignment := range f.byTrip {
+		if assignment.DriverID != driverID {
+			continue
+		}
+		if assignment.Status == domain.TripAssignmentPending || assignment.Status == domain.TripAssignmentAccepted {
+			return assignment, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeAssignmentRepo) Clear(ctx context.Context, tripID string) error {
+	delete(f.byTrip, tripID)
+	return nil
+}
+
+func (f *fakeAssignmentRepo) ClearAll(ctx context.Context) error {
+	f.byTrip = make(map[string]*domain.TripAssignment)
+	return nil
+}
+
+type fakeLocator struct {
+	locations []*domain.DriverLocation
+	radii     []float64
+	evicted   map[string]bool
+}
+
+var _ domain.DriverLocationIndex = (*fakeLocator)(nil)
+
+func (f *fakeLocator) add(driverID string, distance float64, recordedAt time.Time) {
+	d := distance
+	f.locations = append(f.locations, &domain.DriverLocation{
+		DriverID:       driverID,
+		Latitude:       10.87,
+		Longitude:      106.80,
+		RecordedAt:     recordedAt,
+		DistanceMeters: &d,
+	})
+}
+
+func (f *fakeLocator) Upsert(ctx context.Context, driverID string, location *domain.DriverLocation) error {
+	return nil
+}
+
+func (f *fakeLocator) Remove(ctx context.Context, driverID string) error {
+	kept := f.locations[:0]
+	for _, loc := range f.locations {
+		if loc.DriverID != driverID {
+			kept = append(kept, loc)
+		}
+	}
+	f.locations = kept
+	return nil
+}
+
+func (f *fakeLocator) Evict(ctx context.Context, driverID string) (bool, error) {
+	if f.evicted == nil {
+		f.evicted = map[string]bool{}
+	}
+	if f.evicted[driverID] {
+		return false, nil
+	}
+	f.evicted[driverID] = true
+	return true, nil
+}
+
+func (f *fakeLocator) Stale(ctx context.Context, olderThan time.Time) ([]*domain.DriverLocation, error) {
+	var stale []*domain.DriverLocation
+	for _, loc := range f.locations {
+		if loc.RecordedAt.Before(olderThan) {
+			stale = append(stale, loc)
+		}
+	}
+	return stale, nil
+}
+
+func (f *fakeLocator) Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int, maxAge time.Duration) ([]*domain.DriverLocation, error) {
+	f.radii = append(f.radii, radiusMeters)
+	matches := make([]*domain.DriverLocation, 0, len(f.locations))
+	for _, loc := range f.locations {
+		if f.evicted[loc.DriverID] || (maxAge > 0 && time.Since(loc.RecordedAt) > maxAge) {
+			continue
+		}
+		if *loc.DistanceMeters <= radiusMeters {
+			matches = append(matches, loc)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return *matches[i].DistanceMeters < *matches[j].DistanceMeters
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func newDispatchTrip(repo *stubRepo) *domain.Trip {
+	lat, lng := 10.8700, 106.8030
+	trip := &domain.Trip{
+		ID:         "trip-dispatch",
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &lat,
+		OriginLng:  &lng,
+		Status:     domain.TripStatusRequested,
+	}
+	repo.trips[trip.ID] = trip
+	return trip
+}
+
+func TestAssignNextAvailableDriverPicksNearestFreeDriver(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+
+	drivers.addDriver("busy", domain.DriverOnline)
+	drivers.addDriver("offline", domain.DriverOffline)
+	drivers.addDriver("near", domain.DriverOnline)
+	drivers.addDriver("far", domain.DriverOnline)
+	locator.add("busy", 100, now)
+	locator.add("offline", 150, now)
+	locator.add("near", 2500, now)
+	locator.add("far", 6000, now)
+	_, _ = assignments.Assign(context.Background(), "other-trip", "busy")
+
+	service := domain.NewDriverService(drivers, assignments, trips, nil, locator)
+	driver, err := service.AssignNextAvailableDriver(context.Background(), trip.ID)
+	require.NoError(t, err)
+	require.Equal(t, "near", driver.ID)
+	require.Equal(t, []float64{1000, 2000, 4000}, locator.radii)
+
+	assignment, err := assignments.GetByTripID(context.Background(), trip.ID)
+	require.NoError(t, err)
+	require.Equal(t, "near", assignment.DriverID)
+	require.Equal(t, "near", *trips.trips[trip.ID].DriverID)
+}
+
+func TestRankDispatchCandidatesPrefersFreshLocations(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+
+	drivers.addDriver("stale", domain.DriverOnline)
+	drivers.addDriver("fresh", domain.DriverOnline)
+	locator.add("stale", 200, now.Add(-2*time.Minute))
+	locator.add("fresh", 400, now)
+
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), trips, nil, locator)
+	candidates, err := service.RankDispatchCandidates(context.Background(), trip, nil)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	require.Equal(t, "fresh", candidates[0].Driver.ID)
+	require.Equal(t, "stale", candidates[1].Driver.ID)
+
+	candidates, err = service.RankDispatchCandidates(context.Background(), trip, map[string]struct{}{"fresh": {}})
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	require.Equal(t, "stale", candidates[0].Driver.ID)
+}
+
+func TestRankDispatchCandidatesFiltersByService(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+	ctx := context.Background()
+
+	for id, vehicle := range map[string]*domain.Vehicle{
+		"car":       {Category: domain.VehicleCar, Capacity: 4},
+		"bike":      {Category: domain.VehicleMotorbike, Capacity: 1},
+		"legacy":    {},
+		"allowlist": {Category: domain.VehicleMotorbike, Capacity: 1},
+	} {
+		drivers.addDriver(id, domain.DriverOnline)
+		drivers.drivers[id].Vehicle = vehicle
+	}
+	locator.add("car", 100, now)
+	locator.add("bike", 200, now)
+	locator.add("legacy", 300, now)
+	locator.add("allowlist", 400, now)
+
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), trips, nil, locator)
+	driver, err := service.SetEligibleServices(ctx, "allowlist", []string{"UIT-Rider", "uit-rider"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"uit-rider"}, driver.EligibleServices)
+	_, err = service.SetEligibleServices(ctx, "allowlist", []string{"uit-boat"})
+	require.ErrorIs(t, err, domain.ErrUnknownService)
+
+	// The uit-bike trip skips the car, the motorbike limited to uit-rider and
+	// the vehicle with no category yet.
+	candidates, err := service.RankDispatchCandidates(ctx, trip, nil)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	require.Equal(t, "bike", candidates[0].Driver.ID)
+
+	// uit-plus needs a seven-seat car.
+	trip.ServiceID = "uit-plus"
+	_, err = service.RankDispatchCandidates(ctx, trip, nil)
+	require.ErrorIs(t, err, domain.ErrNoDriversAvailable)
+
+	// Services without a requirement take any vehicle.
+	trip.ServiceID = "uit-xe-om"
+	candidates, err = service.RankDispatchCandidates(ctx, trip, nil)
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+}
+
+// stubDriveTimes answers matrix lookups with one drive time per source, in the
+// order the sources are given. Nil marks a source with no route.
+type stubDriveTimes struct {
+	seconds []*float64
+	err     error
+}
+
+func (s *stubDriveTimes) EstimateMatrix(ctx context.Context, sources, destinations []domain.Waypoint) ([][]*domain.RouteEstimate, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	estimates := make([][]*domain.RouteEstimate, len(sources))
+	for i := range sources {
+		estimates[i] = []*domain.RouteEstimate{nil}
+		if s.seconds[i] != nil {
+			estimates[i][0] = &domain.RouteEstimate{DurationSeconds: *s.seconds[i]}
+		}
+	}
+	return estimates, nil
+}
+
+func TestDriverAssignmentChecksEligibility(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	trips := newStubRepo()
+	trip := newDispatchTrip(trips)
+	ctx := context.Background()
+	drivers.addDriver("a-car", domain.DriverOnline)
+	drivers.drivers["a-car"].Vehicle = &domain.Vehicle{Category: domain.VehicleCar, Capacity: 4}
+	drivers.addDriver("b-bike", domain.DriverOnline)
+
+	// Without a GEO index the online-driver fallback skips the car.
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), trips, nil, nil)
+	driver, err := service.AssignNextAvailableDriver(ctx, trip.ID)
+	require.NoError(t, err)
+	require.Equal(t, "b-bike", driver.ID)
+
+	other := *trip
+	other.ID = "trip-other"
+	other.DriverID = nil
+	trips.trips[other.ID] = &other
+	_, err = service.AssignTrip(ctx, other.ID, "a-car")
+	require.ErrorIs(t, err, domain.ErrDriverIneligible)
+	_, err = service.AcceptTrip(ctx, other.ID, "a-car")
+	require.ErrorIs(t, err, domain.ErrDriverIneligible)
+	require.Nil(t, trips.trips[other.ID].DriverID)
+
+	// Status updates never hand the trip to a driver either.
+	trips.trips[other.ID].Status = domain.TripStatusAccepted
+	_, err = service.UpdateTripStatus(ctx, other.ID, "a-car", domain.TripStatusArriving, "")
+	require.ErrorIs(t, err, domain.ErrTransitionNotPermitted)
+	require.Nil(t, trips.trips[other.ID].DriverID)
+}
+
+func TestRankDispatchCandidatesByDriveTime(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+
+	for id, distance := range map[string]float64{"across-river": 300, "stranded": 600, "same-side": 900} {
+		drivers.addDriver(id, domain.DriverOnline)
+		locator.add(id, distance, now)
+	}
+	slow, fast := 600.0, 120.0
+	driveTimes := &stubDriveTimes{seconds: []*float64{&slow, nil, &fast}}
+
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), trips, nil, locator, domain.WithDriveTimeRanking(driveTimes))
+	candidates, err := service.RankDispatchCandidates(context.Background(), trip, nil)
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+	require.Equal(t, "same-side", candidates[0].Driver.ID)
+	require.InDelta(t, 120, *candidates[0].DriveSeconds, 0.01)
+	require.Equal(t, "across-river", candidates[1].Driver.ID)
+	require.Equal(t, "stranded", candidates[2].Driver.ID)
+	require.Nil(t, candidates[2].DriveSeconds)
+
+	// Without drive times the straight-line ranking stands.
+	driveTimes.err = errors.New("routing down")
+	candidates, err = service.RankDispatchCandidates(context.Background(), trip, nil)
+	require.NoError(t, err)
+	require.Equal(t, "across-river", candidates[0].Driver.ID)
+	require.Nil(t, candidates[0].DriveSeconds)
+}
+
+func TestAssignNextAvailableDriverNoneInRange(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+
+	drivers.addDriver("distant", domain.DriverOnline)
+	locator.add("distant", 20000, time.Now().UTC())
+
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), trips, nil, locator,
+		domain.WithDispatchConfig(domain.DispatchConfig{InitialRadiusMeters: 500, MaxRadiusMeters: 1500}))
+	_, err := service.AssignNextAvailableDriver(context.Background(), trip.ID)
+	require.ErrorIs(t, err, domain.ErrNoDriversAvailable)
+	require.Equal(t, []float64{500, 1000, 1500}, locator.radii)
+}
+
+func fastOffers(attempts int) domain.DriverServiceOption {
+	return domain.WithDispatchConfig(domain.DispatchConfig{
+		OfferTimeout:      30 * time.Millisecond,
+		OfferPollInterval: 5 * time.Millisecond,
+		MaxOfferAttempts:  attempts,
+	})
+}
+
+func TestDispatchTripMovesOnAfterExpiredOffer(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+
+	drivers.addDriver("silent", domain.DriverOnline)
+	drivers.addDriver("eager", domain.DriverOnline)
+	locator.add("silent", 100, now)
+	locator.add("eager", 300, now)
+	assignments.onAssign = func(assignment *domain.TripAssignment) {
+		if assignment.DriverID == "eager" {
+			assignment.Status = domain.TripAssignmentAccepted
+		}
+	}
+
+	service := domain.NewDriverService(drivers, assignments, trips, nil, locator, fastOffers(3))
+	driver, err := service.DispatchTrip(context.Background(), trip.ID)
+	require.NoError(t, err)
+	require.Equal(t, "eager", driver.ID)
+	require.Equal(t, "eager", *trips.trips[trip.ID].DriverID)
+
+	_, err = service.AcceptTrip(context.Background(), trip.ID, "silent")
+	require.ErrorIs(t, err, domain.ErrAssignmentConflict)
+}
+
+func TestDispatchTripGivesUpAfterMaxAttempts(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	locator := &fakeLocator{}
+	trip := newDispatchTrip(trips)
+	now := time.Now().UTC()
+
+	var offered []string
+	for i, id := range []string{"a", "b", "c"} {
+		drivers.addDriver(id, domain.DriverOnline)
+		locator.add(id, float64(100*(i+1)), now)
+	}
+	assignments.onAssign = func(assignment *domain.TripAssignment) {
+		offered = append(offered, assignment.DriverID)
+		assignment.Status = domain.TripAssignmentDeclined
+	}
+
+	service := domain.NewDriverService(drivers, assignments, trips, nil, locator, fastOffers(2))
+	driver, err := service.DispatchTrip(context.Background(), trip.ID)
+	require.ErrorIs(t, err, domain.ErrNoDriversAvailable)
+	require.Nil(t, driver)
+	require.Equal(t, []string{"a", "b"}, offered)
+	require.Equal(t, domain.TripStatusNoDriverFound, trips.statuses[trip.ID])
+	require.Nil(t, trips.trips[trip.ID].DriverID)
+}
+
+func TestAcceptTripRejectsExpiredOffer(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	trip := newDispatchTrip(trips)
+	drivers.addDriver("late", domain.DriverOnline)
+
+	service := domain.NewDriverService(drivers, assignments, trips, nil, nil)
+	_, err := service.AssignTrip(context.Background(), trip.ID, "late")
+	require.NoError(t, err)
+	_, err = assignments.ResolvePending(context.Background(), trip.ID, "late", domain.TripAssignmentExpired, nil)
+	require.NoError(t, err)
+
+	_, err = service.AcceptTrip(context.Background(), trip.ID, "late")
+	require.ErrorIs(t, err, domain.ErrAssignmentExpired)
+	require.Equal(t, domain.TripStatusRequested, trips.trips[trip.ID].Status)
+}
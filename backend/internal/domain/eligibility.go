@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidVehicle indicates an unknown vehicle category or a bad capacity.
+var ErrInvalidVehicle = errors.New("invalid vehicle")
+
+// ErrUnknownService indicates a service ID dispatch does not know about.
+var ErrUnknownService = errors.New("unknown service")
+
+// ErrDriverIneligible indicates the driver may not serve the trip's service.
+var ErrDriverIneligible = errors.New("driver not eligible for this service")
+
+// VehicleCategory groups vehicles by the kind of trips they can serve.
+type VehicleCategory string
+
+const (
+	VehicleMotorbike VehicleCategory = "motorbike"
+	VehicleCar       VehicleCategory = "car"
+)
+
+// maxVehicleCapacity bounds the rider seats a vehicle may declare.
+const maxVehicleCapacity = 16
+
+// VehicleRequirement is what a service needs from a driver's vehicle.
+type VehicleRequirement struct {
+	Category VehicleCategory
+	// MinCapacity is the fewest rider seats the vehicle must have.
+	MinCapacity int
+}
+
+// DefaultVehicleRequirements returns the vehicle each service ID is dispatched to.
+func DefaultVehicleRequirements() map[string]VehicleRequirement {
+	return map[string]VehicleRequirement{
+		"uit-bike":  {Category: VehicleMotorbike, MinCapacity: 1},
+		"uit-rider": {Category: VehicleMotorbike, MinCapacity: 1},
+		"uit-go":    {Category: VehicleCar, MinCapacity: 4},
+		"uit-car":   {Category: VehicleCar, MinCapacity: 4},
+		"uit-plus":  {Category: VehicleCar, MinCapacity: 7},
+	}
+}
+
+// defaultVehicleCapacity is assumed when a vehicle is saved without a capacity.
+func defaultVehicleCapacity(category VehicleCategory) int {
+	switch category {
+	case VehicleMotorbike:
+		return 1
+	case VehicleCar:
+		return 4
+	}
+	return 0
+}
+
+// WithVehicleRequirements overrides the vehicle requirements for the given
+// service IDs.
+func WithVehicleRequirements(requirements map[string]VehicleRequirement) DriverServiceOption {
+	return func(s *DriverService) {
+		for serviceID, requirement := range requirements {
+			s.requirements[normalizeServiceID(serviceID)] = requirement
+		}
+	}
+}
+
+// validateVehicle normalises the category and fills a default capacity.
+func validateVehicle(vehicle *Vehicle) error {
+	if vehicle == nil {
+		return nil
+	}
+	vehicle.Category = VehicleCategory(strings.ToLower(strings.TrimSpace(string(vehicle.Category))))
+	switch vehicle.Category {
+	case "", VehicleMotorbike, VehicleCar:
+	default:
+		return fmt.Errorf("%w: unknown category %q", ErrInvalidVehicle, vehicle.Category)
+	}
+	if vehicle.Capacity == 0 {
+		vehicle.Capacity = defaultVehicleCapacity(vehicle.Category)
+	}
+	if vehicle.Capacity < 0 || vehicle.Capacity > maxVehicleCapacity {
+		return fmt.Errorf("%w: capacity must be between 1 and %d", ErrInvalidVehicle, maxVehicleCapacity)
+	}
+	return nil
+}
+
+// CanServe reports whether the driver may be dispatched trips of serviceID. A
+// driver with an admin-managed eligibility list serves exactly those services;
+// otherwise their vehicle must meet the service's requirement, so a vehicle
+// with no category yet serves only services without one.
+func (s *DriverService) CanServe(driver *Driver, serviceID string) bool {
+	if driver == nil {
+		return false
+	}
+	serviceID = normalizeServiceID(serviceID)
+	if len(driver.EligibleServices) > 0 {
+		for _, eligible := range driver.EligibleServices {
+			if eligible == serviceID {
+				return true
+			}
+		}
+		return false
+	}
+	requirement, ok := s.requirements[serviceID]
+	if !ok {
+		return true
+	}
+	if driver.Vehicle == nil {
+		return false
+	}
+	return driver.Vehicle.Category == requirement.Category && driver.Vehicle.Capacity >= requirement.MinCapacity
+}
+
+// SetEligibleServices replaces the services an admin allows the driver to serve.
+// An empty list returns the driver to vehicle-based eligibility.
+func (s *DriverService) SetEligibleServices(ctx context.Context, driverID string, services []string) (*Driver, error) {
+	normalized := make([]string, 0, len(services))
+	seen := make(map[string]struct{}, len(services))
+	for _, serviceID := range services {
+		serviceID = normalizeServiceID(serviceID)
+		if _, ok := s.requirements[serviceID]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownService, serviceID)
+		}
+		if _, dup := seen[serviceID]; dup {
+			continue
+		}
+		seen[serviceID] = struct{}{}
+		normalized = append(normalized, serviceID)
+	}
+	sort.Strings(normalized)
+	if err := s.drivers.SetEligibleServices(ctx, driverID, normalized); err != nil {
+		return nil, err
+	}
+	driver, err := s.drivers.FindByID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	enrichDriver(ctx, s.drivers, driver)
+	return driver, nil
+}
+
+func normalizeServiceID(serviceID string) string {
+	return strings.ToLower(strings.TrimSpace(serviceID))
+}
@@ -15,6 +15,7 @@ var (
 	ErrNoDriversAvailable      = errors.New("no drivers available")
 	ErrTripAssignmentNotFound  = errors.New("trip assignment not found")
 	ErrAssignmentConflict      = errors.New("assignment conflict")
+	ErrAssignmentExpired       = errors.New("assignment offer no longer available")
 	ErrWalletInvalidAmount     = errors.New("invalid wallet amount")
 	ErrWalletInsufficientFunds = errors.New("insufficient wallet balance")
 )
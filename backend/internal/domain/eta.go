@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ETAConfig tunes live ETA updates computed from driver location pings.
+type ETAConfig struct {
+	// MinInterval is the least time between two route lookups for a trip.
+	MinInterval time.Duration
+	// Precision is how many decimals of the driver's position are kept for the
+	// lookup, so pings close together reuse the routing client's cached route.
+	Precision int
+}
+
+// DefaultETAConfig returns the baseline ETA refresh settings.
+func DefaultETAConfig() ETAConfig {
+	return ETAConfig{
+		MinInterval: 15 * time.Second,
+		Precision:   3,
+	}
+}
+
+// WithLiveETA recomputes the driver's ETA from location pings using routes.
+// The routing client falls back to its synthetic straight-line estimate when
+// the routing backend is down, so updates keep flowing.
+func WithLiveETA(routes RouteEstimator, cfg ETAConfig) TripServiceOption {
+	return func(s *TripService) {
+		s.etaRoutes = routes
+		if cfg.MinInterval > 0 {
+			s.eta.MinInterval = cfg.MinInterval
+		}
+		if cfg.Precision > 0 {
+			s.eta.Precision = cfg.Precision
+		}
+	}
+}
+
+// TripETA is the latest estimate of when the driver reaches Target: the
+// pickup while on the way there, the destination during the ride.
+type TripETA struct {
+	Target          ArrivalPlace `json:"target"`
+	DistanceMeters  float64      `json:"distanceMeters"`
+	DurationSeconds float64      `json:"durationSeconds"`
+	ArriveAt        time.Time    `json:"arriveAt"`
+	UpdatedAt       time.Time    `json:"updatedAt"`
+}
+
+// etaTarget returns where the driver is heading while the trip is in status.
+func etaTarget(status TripStatus) (ArrivalPlace, bool) {
+	switch status {
+	case TripStatusAccepted, TripStatusArriving:
+		return ArrivalPickup, true
+	case TripStatusInRide:
+		return ArrivalDestination, true
+	default:
+		return "", false
+	}
+}
+
+// etaThrottle remembers when each trip's ETA was last looked up.
+type etaThrottle struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// allow reports whether a lookup at now is due and, if so, records it.
+func (t *etaThrottle) allow(tripID string, now time.Time, interval time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil {
+		t.last = make(map[string]time.Time)
+	}
+	if last, ok := t.last[tripID]; ok && now.Sub(last) < interval {
+		return false
+	}
+	t.last[tripID] = now
+	return true
+}
+
+func (t *etaThrottle) forget(tripID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.last, tripID)
+}
+
+// RefreshETA recomputes the trip's ETA from a driver location ping and stores
+// it on the trip. Lookups are throttled per trip; it returns nil when the ping
+// came too soon after the last lookup or the trip is not underway.
+func (s *TripService) RefreshETA(ctx context.Context, tripID string, update LocationUpdate) (*TripETA, error) {
+	if s.etaRoutes == nil {
+		return nil, nil
+	}
+	at := update.Timestamp
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	if !s.etaRefresh.allow(tripID, at, s.eta.MinInterval) {
+		return nil, nil
+	}
+	trip, err := s.repo.GetTrip(tripID)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := etaTarget(trip.Status)
+	if !ok {
+		s.etaRefresh.forget(tripID)
+		return nil, nil
+	}
+	driver := Waypoint{Lat: roundTo(update.Latitude, s.eta.Precision), Lng: roundTo(update.Longitude, s.eta.Precision)}
+	points := []Waypoint{driver}
+	if target == ArrivalPickup {
+		if trip.OriginLat == nil || trip.OriginLng == nil {
+			return nil, nil
+		}
+		points = append(points, Waypoint{Lat: *trip.OriginLat, Lng: *trip.OriginLng})
+	} else {
+		if trip.DestLat == nil || trip.DestLng == nil {
+			return nil, nil
+		}
+		for _, stop := range trip.Stops {
+			if stop.ArrivedAt == nil {
+				points = append(points, Waypoint{Lat: stop.Lat, Lng: stop.Lng})
+			}
+		}
+		points = append(points, Waypoint{Lat: *trip.DestLat, Lng: *trip.DestLng})
+	}
+
+	route, err := estimateRouteVia(ctx, s.etaRoutes, points)
+	if err != nil {
+		return nil, err
+	}
+	eta := &TripETA{
+		Target:          target,
+		DistanceMeters:  route.DistanceMeters,
+		DurationSeconds: route.DurationSeconds,
+		ArriveAt:        at.Add(time.Duration(route.DurationSeconds * float64(time.Second))),
+		UpdatedAt:       at,
+	}
+	if err := s.repo.SaveTripETA(tripID, eta); err != nil {
+		return nil, err
+	}
+	return eta, nil
+}
+
+// clearStaleETA drops the stored ETA once a transition changes where the
+// driver is heading, so clients never see a pickup ETA during the ride. The
+// next ping then estimates the new target without waiting out the throttle.
+func (s *TripService) clearStaleETA(trip *Trip, change TripStatusChange) {
+	from, _ := etaTarget(change.From)
+	if to, ok := etaTarget(change.To); ok && to == from {
+		return
+	}
+	s.etaRefresh.forget(trip.ID)
+	if trip.ETA == nil {
+		return
+	}
+	if err := s.repo.SaveTripETA(trip.ID, nil); err != nil {
+		log.Printf("clear eta of trip %s: %v", trip.ID, err)
+		return
+	}
+	trip.ETA = nil
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,67 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+func TestRefreshETATargetsPickupAndThrottles(t *testing.T) {
+	repo := newStubRepo()
+	routes := &waypointRouteEstimator{}
+	service := domain.NewTripService(repo, nil, nil, domain.WithLiveETA(routes, domain.ETAConfig{MinInterval: time.Minute}))
+	trip := multiStopTrip()
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	// Nothing to estimate before a driver accepts.
+	eta, err := service.RefreshETA(context.Background(), trip.ID, ping(10.86, 106.81))
+	require.NoError(t, err)
+	require.Nil(t, eta)
+
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	now := time.Now().UTC()
+	update := domain.LocationUpdate{Latitude: 10.861234, Longitude: 106.809876, Timestamp: now}
+	eta, err = service.RefreshETA(context.Background(), trip.ID, update)
+	require.NoError(t, err)
+	require.Equal(t, domain.ArrivalPickup, eta.Target)
+	require.Equal(t, now.Add(1500*time.Second), eta.ArriveAt)
+	// The driver position is rounded so nearby pings share a cached route.
+	require.Equal(t, []domain.Waypoint{{Lat: 10.861, Lng: 106.81}, {Lat: 10.87, Lng: 106.80}}, routes.waypoints)
+	require.Equal(t, eta, repo.trips[trip.ID].ETA)
+
+	update.Timestamp = now.Add(30 * time.Second)
+	eta, err = service.RefreshETA(context.Background(), trip.ID, update)
+	require.NoError(t, err)
+	require.Nil(t, eta)
+}
+
+func TestRefreshETARoutesThroughRemainingStopsDuringRide(t *testing.T) {
+	repo := newStubRepo()
+	routes := &waypointRouteEstimator{}
+	service := domain.NewTripService(repo, nil, nil, domain.WithLiveETA(routes, domain.ETAConfig{}))
+	trip := multiStopTrip()
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	_, err := service.RefreshETA(context.Background(), trip.ID, ping(10.86, 106.81))
+	require.NoError(t, err)
+
+	// Starting the ride drops the pickup ETA and the throttle does not hold
+	// back the first estimate towards the destination.
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusArriving))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusInRide))
+	require.Nil(t, repo.trips[trip.ID].ETA)
+	reached := time.Now().UTC()
+	trip.Stops[0].ArrivedAt = &reached
+
+	eta, err := service.RefreshETA(context.Background(), trip.ID, ping(10.873, 106.794))
+	require.NoError(t, err)
+	require.Equal(t, domain.ArrivalDestination, eta.Target)
+	require.Equal(t, []domain.Waypoint{{Lat: 10.873, Lng: 106.794}, {Lat: 10.876, Lng: 106.790}, {Lat: 10.88, Lng: 106.78}}, routes.waypoints)
+
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusCompleted))
+	require.Nil(t, repo.trips[trip.ID].ETA)
+}
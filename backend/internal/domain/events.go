@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TripEventType names the kind of payload stored on a trip event.
+type TripEventType string
+
+const (
+	TripEventLocation     TripEventType = "location"
+	TripEventStatus       TripEventType = "status"
+	TripEventAssignment   TripEventType = "assignment"
+	TripEventDecline      TripEventType = "decline"
+	TripEventCancellation TripEventType = "cancellation"
+	TripEventFare         TripEventType = "fare"
+	TripEventStop         TripEventType = "stop"
+	TripEventArrival      TripEventType = "arrival"
+)
+
+// TripEvent is one entry in a trip's history. Payload holds the JSON form of
+// the type-specific struct: LocationUpdate, TripStatusChange,
+// TripAssignmentEvent, TripCancellationEvent, TripFareEvent, TripStop or
+// TripArrivalEvent.
+type TripEvent struct {
+	ID        string          `json:"id"`
+	TripID    string          `json:"tripId"`
+	Type      TripEventType   `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// TripAssignmentEvent records an offer to a driver and how it was resolved.
+// Declines use the same payload under TripEventDecline.
+type TripAssignmentEvent struct {
+	DriverID string               `json:"driverId"`
+	Status   TripAssignmentStatus `json:"status"`
+}
+
+// TripCancellationEvent records who cancelled a trip, why and what it cost.
+type TripCancellationEvent struct {
+	Actor   TransitionActor `json:"actor"`
+	ActorID string          `json:"actorId,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+	Fee     int64           `json:"fee,omitempty"`
+}
+
+// TripFareKind distinguishes the fare changes recorded on a trip.
+type TripFareKind string
+
+const (
+	TripFareQuoted       TripFareKind = "quoted"
+	TripFareFinal        TripFareKind = "final"
+	TripFareCancellation TripFareKind = "cancellation_fee"
+)
+
+// TripFareEvent records a fare being quoted, settled or charged.
+type TripFareEvent struct {
+	Kind            TripFareKind `json:"kind"`
+	Amount          int64        `json:"amount"`
+	Currency        string       `json:"currency,omitempty"`
+	Basis           FareBasis    `json:"basis,omitempty"`
+	SurgeMultiplier *float64     `json:"surgeMultiplier,omitempty"`
+}
+
+// NewTripEvent encodes payload into a trip event of the given type.
+func NewTripEvent(tripID string, eventType TripEventType, payload any, at time.Time) (TripEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return TripEvent{}, err
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	return TripEvent{TripID: tripID, Type: eventType, Payload: raw, CreatedAt: at}, nil
+}
+
+// TripEventFilter narrows a trip's history.
+type TripEventFilter struct {
+	// Types limits the history to these event types; empty means all.
+	Types []TripEventType
+	// DriverID, when set, hides assignment and decline events of other drivers.
+	DriverID string
+	Limit    int
+}
+
+// TripEventTypesForRole returns the event types each role may read. Riders do
+// not see which drivers were offered or declined the trip, and only admins may
+// read the raw location trail.
+func TripEventTypesForRole(role string) []TripEventType {
+	switch ActorForRole(role) {
+	case ActorAdmin:
+		return []TripEventType{TripEventStatus, TripEventAssignment, TripEventDecline, TripEventCancellation, TripEventFare, TripEventStop, TripEventArrival, TripEventLocation}
+	case ActorDriver:
+		return []TripEventType{TripEventStatus, TripEventAssignment, TripEventDecline, TripEventCancellation, TripEventFare, TripEventStop, TripEventArrival}
+	default:
+		return []TripEventType{TripEventStatus, TripEventCancellation, TripEventFare, TripEventStop, TripEventArrival}
+	}
+}
+
+// VisibleTripEventTypes intersects the requested types with what role may read.
+// With nothing requested it returns the role's timeline without location pings,
+// which would otherwise drown out everything else.
+func VisibleTripEventTypes(role string, requested []TripEventType) []TripEventType {
+	allowed := TripEventTypesForRole(role)
+	visible := make([]TripEventType, 0, len(allowed))
+	for _, eventType := range allowed {
+		if len(requested) == 0 {
+			if eventType != TripEventLocation {
+				visible = append(visible, eventType)
+			}
+			continue
+		}
+		for _, want := range requested {
+			if want == eventType {
+				visible = append(visible, eventType)
+				break
+			}
+		}
+	}
+	return visible
+}
+
+func (f TripEventFilter) allows(event TripEvent) bool {
+	if f.DriverID == "" || (event.Type != TripEventAssignment && event.Type != TripEventDecline) {
+		return true
+	}
+	var payload TripAssignmentEvent
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return false
+	}
+	return payload.DriverID == f.DriverID
+}
@@ -0,0 +1,94 @@
+package domain_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+func TestTripServiceRecordsFareAndCancellationEvents(t *testing.T) {
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	wallet := &recordingWallet{}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(repo, wallet, nil,
+		domain.WithFareEstimator(estimator),
+		domain.WithCancellationPolicy(domain.CancellationPolicy{GracePeriod: 0, Fee: 12000}),
+	)
+
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	time.Sleep(time.Millisecond)
+	_, err := service.Transition(context.Background(), trip.ID, domain.TripStatusChange{
+		To:      domain.TripStatusCancelled,
+		Actor:   domain.ActorRider,
+		ActorID: "rider-1",
+		Reason:  "driver too far",
+	})
+	require.NoError(t, err)
+
+	events, err := service.Events(context.Background(), trip.ID, domain.TripEventFilter{
+		Types: domain.VisibleTripEventTypes("rider", nil),
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	var quoted domain.TripFareEvent
+	require.Equal(t, domain.TripEventFare, events[0].Type)
+	require.NoError(t, json.Unmarshal(events[0].Payload, &quoted))
+	require.Equal(t, domain.TripFareQuoted, quoted.Kind)
+	require.Equal(t, *trip.QuotedFare, quoted.Amount)
+
+	var cancellation domain.TripCancellationEvent
+	require.Equal(t, domain.TripEventCancellation, events[1].Type)
+	require.NoError(t, json.Unmarshal(events[1].Payload, &cancellation))
+	require.Equal(t, domain.ActorRider, cancellation.Actor)
+	require.Equal(t, "driver too far", cancellation.Reason)
+	require.Equal(t, int64(12000), cancellation.Fee)
+
+	var fee domain.TripFareEvent
+	require.NoError(t, json.Unmarshal(events[2].Payload, &fee))
+	require.Equal(t, domain.TripFareCancellation, fee.Kind)
+}
+
+func TestTripEventVisibilityByRole(t *testing.T) {
+	require.NotContains(t, domain.VisibleTripEventTypes("rider", nil), domain.TripEventAssignment)
+	require.Empty(t, domain.VisibleTripEventTypes("rider", []domain.TripEventType{domain.TripEventDecline}))
+	require.NotContains(t, domain.VisibleTripEventTypes("admin", nil), domain.TripEventLocation)
+	require.Equal(t, []domain.TripEventType{domain.TripEventLocation},
+		domain.VisibleTripEventTypes("admin", []domain.TripEventType{domain.TripEventLocation}))
+
+	repo := newStubRepo()
+	service := domain.NewTripService(repo, nil, nil)
+	for _, driverID := range []string{"driver-1", "driver-2"} {
+		event, err := domain.NewTripEvent("trip-1", domain.TripEventDecline,
+			domain.TripAssignmentEvent{DriverID: driverID, Status: domain.TripAssignmentDeclined}, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, service.RecordEvent(context.Background(), event))
+	}
+
+	events, err := service.Events(context.Background(), "trip-1", domain.TripEventFilter{
+		Types:    domain.VisibleTripEventTypes("driver", nil),
+		DriverID: "driver-2",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Contains(t, string(events[0].Payload), "driver-2")
+}
@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// FareCurrency is the currency every fare amount is expressed in.
+const FareCurrency = "VND"
+
+var (
+	// ErrFareRuleNotFound indicates there is no pricing rule for the service.
+	ErrFareRuleNotFound = errors.New("fare rule not found")
+	// ErrFareEstimationUnavailable indicates the service runs without a fare estimator.
+	ErrFareEstimationUnavailable = errors.New("fare estimation unavailable")
+)
+
+// FareRule prices a trip for one service. Amounts are in VND.
+type FareRule struct {
+	BaseFare    int64 `json:"baseFare"`
+	PerKm       int64 `json:"perKm"`
+	PerMinute   int64 `json:"perMinute"`
+	MinimumFare int64 `json:"minimumFare"`
+	BookingFee  int64 `json:"bookingFee"`
+}
+
+// FareQuote is the price breakdown shown to the rider before booking. Quotes
+// handed to riders carry a QuoteID that books the trip at this price until
+// ExpiresAt.
+type FareQuote struct {
+	QuoteID         string     `json:"quoteId,omitempty"`
+	ServiceID       string     `json:"serviceId"`
+	DistanceMeters  float64    `json:"distanceMeters"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	BaseFare        int64      `json:"baseFare"`
+	DistanceFare    int64      `json:"distanceFare"`
+	TimeFare        int64      `json:"timeFare"`
+	BookingFee      int64      `json:"bookingFee"`
+	MinimumFare     int64      `json:"minimumFare"`
+	SurgeMultiplier float64    `json:"surgeMultiplier"`
+	SurgeFare       int64      `json:"surgeFare"`
+	ZoneSurcharge   int64      `json:"zoneSurcharge,omitempty"`
+	Total           int64      `json:"total"`
+	Currency        string     `json:"currency"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+}
+
+// FareRequest describes the trip to price. Stops are visited in order between
+// origin and destination.
+type FareRequest struct {
+	RiderID   string
+	ServiceID string
+	OriginLat float64
+	OriginLng float64
+	DestLat   float64
+	DestLng   float64
+	Stops     []Waypoint
+}
+
+// RouteEstimate is the driving distance and duration between two points.
+type RouteEstimate struct {
+	DistanceMeters  float64
+	DurationSeconds float64
+}
+
+// RouteEstimator looks up driving distance and duration for fare estimation.
+type RouteEstimator interface {
+	EstimateRoute(ctx context.Context, originLat, originLng, destLat, destLng float64) (*RouteEstimate, error)
+}
+
+// WaypointRouteEstimator is implemented by route estimators that can price a
+// route through several waypoints in one lookup.
+type WaypointRouteEstimator interface {
+	EstimateRouteVia(ctx context.Context, waypoints []Waypoint) (*RouteEstimate, error)
+}
+
+// DefaultFareRules returns the baseline pricing per service ID.
+func DefaultFareRules() map[string]FareRule {
+	return map[string]FareRule{
+		"uit-bike":  {BaseFare: 10000, PerKm: 4000, PerMinute: 300, MinimumFare: 12000, BookingFee: 2000},
+		"uit-rider": {BaseFare: 10000, PerKm: 4000, PerMinute: 300, MinimumFare: 12000, BookingFee: 2000},
+		"uit-go":    {BaseFare: 20000, PerKm: 9000, PerMinute: 400, MinimumFare: 25000, BookingFee: 3000},
+		"uit-car":   {BaseFare: 25000, PerKm: 11000, PerMinute: 500, MinimumFare: 30000, BookingFee: 3000},
+		"uit-plus":  {BaseFare: 35000, PerKm: 15000, PerMinute: 700, MinimumFare: 45000, BookingFee: 5000},
+	}
+}
+
+// FareEstimator prices trips from routing data and per-service rules.
+type FareEstimator struct {
+	routes RouteEstimator
+	rules  map[string]FareRule
+}
+
+// FareEstimatorOption customises fare estimation.
+type FareEstimatorOption func(*FareEstimator)
+
+// WithFareRules overrides the rules for the given service IDs.
+func WithFareRules(rules map[string]FareRule) FareEstimatorOption {
+	return func(e *FareEstimator) {
+		for serviceID, rule := range rules {
+			e.rules[strings.ToLower(serviceID)] = rule
+		}
+	}
+}
+
+// NewFareEstimator wires a fare estimator on top of a route estimator.
+func NewFareEstimator(routes RouteEstimator, opts ...FareEstimatorOption) *FareEstimator {
+	estimator := &FareEstimator{
+		routes: routes,
+		rules:  DefaultFareRules(),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(estimator)
+		}
+	}
+	return estimator
+}
+
+// Rule returns the pricing rule for the service.
+func (e *FareEstimator) Rule(serviceID string) (FareRule, error) {
+	rule, ok := e.rules[strings.ToLower(strings.TrimSpace(serviceID))]
+	if !ok {
+		return FareRule{}, ErrFareRuleNotFound
+	}
+	return rule, nil
+}
+
+// Estimate looks up the route and prices it with the service's rule.
+func (e *FareEstimator) Estimate(ctx context.Context, req FareRequest) (*FareQuote, error) {
+	rule, err := e.Rule(req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if e.routes == nil {
+		return nil, errors.New("route estimator not configured")
+	}
+	route, err := e.estimateRoute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	quote := PriceFare(rule, route.DistanceMeters, route.DurationSeconds)
+	quote.ServiceID = strings.ToLower(strings.TrimSpace(req.ServiceID))
+	return quote, nil
+}
+
+// estimateRoute measures origin to destination through any stops.
+func (e *FareEstimator) estimateRoute(ctx context.Context, req FareRequest) (*RouteEstimate, error) {
+	if len(req.Stops) == 0 {
+		return e.routes.EstimateRoute(ctx, req.OriginLat, req.OriginLng, req.DestLat, req.DestLng)
+	}
+	points := make([]Waypoint, 0, len(req.Stops)+2)
+	points = append(points, Waypoint{Lat: req.OriginLat, Lng: req.OriginLng})
+	points = append(points, req.Stops...)
+	points = append(points, Waypoint{Lat: req.DestLat, Lng: req.DestLng})
+	return estimateRouteVia(ctx, e.routes, points)
+}
+
+// estimateRouteVia measures a route through points, asking for each leg in
+// turn when routes cannot take waypoints.
+func estimateRouteVia(ctx context.Context, routes RouteEstimator, points []Waypoint) (*RouteEstimate, error) {
+	if via, ok := routes.(WaypointRouteEstimator); ok {
+		return via.EstimateRouteVia(ctx, points)
+	}
+	total := &RouteEstimate{}
+	for i := 1; i < len(points); i++ {
+		leg, err := routes.EstimateRoute(ctx, points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+		if err != nil {
+			return nil, err
+		}
+		total.DistanceMeters += leg.DistanceMeters
+		total.DurationSeconds += leg.DurationSeconds
+	}
+	return total, nil
+}
+
+// PriceFare applies a rule to a distance and duration.
+func PriceFare(rule FareRule, distanceMeters, durationSeconds float64) *FareQuote {
+	distanceMeters = math.Max(distanceMeters, 0)
+	durationSeconds = math.Max(durationSeconds, 0)
+	quote := &FareQuote{
+		DistanceMeters:  distanceMeters,
+		DurationSeconds: durationSeconds,
+		BaseFare:        rule.BaseFare,
+		DistanceFare:    int64(math.Round(distanceMeters / 1000 * float64(rule.PerKm))),
+		TimeFare:        int64(math.Round(durationSeconds / 60 * float64(rule.PerMinute))),
+		BookingFee:      rule.BookingFee,
+		MinimumFare:     rule.MinimumFare,
+		SurgeMultiplier: 1,
+		Currency:        FareCurrency,
+	}
+	// The minimum applies to the ride itself; the booking fee is always added on top.
+	ride := quote.BaseFare + quote.DistanceFare + quote.TimeFare
+	if ride < rule.MinimumFare {
+		ride = rule.MinimumFare
+	}
+	quote.Total = ride + quote.BookingFee
+	return quote
+}
+
+// ApplySurge scales the ride part of the quote, after the minimum, by
+// multiplier. The booking fee is not surged. Multipliers at or below 1 leave
+// the quote unchanged.
+func ApplySurge(quote *FareQuote, multiplier float64) {
+	if quote == nil || multiplier <= 1 {
+		return
+	}
+	ride := quote.Total - quote.BookingFee
+	quote.SurgeMultiplier = multiplier
+	quote.SurgeFare = int64(math.Round(float64(ride) * (multiplier - 1)))
+	quote.Total += quote.SurgeFare
+}
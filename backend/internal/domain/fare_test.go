@@ -0,0 +1,216 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type stubRouteEstimator struct {
+	estimate *domain.RouteEstimate
+}
+
+func (s *stubRouteEstimator) EstimateRoute(ctx context.Context, originLat, originLng, destLat, destLng float64) (*domain.RouteEstimate, error) {
+	return s.estimate, nil
+}
+
+type recordingWallet struct {
+	ensured    int64
+	deducted   int64
+	cancelFee  int64
+	charges    int
+	balanceErr error
+	deductErr  error
+}
+
+func (w *recordingWallet) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string, fare int64) (int64, error) {
+	if w.balanceErr != nil {
+		return 0, w.balanceErr
+	}
+	w.ensured = fare
+	return fare, nil
+}
+
+func (w *recordingWallet) DeductTripFare(ctx context.Context, userID, serviceID string, fare int64) (*domain.WalletSummary, int64, error) {
+	if w.deductErr != nil {
+		return nil, 0, w.deductErr
+	}
+	w.deducted = fare
+	w.charges++
+	return &domain.WalletSummary{UserID: userID}, fare, nil
+}
+
+func (w *recordingWallet) RewardTripCompletion(ctx context.Context, userID string) (*domain.WalletSummary, int64, error) {
+	return &domain.WalletSummary{UserID: userID}, 0, nil
+}
+
+func (w *recordingWallet) ChargeCancellationFee(ctx context.Context, userID string, fee int64) (*domain.WalletSummary, error) {
+	w.cancelFee = fee
+	return &domain.WalletSummary{UserID: userID}, nil
+}
+
+// detachedRepo returns copies from GetTrip like a database-backed repository.
+type detachedRepo struct {
+	*stubRepo
+}
+
+func (r *detachedRepo) GetTrip(id string) (*domain.Trip, error) {
+	trip, err := r.stubRepo.GetTrip(id)
+	if err != nil {
+		return nil, err
+	}
+	clone := *trip
+	return &clone, nil
+}
+
+func TestPriceFareAppliesRuleAndMinimum(t *testing.T) {
+	rule := domain.FareRule{BaseFare: 10000, PerKm: 4000, PerMinute: 300, MinimumFare: 12000, BookingFee: 2000}
+
+	quote := domain.PriceFare(rule, 5500, 900)
+	require.Equal(t, int64(22000), quote.DistanceFare)
+	require.Equal(t, int64(4500), quote.TimeFare)
+	require.Equal(t, int64(38500), quote.Total)
+	require.Equal(t, domain.FareCurrency, quote.Currency)
+
+	short := domain.PriceFare(rule, 200, 60)
+	require.Equal(t, int64(14000), short.Total)
+}
+
+func TestTripServiceChargesQuotedFare(t *testing.T) {
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	wallet := &recordingWallet{}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(repo, wallet, nil, domain.WithFareEstimator(estimator))
+
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "UIT-Bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.NotNil(t, trip.QuotedFare)
+	require.Equal(t, int64(38500), *trip.QuotedFare)
+	require.Equal(t, int64(38500), wallet.ensured)
+
+	require.Equal(t, domain.FareCurrency, trip.Currency)
+	require.Equal(t, 5500.0, *trip.RouteDistanceMeters)
+
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusInRide))
+	require.NotNil(t, repo.trips[trip.ID].StartedAt)
+	require.Nil(t, repo.trips[trip.ID].CompletedAt)
+
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusCompleted))
+	require.Equal(t, int64(38500), wallet.deducted)
+	stored := repo.trips[trip.ID]
+	require.NotNil(t, stored.CompletedAt)
+	require.NotNil(t, stored.FinalFare)
+	require.Equal(t, int64(38500), *stored.FinalFare)
+	require.NotNil(t, stored.FareChargedAt)
+}
+
+func TestTripServiceRetriesFailedFareCharge(t *testing.T) {
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	wallet := &recordingWallet{deductErr: errors.New("wallet unavailable")}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(repo, wallet, nil, domain.WithFareEstimator(estimator))
+	ctx := context.Background()
+
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, service.Create(ctx, trip))
+	require.NoError(t, service.UpdateStatus(ctx, trip.ID, domain.TripStatusAccepted))
+	require.NoError(t, service.UpdateStatus(ctx, trip.ID, domain.TripStatusInRide))
+
+	// The completion and its fare are stored even though billing fails.
+	require.NoError(t, service.UpdateStatus(ctx, trip.ID, domain.TripStatusCompleted))
+	stored := repo.trips[trip.ID]
+	require.Equal(t, domain.TripStatusCompleted, stored.Status)
+	require.NotNil(t, stored.CompletedAt)
+	require.Equal(t, int64(38500), *stored.FinalFare)
+	require.Nil(t, stored.FareChargedAt)
+	require.Zero(t, wallet.charges)
+
+	charged, err := service.ChargePendingFares(ctx, 10)
+	require.NoError(t, err)
+	require.Zero(t, charged)
+	require.Nil(t, stored.FareChargedAt)
+
+	wallet.deductErr = nil
+	charged, err = service.ChargePendingFares(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, charged)
+	require.Equal(t, int64(38500), wallet.deducted)
+	require.NotNil(t, stored.FareChargedAt)
+
+	charged, err = service.ChargePendingFares(ctx, 10)
+	require.NoError(t, err)
+	require.Zero(t, charged)
+	require.Equal(t, 1, wallet.charges)
+}
+
+func TestDriverCompletionChargesThroughTripService(t *testing.T) {
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	wallet := &recordingWallet{}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	trips := domain.NewTripService(repo, wallet, nil, domain.WithFareEstimator(estimator))
+	drivers := newFakeDriverRepo()
+	drivers.addDriver("driver-1", domain.DriverOnline)
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), trips.DriverSync(), nil, nil)
+	ctx := context.Background()
+
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, trips.Create(ctx, trip))
+	_, err := service.AcceptTrip(ctx, trip.ID, "driver-1")
+	require.NoError(t, err)
+
+	_, err = service.UpdateTripStatus(ctx, trip.ID, "driver-1", domain.TripStatusInRide, "")
+	require.NoError(t, err)
+	_, err = service.UpdateTripStatus(ctx, trip.ID, "driver-1", domain.TripStatusCompleted, "")
+	require.NoError(t, err)
+	require.Equal(t, int64(38500), wallet.deducted)
+	stored := repo.trips[trip.ID]
+	require.Equal(t, domain.TripStatusCompleted, stored.Status)
+	require.Equal(t, int64(38500), *stored.FinalFare)
+}
+
+func TestFareEstimatorUnknownService(t *testing.T) {
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{estimate: &domain.RouteEstimate{}})
+	_, err := estimator.Estimate(context.Background(), domain.FareRequest{ServiceID: "uit-jet"})
+	require.ErrorIs(t, err, domain.ErrFareRuleNotFound)
+}
@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// GeofenceConfig tunes automatic arrival detection from driver location pings.
+type GeofenceConfig struct {
+	// PickupRadiusMeters is how close to the pickup an accepted trip's driver
+	// must get for the trip to move to arriving.
+	PickupRadiusMeters float64
+	// DestinationRadiusMeters is how close to the destination counts as arrived.
+	DestinationRadiusMeters float64
+	// ExitMarginMeters is how far beyond a radius a ping must land to reset the
+	// count of pings inside it. Pings in between leave the count alone, so GPS
+	// noise at the edge does not flap.
+	ExitMarginMeters float64
+	// ConfirmPings is how many pings inside a radius trigger the arrival.
+	ConfirmPings int
+}
+
+// DefaultGeofenceConfig returns the baseline arrival radii.
+func DefaultGeofenceConfig() GeofenceConfig {
+	return GeofenceConfig{
+		PickupRadiusMeters:      150,
+		DestinationRadiusMeters: 100,
+		ExitMarginMeters:        75,
+		ConfirmPings:            2,
+	}
+}
+
+// WithGeofence overrides the arrival detection settings.
+func WithGeofence(cfg GeofenceConfig) TripServiceOption {
+	return func(s *TripService) {
+		if cfg.PickupRadiusMeters > 0 {
+			s.geofence.PickupRadiusMeters = cfg.PickupRadiusMeters
+		}
+		if cfg.DestinationRadiusMeters > 0 {
+			s.geofence.DestinationRadiusMeters = cfg.DestinationRadiusMeters
+		}
+		if cfg.ExitMarginMeters > 0 {
+			s.geofence.ExitMarginMeters = cfg.ExitMarginMeters
+		}
+		if cfg.ConfirmPings > 0 {
+			s.geofence.ConfirmPings = cfg.ConfirmPings
+		}
+	}
+}
+
+// ArrivalPlace names the geofence a driver entered.
+type ArrivalPlace string
+
+const (
+	ArrivalPickup      ArrivalPlace = "pickup"
+	ArrivalDestination ArrivalPlace = "destination"
+)
+
+// TripArrivalEvent records a driver detected inside a trip geofence.
+type TripArrivalEvent struct {
+	Place          ArrivalPlace `json:"place"`
+	DistanceMeters float64      `json:"distanceMeters"`
+	Latitude       float64      `json:"lat"`
+	Longitude      float64      `json:"lng"`
+}
+
+// TripArrival is the outcome of a location ping that completed an arrival.
+// Status is the trip status to announce: arriving at the pickup, or the
+// arrived marker at the destination.
+type TripArrival struct {
+	Place  ArrivalPlace
+	Status TripStatus
+	At     time.Time
+}
+
+// geofenceTracker counts consecutive pings inside each trip's current geofence.
+type geofenceTracker struct {
+	mu    sync.Mutex
+	trips map[string]*geofenceState
+}
+
+type geofenceState struct {
+	place  ArrivalPlace
+	inside int
+	fired  bool
+}
+
+// observe adds a ping at distance from the place and reports whether it
+// completes the arrival. Each place fires at most once per trip.
+func (g *geofenceTracker) observe(tripID string, place ArrivalPlace, distance, radius float64, cfg GeofenceConfig) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.trips == nil {
+		g.trips = make(map[string]*geofenceState)
+	}
+	state, ok := g.trips[tripID]
+	if !ok || state.place != place {
+		state = &geofenceState{place: place}
+		g.trips[tripID] = state
+	}
+	if state.fired {
+		return false
+	}
+	switch {
+	case distance <= radius:
+		state.inside++
+	case distance > radius+cfg.ExitMarginMeters:
+		state.inside = 0
+	}
+	if state.inside < cfg.ConfirmPings {
+		return false
+	}
+	state.fired = true
+	return true
+}
+
+func (g *geofenceTracker) forget(tripID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.trips, tripID)
+}
+
+// TrackArrival checks a driver location ping against the trip's geofences. A
+// driver settling within the pickup radius of an accepted trip moves it to
+// arriving; one settling near the destination during the ride flags arrival
+// there. Both notify the rider. It returns nil when the ping completed neither.
+func (s *TripService) TrackArrival(ctx context.Context, tripID string, update LocationUpdate) (*TripArrival, error) {
+	trip, err := s.repo.GetTrip(tripID)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		place    ArrivalPlace
+		lat, lng *float64
+		radius   float64
+	)
+	switch trip.Status {
+	case TripStatusAccepted:
+		place, lat, lng, radius = ArrivalPickup, trip.OriginLat, trip.OriginLng, s.geofence.PickupRadiusMeters
+	case TripStatusInRide:
+		place, lat, lng, radius = ArrivalDestination, trip.DestLat, trip.DestLng, s.geofence.DestinationRadiusMeters
+	default:
+		s.arrivals.forget(tripID)
+		return nil, nil
+	}
+	if lat == nil || lng == nil {
+		return nil, nil
+	}
+	distance := haversineMeters(update.Latitude, update.Longitude, *lat, *lng)
+	if !s.arrivals.observe(tripID, place, distance, radius, s.geofence) {
+		return nil, nil
+	}
+
+	at := update.Timestamp
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	arrival := &TripArrival{Place: place, At: at}
+	if place == ArrivalPickup {
+		arrival.Status = TripStatusArriving
+		change := TripStatusChange{From: TripStatusAccepted, To: TripStatusArriving, Actor: ActorSystem, Reason: "driver entered the pickup area", At: at}
+		// Transition notifies the rider.
+		if _, err := s.Transition(ctx, tripID, change); err != nil {
+			if errors.Is(err, ErrInvalidTransition) {
+				// The driver moved the trip on in the meantime.
+				return nil, nil
+			}
+			return nil, err
+		}
+	} else {
+		arrival.Status = TripStatusArrived
+		if s.arrivedAtDestination(tripID) {
+			return nil, nil
+		}
+		s.notifyStatus(ctx, trip, TripStatusArrived)
+	}
+	s.recordEvent(tripID, TripEventArrival, TripArrivalEvent{
+		Place:          place,
+		DistanceMeters: distance,
+		Latitude:       update.Latitude,
+		Longitude:      update.Longitude,
+	}, at)
+	return arrival, nil
+}
+
+// arrivedAtDestination reports whether the destination arrival was already
+// recorded, e.g. by another replica before the driver reconnected here.
+func (s *TripService) arrivedAtDestination(tripID string) bool {
+	events, err := s.repo.ListTripEvents(tripID, []TripEventType{TripEventArrival}, 0)
+	if err != nil {
+		return false
+	}
+	for _, event := range events {
+		var arrival TripArrivalEvent
+		if json.Unmarshal(event.Payload, &arrival) == nil && arrival.Place == ArrivalDestination {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type recordingNotifier struct {
+	statuses []domain.TripStatus
+}
+
+func (n *recordingNotifier) NotifyDriverTripAssigned(ctx context.Context, driver *domain.Driver, trip *domain.Trip) error {
+	return nil
+}
+
+func (n *recordingNotifier) NotifyRiderStatusChange(ctx context.Context, trip *domain.Trip, status domain.TripStatus) error {
+	n.statuses = append(n.statuses, status)
+	return nil
+}
+
+func ping(lat, lng float64) domain.LocationUpdate {
+	return domain.LocationUpdate{Latitude: lat, Longitude: lng, Timestamp: time.Now().UTC()}
+}
+
+func TestTrackArrivalMovesTripToArrivingNearPickup(t *testing.T) {
+	repo := newStubRepo()
+	notifier := &recordingNotifier{}
+	service := domain.NewTripService(repo, nil, notifier, domain.WithGeofence(domain.GeofenceConfig{
+		PickupRadiusMeters: 100, DestinationRadiusMeters: 100, ExitMarginMeters: 50, ConfirmPings: 2,
+	}))
+	trip := multiStopTrip()
+	trip.Stops = nil
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	notifier.statuses = nil
+
+	// About 80m, 120m and 80m from the pickup: the ping inside the exit margin
+	// does not reset the count, so the second ping inside completes arrival.
+	for _, lat := range []float64{10.87072, 10.87108} {
+		arrival, err := service.TrackArrival(context.Background(), trip.ID, ping(lat, 106.80))
+		require.NoError(t, err)
+		require.Nil(t, arrival)
+	}
+	arrival, err := service.TrackArrival(context.Background(), trip.ID, ping(10.87072, 106.80))
+	require.NoError(t, err)
+	require.Equal(t, domain.ArrivalPickup, arrival.Place)
+	require.Equal(t, domain.TripStatusArriving, repo.statuses[trip.ID])
+	require.Equal(t, []domain.TripStatus{domain.TripStatusArriving}, notifier.statuses)
+}
+
+func TestTrackArrivalResetsAfterLeavingTheMargin(t *testing.T) {
+	repo := newStubRepo()
+	service := domain.NewTripService(repo, nil, nil, domain.WithGeofence(domain.GeofenceConfig{
+		PickupRadiusMeters: 100, DestinationRadiusMeters: 100, ExitMarginMeters: 50, ConfirmPings: 2,
+	}))
+	trip := multiStopTrip()
+	trip.Stops = nil
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+
+	// Inside, then about 300m out, then inside again: not yet two in a row.
+	for _, lat := range []float64{10.87072, 10.8727, 10.87072} {
+		arrival, err := service.TrackArrival(context.Background(), trip.ID, ping(lat, 106.80))
+		require.NoError(t, err)
+		require.Nil(t, arrival)
+	}
+	require.Equal(t, domain.TripStatusAccepted, repo.statuses[trip.ID])
+}
+
+func TestTrackArrivalFlagsDestinationOnce(t *testing.T) {
+	repo := newStubRepo()
+	notifier := &recordingNotifier{}
+	service := domain.NewTripService(repo, nil, notifier, domain.WithGeofence(domain.GeofenceConfig{ConfirmPings: 1}))
+	trip := multiStopTrip()
+	trip.Stops = nil
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusInRide))
+	notifier.statuses = nil
+
+	arrival, err := service.TrackArrival(context.Background(), trip.ID, ping(10.88, 106.78))
+	require.NoError(t, err)
+	require.Equal(t, domain.ArrivalDestination, arrival.Place)
+	require.Equal(t, domain.TripStatusArrived, arrival.Status)
+	require.Equal(t, domain.TripStatusInRide, repo.statuses[trip.ID])
+
+	arrival, err = service.TrackArrival(context.Background(), trip.ID, ping(10.88, 106.78))
+	require.NoError(t, err)
+	require.Nil(t, arrival)
+	require.Equal(t, []domain.TripStatus{domain.TripStatusArrived}, notifier.statuses)
+
+	events, err := service.Events(context.Background(), trip.ID, domain.TripEventFilter{Types: []domain.TripEventType{domain.TripEventArrival}})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
@@ -6,29 +6,108 @@ import "time"
 type TripStatus string
 
 const (
+	TripStatusScheduled TripStatus = "scheduled"
 	TripStatusRequested TripStatus = "requested"
 	TripStatusAccepted  TripStatus = "accepted"
 	TripStatusArriving  TripStatus = "arriving"
 	TripStatusInRide    TripStatus = "in_ride"
 	TripStatusCompleted TripStatus = "completed"
 	TripStatusCancelled TripStatus = "cancelled"
+	// TripStatusNoDriverFound marks a trip that exhausted its dispatch attempts.
+	TripStatusNoDriverFound TripStatus = "no_driver_found"
+	// TripStatusArrived is not a lifecycle status: it announces that an in_ride
+	// trip reached its destination, ahead of the driver completing it.
+	TripStatusArrived TripStatus = "arrived"
 )
 
 // Trip represents a rider trip request.
 type Trip struct {
-	ID         string     `json:"id"`
-	RiderID    string     `json:"riderId"`
-	DriverID   *string    `json:"driverId,omitempty"`
-	ServiceID  string     `json:"serviceId"`
-	OriginText string     `json:"originText"`
-	DestText   string     `json:"destText"`
-	OriginLat  *float64   `json:"originLat,omitempty"`
-	OriginLng  *float64   `json:"originLng,omitempty"`
-	DestLat    *float64   `json:"destLat,omitempty"`
-	DestLng    *float64   `json:"destLng,omitempty"`
-	Status     TripStatus `json:"status"`
-	CreatedAt  time.Time  `json:"createdAt"`
-	UpdatedAt  time.Time  `json:"updatedAt"`
+	ID                   string     `json:"id"`
+	RiderID              string     `json:"riderId"`
+	DriverID             *string    `json:"driverId,omitempty"`
+	ServiceID            string     `json:"serviceId"`
+	OriginText           string     `json:"originText"`
+	DestText             string     `json:"destText"`
+	OriginLat            *float64   `json:"originLat,omitempty"`
+	OriginLng            *float64   `json:"originLng,omitempty"`
+	DestLat              *float64   `json:"destLat,omitempty"`
+	DestLng              *float64   `json:"destLng,omitempty"`
+	Stops                []TripStop `json:"stops,omitempty"`
+	QuotedFare           *int64     `json:"quotedFare,omitempty"`
+	FinalFare            *int64     `json:"finalFare,omitempty"`
+	Currency             string     `json:"currency,omitempty"`
+	RouteDistanceMeters  *float64   `json:"routeDistanceMeters,omitempty"`
+	RouteDurationSeconds *float64   `json:"routeDurationSeconds,omitempty"`
+	ActualDistanceMeters *float64   `json:"actualDistanceMeters,omitempty"`
+	FareBasis            FareBasis  `json:"fareBasis,omitempty"`
+	SurgeMultiplier      *float64   `json:"surgeMultiplier,omitempty"`
+	ZoneSurcharge        *int64     `json:"zoneSurcharge,omitempty"`
+	CancellationFee      *int64     `json:"cancellationFee,omitempty"`
+	ScheduledAt          *time.Time `json:"scheduledAt,omitempty"`
+	AcceptedAt           *time.Time `json:"acceptedAt,omitempty"`
+	StartedAt            *time.Time `json:"startedAt,omitempty"`
+	CompletedAt          *time.Time `json:"completedAt,omitempty"`
+	FareChargedAt        *time.Time `json:"fareChargedAt,omitempty"`
+	ETA                  *TripETA   `json:"eta,omitempty"`
+	Status               TripStatus `json:"status"`
+	CreatedAt            time.Time  `json:"createdAt"`
+	UpdatedAt            time.Time  `json:"updatedAt"`
+
+	// MatchedTrail is the in-ride trail snapped to roads at completion. It is
+	// only read by track exports, so it stays out of trip payloads.
+	MatchedTrail *MatchedTrail `json:"-"`
+	// QuoteID names the fare quote the rider booked from. Only Create reads it.
+	QuoteID string `json:"-"`
+}
+
+// TripProgress carries lifecycle facts recorded as a trip moves through its
+// states. Nil fields are left untouched and values already stored are kept.
+type TripProgress struct {
+	FinalFare            *int64     `json:"finalFare,omitempty"`
+	ActualDistanceMeters *float64   `json:"actualDistanceMeters,omitempty"`
+	FareBasis            FareBasis  `json:"fareBasis,omitempty"`
+	CancellationFee      *int64     `json:"cancellationFee,omitempty"`
+	AcceptedAt           *time.Time `json:"acceptedAt,omitempty"`
+	StartedAt            *time.Time `json:"startedAt,omitempty"`
+	CompletedAt          *time.Time `json:"completedAt,omitempty"`
+	FareChargedAt        *time.Time `json:"fareChargedAt,omitempty"`
+}
+
+// IsZero reports whether the progress carries no values.
+func (p TripProgress) IsZero() bool {
+	return p.FinalFare == nil && p.ActualDistanceMeters == nil && p.FareBasis == "" && p.CancellationFee == nil &&
+		p.AcceptedAt == nil && p.StartedAt == nil && p.CompletedAt == nil && p.FareChargedAt == nil
+}
+
+// ApplyTo copies the progress onto fields the trip has not recorded yet.
+func (p TripProgress) ApplyTo(trip *Trip) {
+	if trip == nil {
+		return
+	}
+	if trip.FinalFare == nil {
+		trip.FinalFare = p.FinalFare
+	}
+	if trip.ActualDistanceMeters == nil {
+		trip.ActualDistanceMeters = p.ActualDistanceMeters
+	}
+	if trip.FareBasis == "" {
+		trip.FareBasis = p.FareBasis
+	}
+	if trip.CancellationFee == nil {
+		trip.CancellationFee = p.CancellationFee
+	}
+	if trip.AcceptedAt == nil {
+		trip.AcceptedAt = p.AcceptedAt
+	}
+	if trip.StartedAt == nil {
+		trip.StartedAt = p.StartedAt
+	}
+	if trip.CompletedAt == nil {
+		trip.CompletedAt = p.CompletedAt
+	}
+	if trip.FareChargedAt == nil {
+		trip.FareChargedAt = p.FareChargedAt
+	}
 }
 
 // LocationUpdate represents a driver location ping.
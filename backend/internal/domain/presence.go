@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// PresenceConfig tunes how drivers that stop sending locations, usually
+// because their app crashed or lost signal, are retired.
+type PresenceConfig struct {
+	// StaleAfter is how old a driver's last location may be before the driver
+	// drops out of nearby searches and dispatch.
+	StaleAfter time.Duration
+	// OfflineAfter is the missed-heartbeat window after which an online driver
+	// is switched offline.
+	OfflineAfter time.Duration
+}
+
+// DefaultPresenceConfig returns the baseline staleness and heartbeat windows.
+func DefaultPresenceConfig() PresenceConfig {
+	return PresenceConfig{
+		StaleAfter:   3 * time.Minute,
+		OfflineAfter: 10 * time.Minute,
+	}
+}
+
+// WithPresence overrides the driver staleness and heartbeat windows.
+func WithPresence(cfg PresenceConfig) DriverServiceOption {
+	return func(s *DriverService) {
+		if cfg.StaleAfter > 0 {
+			s.presence.StaleAfter = cfg.StaleAfter
+		}
+		if cfg.OfflineAfter > 0 {
+			s.presence.OfflineAfter = cfg.OfflineAfter
+		}
+	}
+}
+
+// PresenceSweep reports what a sweep did.
+type PresenceSweep struct {
+	// Evicted drivers left the location index but stay online.
+	Evicted []string
+	// WentOffline drivers missed the heartbeat window and were switched off.
+	WentOffline []string
+}
+
+// SweepStaleDrivers evicts drivers whose last location is older than
+// StaleAfter from the location index, so they are no longer dispatched, and
+// switches drivers silent for OfflineAfter offline. A fresh location puts an
+// evicted driver straight back. Several driver-service replicas may sweep.
+func (s *DriverService) SweepStaleDrivers(ctx context.Context, now time.Time) (*PresenceSweep, error) {
+	sweep := &PresenceSweep{}
+	if s.locator == nil {
+		return sweep, nil
+	}
+	stale, err := s.locator.Stale(ctx, now.Add(-s.presence.StaleAfter))
+	if err != nil {
+		return nil, err
+	}
+	for _, loc := range stale {
+		if now.Sub(loc.RecordedAt) >= s.presence.OfflineAfter {
+			_, err := s.UpdateAvailability(ctx, loc.DriverID, DriverOffline)
+			if errors.Is(err, ErrDriverNotFound) {
+				err = s.locator.Remove(ctx, loc.DriverID)
+			}
+			if err != nil {
+				log.Printf("switch silent driver %s offline: %v", loc.DriverID, err)
+				continue
+			}
+			sweep.WentOffline = append(sweep.WentOffline, loc.DriverID)
+			continue
+		}
+		evicted, err := s.locator.Evict(ctx, loc.DriverID)
+		if err != nil {
+			log.Printf("evict stale driver %s: %v", loc.DriverID, err)
+			continue
+		}
+		if evicted {
+			sweep.Evicted = append(sweep.Evicted, loc.DriverID)
+		}
+	}
+	return sweep, nil
+}
@@ -0,0 +1,50 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+func TestSweepStaleDriversEvictsThenSwitchesOffline(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	locator := &fakeLocator{}
+	now := time.Now().UTC()
+
+	drivers.addDriver("fresh", domain.DriverOnline)
+	drivers.addDriver("quiet", domain.DriverOnline)
+	drivers.addDriver("crashed", domain.DriverOnline)
+	locator.add("fresh", 300, now)
+	locator.add("quiet", 400, now.Add(-5*time.Minute))
+	locator.add("crashed", 500, now.Add(-20*time.Minute))
+
+	service := domain.NewDriverService(drivers, newFakeAssignmentRepo(), newStubRepo(), nil, locator,
+		domain.WithPresence(domain.PresenceConfig{StaleAfter: 2 * time.Minute, OfflineAfter: 15 * time.Minute}))
+
+	sweep, err := service.SweepStaleDrivers(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"quiet"}, sweep.Evicted)
+	require.Equal(t, []string{"crashed"}, sweep.WentOffline)
+	require.Equal(t, domain.DriverOnline, drivers.statuses["quiet"])
+	require.Equal(t, domain.DriverOffline, drivers.statuses["crashed"])
+
+	nearby, err := service.SearchNearbyDrivers(context.Background(), 10.87, 106.80, 1000, 10)
+	require.NoError(t, err)
+	require.Len(t, nearby, 1)
+	require.Equal(t, "fresh", nearby[0].ID)
+
+	// Evicted drivers are reported once and go offline when the window closes.
+	sweep, err = service.SweepStaleDrivers(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, sweep.Evicted)
+	require.Empty(t, sweep.WentOffline)
+
+	sweep, err = service.SweepStaleDrivers(context.Background(), now.Add(11*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []string{"quiet"}, sweep.WentOffline)
+	require.Equal(t, domain.DriverOffline, drivers.statuses["quiet"])
+}
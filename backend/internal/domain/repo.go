@@ -1,20 +1,41 @@
 package domain
 
+import "time"
+
 // TripRepository defines persistence operations for trips and events.
 type TripRepository interface {
 	CreateTrip(trip *Trip) error
 	GetTrip(id string) (*Trip, error)
-	UpdateTripStatus(id string, status TripStatus) error
+	TransitionTrip(id string, change TripStatusChange) error
 	SetTripDriver(id string, driverID *string) error
+	SaveTripProgress(id string, progress TripProgress) error
+	MarkTripStopReached(id string, index int, at time.Time) error
+	SaveTripETA(id string, eta *TripETA) error
+	SaveMatchedTrail(id string, trail *MatchedTrail) error
 	SaveLocation(tripID string, update LocationUpdate) error
 	GetLatestLocation(tripID string) (*LocationUpdate, error)
+	ListLocations(tripID string, from, to time.Time) ([]LocationUpdate, error)
+	AppendTripEvent(event TripEvent) error
+	ListTripEvents(tripID string, types []TripEventType, limit int) ([]TripEvent, error)
 	ListTrips(userID string, role string, limit, offset int) ([]*Trip, int64, error)
+	ListScheduledTrips(riderID string, limit int) ([]*Trip, error)
+	ListDueScheduledTrips(before time.Time, limit int) ([]*Trip, error)
+	// ClaimFareCharge marks a completed trip's fare as charged at the given
+	// time and reports whether this call did so. ReleaseFareCharge undoes a
+	// claim whose charge failed, and ListUnchargedTrips returns completed trips
+	// not charged yet.
+	ClaimFareCharge(id string, at time.Time) (bool, error)
+	ReleaseFareCharge(id string) error
+	ListUnchargedTrips(limit int) ([]*Trip, error)
 	PurgeAll() error
 }
 
 // TripSyncRepository exposes the subset of trip operations needed by the driver service.
 type TripSyncRepository interface {
 	GetTrip(id string) (*Trip, error)
-	UpdateTripStatus(id string, status TripStatus) error
+	TransitionTrip(id string, change TripStatusChange) error
 	SetTripDriver(id string, driverID *string) error
+	SaveTripProgress(id string, progress TripProgress) error
+	MarkTripStopReached(id string, index int, at time.Time) error
+	AppendTripEvent(event TripEvent) error
 }
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrInvalidSchedule rejects pickup times in the past or beyond the booking window.
+var ErrInvalidSchedule = errors.New("pickup time must be in the future and within the booking window")
+
+// ScheduleConfig bounds future pickups and when they are handed to dispatch.
+type ScheduleConfig struct {
+	// MaxAhead is how far in advance a pickup may be booked.
+	MaxAhead time.Duration
+	// LeadTime is how long before pickup a scheduled trip is released to
+	// dispatch. Pickups sooner than this are dispatched immediately.
+	LeadTime time.Duration
+}
+
+// DefaultScheduleConfig returns the baseline booking window and lead time.
+func DefaultScheduleConfig() ScheduleConfig {
+	return ScheduleConfig{
+		MaxAhead: 7 * 24 * time.Hour,
+		LeadTime: 15 * time.Minute,
+	}
+}
+
+// WithSchedule overrides the scheduled trip settings.
+func WithSchedule(cfg ScheduleConfig) TripServiceOption {
+	return func(s *TripService) {
+		if cfg.MaxAhead > 0 {
+			s.schedule.MaxAhead = cfg.MaxAhead
+		}
+		if cfg.LeadTime >= 0 {
+			s.schedule.LeadTime = cfg.LeadTime
+		}
+	}
+}
+
+// initialStatus validates a requested pickup time and picks the status a new
+// trip starts in.
+func (c ScheduleConfig) initialStatus(pickup *time.Time, now time.Time) (TripStatus, error) {
+	if pickup == nil {
+		return TripStatusRequested, nil
+	}
+	if pickup.Before(now) || pickup.After(now.Add(c.MaxAhead)) {
+		return "", ErrInvalidSchedule
+	}
+	if pickup.Sub(now) <= c.LeadTime {
+		return TripStatusRequested, nil
+	}
+	return TripStatusScheduled, nil
+}
+
+// ListScheduled returns the rider's upcoming scheduled trips, soonest first.
+func (s *TripService) ListScheduled(ctx context.Context, riderID string, limit int) ([]*Trip, error) {
+	if riderID == "" {
+		return nil, errors.New("rider id required")
+	}
+	return s.repo.ListScheduledTrips(riderID, limit)
+}
+
+// ReleaseDueTrips moves scheduled trips whose pickup falls within the lead time
+// into requested and returns them for dispatch. Trips released or cancelled
+// concurrently are skipped, so several trip-service replicas may run this.
+func (s *TripService) ReleaseDueTrips(ctx context.Context, now time.Time, limit int) ([]*Trip, error) {
+	due, err := s.repo.ListDueScheduledTrips(now.Add(s.schedule.LeadTime), limit)
+	if err != nil {
+		return nil, err
+	}
+	released := make([]*Trip, 0, len(due))
+	for _, candidate := range due {
+		trip, err := s.Transition(ctx, candidate.ID, TripStatusChange{
+			From:   TripStatusScheduled,
+			To:     TripStatusRequested,
+			Actor:  ActorSystem,
+			Reason: "scheduled pickup approaching",
+		})
+		if errors.Is(err, ErrInvalidTransition) {
+			continue
+		}
+		if err != nil {
+			log.Printf("release scheduled trip %s: %v", candidate.ID, err)
+			continue
+		}
+		released = append(released, trip)
+	}
+	return released, nil
+}
+
+// UnreleaseTrip returns a released trip to scheduled when it could not be
+// handed to dispatch, so the next ReleaseDueTrips picks it up again. Trips
+// that moved on in the meantime are left alone.
+func (s *TripService) UnreleaseTrip(ctx context.Context, tripID, reason string) error {
+	_, err := s.Transition(ctx, tripID, TripStatusChange{
+		From:   TripStatusRequested,
+		To:     TripStatusScheduled,
+		Actor:  ActorSystem,
+		Reason: reason,
+	})
+	if errors.Is(err, ErrInvalidTransition) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+func scheduledTrip(pickup time.Time) *domain.Trip {
+	return &domain.Trip{
+		RiderID:     "rider-1",
+		ServiceID:   "uit-bike",
+		OriginText:  "UIT",
+		DestText:    "KTX",
+		ScheduledAt: &pickup,
+	}
+}
+
+func TestTripServiceCreateScheduledTrip(t *testing.T) {
+	repo := newStubRepo()
+	service := domain.NewTripService(repo, nil, nil,
+		domain.WithSchedule(domain.ScheduleConfig{MaxAhead: 48 * time.Hour, LeadTime: 10 * time.Minute}),
+	)
+	now := time.Now().UTC()
+
+	later := scheduledTrip(now.Add(2 * time.Hour))
+	require.NoError(t, service.Create(context.Background(), later))
+	require.Equal(t, domain.TripStatusScheduled, later.Status)
+
+	soon := scheduledTrip(now.Add(5 * time.Minute))
+	require.NoError(t, service.Create(context.Background(), soon))
+	require.Equal(t, domain.TripStatusRequested, soon.Status)
+
+	require.ErrorIs(t, service.Create(context.Background(), scheduledTrip(now.Add(-time.Minute))), domain.ErrInvalidSchedule)
+	require.ErrorIs(t, service.Create(context.Background(), scheduledTrip(now.Add(72*time.Hour))), domain.ErrInvalidSchedule)
+
+	upcoming, err := service.ListScheduled(context.Background(), "rider-1", 10)
+	require.NoError(t, err)
+	require.Len(t, upcoming, 1)
+	require.Equal(t, later.ID, upcoming[0].ID)
+}
+
+func TestTripServiceReleaseDueTrips(t *testing.T) {
+	repo := newStubRepo()
+	service := domain.NewTripService(repo, nil, nil,
+		domain.WithSchedule(domain.ScheduleConfig{LeadTime: 15 * time.Minute}),
+	)
+	now := time.Now().UTC()
+
+	due := scheduledTrip(now.Add(time.Hour))
+	notDue := scheduledTrip(now.Add(3 * time.Hour))
+	cancelled := scheduledTrip(now.Add(time.Hour))
+	for _, trip := range []*domain.Trip{due, notDue, cancelled} {
+		require.NoError(t, service.Create(context.Background(), trip))
+		require.Equal(t, domain.TripStatusScheduled, trip.Status)
+	}
+	_, err := service.Transition(context.Background(), cancelled.ID, domain.TripStatusChange{
+		To:      domain.TripStatusCancelled,
+		Actor:   domain.ActorRider,
+		ActorID: "rider-1",
+	})
+	require.NoError(t, err)
+
+	released, err := service.ReleaseDueTrips(context.Background(), now.Add(50*time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	require.Equal(t, due.ID, released[0].ID)
+	require.Equal(t, domain.TripStatusRequested, released[0].Status)
+
+	stored, err := service.Fetch(context.Background(), notDue.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.TripStatusScheduled, stored.Status)
+
+	// A trip that could not be queued is released again on the next pass.
+	require.NoError(t, service.UnreleaseTrip(context.Background(), due.ID, "dispatch queue unavailable"))
+	released, err = service.ReleaseDueTrips(context.Background(), now.Add(50*time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	require.Equal(t, due.ID, released[0].ID)
+	require.NoError(t, service.UnreleaseTrip(context.Background(), cancelled.ID, "dispatch queue unavailable"))
+	stored, err = service.Fetch(context.Background(), cancelled.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.TripStatusCancelled, stored.Status)
+}
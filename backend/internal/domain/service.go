@@ -14,18 +14,77 @@ type TripService struct {
 	repo     TripRepository
 	wallets  WalletOperations
 	notifier TripEventNotifier
+	fares    *FareEstimator
+
+	settlement   FareSettlementConfig
+	cancellation CancellationPolicy
+	schedule     ScheduleConfig
+	geofence     GeofenceConfig
+	arrivals     geofenceTracker
+	eta          ETAConfig
+	etaRoutes    RouteEstimator
+	etaRefresh   etaThrottle
+	trails       TrailMatcher
+	surge        SurgePricer
+	quotes       QuoteStore
+	quoteTTL     time.Duration
+	areas        *ServiceAreaCatalog
+}
+
+// TripServiceOption customises trip service behaviour.
+type TripServiceOption func(*TripService)
+
+// WithFareEstimator enables route-based fare quotes.
+func WithFareEstimator(fares *FareEstimator) TripServiceOption {
+	return func(s *TripService) {
+		s.fares = fares
+	}
 }
 
 // NewTripService creates a TripService.
-func NewTripService(repo TripRepository, wallets WalletOperations, notifier TripEventNotifier) *TripService {
-	return &TripService{
-		repo:     repo,
-		wallets:  wallets,
-		notifier: notifier,
+func NewTripService(repo TripRepository, wallets WalletOperations, notifier TripEventNotifier, opts ...TripServiceOption) *TripService {
+	service := &TripService{
+		repo:         repo,
+		wallets:      wallets,
+		notifier:     notifier,
+		settlement:   DefaultFareSettlementConfig(),
+		cancellation: DefaultCancellationPolicy(),
+		schedule:     DefaultScheduleConfig(),
+		geofence:     DefaultGeofenceConfig(),
+		eta:          DefaultETAConfig(),
+		quotes:       newMemoryQuoteStore(),
+		quoteTTL:     DefaultQuoteTTL,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(service)
+		}
+	}
+	return service
+}
+
+// EstimateFare quotes a trip from its route, the service's fare rule and the
+// current surge at the pickup. The quote books the trip at this price until it
+// expires.
+func (s *TripService) EstimateFare(ctx context.Context, req FareRequest) (*FareQuote, error) {
+	if s.fares == nil {
+		return nil, ErrFareEstimationUnavailable
+	}
+	quote, err := s.priceTrip(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.issueQuote(ctx, req, quote); err != nil {
+		return nil, err
 	}
+	return quote, nil
 }
 
-// Create registers a new trip for a rider.
+// Create registers a new trip for a rider. Pickups and drop-offs outside the
+// service's areas are rejected. Trips booked with a QuoteID are charged the
+// quoted fare and surge; others are priced now. Trips booked for a
+// pickup beyond the scheduling lead time are stored as scheduled and released
+// to dispatch later.
 func (s *TripService) Create(ctx context.Context, trip *Trip) error {
 	if trip.RiderID == "" {
 		return errors.New("rider id required")
@@ -36,19 +95,55 @@ func (s *TripService) Create(ctx context.Context, trip *Trip) error {
 	if trip.ServiceID == "" {
 		return errors.New("service id required")
 	}
+	stops, err := normalizeStops(trip.Stops)
+	if err != nil {
+		return err
+	}
+	trip.Stops = stops
+	if err := s.checkServiceArea(ctx, trip); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	status, err := s.schedule.initialStatus(trip.ScheduledAt, now)
+	if err != nil {
+		return err
+	}
+	var locked *LockedQuote
+	if trip.QuoteID != "" {
+		if locked, err = s.applyQuote(ctx, trip, now); err != nil {
+			return err
+		}
+	} else if trip.QuotedFare == nil {
+		s.quoteTrip(ctx, trip)
+	}
 	if s.wallets != nil {
-		if _, err := s.wallets.EnsureBalanceForTrip(ctx, trip.RiderID, trip.ServiceID); err != nil {
+		if _, err := s.wallets.EnsureBalanceForTrip(ctx, trip.RiderID, trip.ServiceID, tripFare(trip)); err != nil {
+			s.restoreQuote(ctx, locked, now)
 			return err
 		}
 	}
 	if trip.ID == "" {
 		trip.ID = uuid.NewString()
 	}
-	now := time.Now().UTC()
 	trip.CreatedAt = now
 	trip.UpdatedAt = now
-	trip.Status = TripStatusRequested
-	return s.repo.CreateTrip(trip)
+	trip.Status = status
+	if trip.Currency == "" {
+		trip.Currency = FareCurrency
+	}
+	if err := s.repo.CreateTrip(trip); err != nil {
+		s.restoreQuote(ctx, locked, now)
+		return err
+	}
+	if trip.QuotedFare != nil {
+		s.recordEvent(trip.ID, TripEventFare, TripFareEvent{
+			Kind:            TripFareQuoted,
+			Amount:          *trip.QuotedFare,
+			Currency:        trip.Currency,
+			SurgeMultiplier: trip.SurgeMultiplier,
+		}, now)
+	}
+	return nil
 }
 
 // Fetch retrieves a trip with its current state.
@@ -56,50 +151,232 @@ func (s *TripService) Fetch(ctx context.Context, id string) (*Trip, error) {
 	return s.repo.GetTrip(id)
 }
 
-// UpdateStatus changes the trip status.
+// UpdateStatus changes the trip status on behalf of the system.
 func (s *TripService) UpdateStatus(ctx context.Context, id string, status TripStatus) error {
-	if !isValidStatus(status) {
-		return ErrInvalidStatus
-	}
-	var trip *Trip
-	var err error
-	needsWallet := s.wallets != nil && status == TripStatusCompleted
-	needsTripForNotification := s.notifier != nil && (status == TripStatusArriving || status == TripStatusCompleted)
-	if needsWallet || needsTripForNotification {
-		trip, err = s.repo.GetTrip(id)
-		if err != nil {
-			return err
-		}
+	_, err := s.Transition(ctx, id, TripStatusChange{To: status, Actor: ActorSystem})
+	return err
+}
+
+// Transition moves the trip to change.To if the lifecycle table lets
+// change.Actor make that move, and records who made it and why. Accepting stamps
+// AcceptedAt and starting a ride stamps StartedAt. Completing a ride settles the
+// fare against the in-ride trail and stores it and CompletedAt with the status,
+// then charges it to the rider's wallet. A rider cancelling after the grace
+// period pays the cancellation fee.
+func (s *TripService) Transition(ctx context.Context, id string, change TripStatusChange) (*Trip, error) {
+	if !isValidStatus(change.To) {
+		return nil, ErrInvalidStatus
+	}
+	if change.Actor == "" {
+		change.Actor = ActorSystem
+	}
+	trip, err := s.repo.GetTrip(id)
+	if err != nil {
+		return nil, err
+	}
+	if change.From != "" && change.From != trip.Status {
+		return nil, ErrInvalidTransition
+	}
+	change.From = trip.Status
+	if err := ValidateTransition(change.From, change.To, change.Actor); err != nil {
+		return nil, err
+	}
+	if change.At.IsZero() {
+		change.At = time.Now().UTC()
 	}
 
-	if err := s.repo.UpdateTripStatus(id, status); err != nil {
-		return err
+	now := change.At
+	var progress TripProgress
+	switch change.To {
+	case TripStatusAccepted:
+		if trip.AcceptedAt == nil {
+			progress.AcceptedAt = &now
+		}
+	case TripStatusInRide:
+		if trip.StartedAt == nil {
+			progress.StartedAt = &now
+		}
+	case TripStatusCompleted:
+		fare := s.settleFare(ctx, trip, now, &progress)
+		progress.CompletedAt = &now
+		if fare > 0 {
+			progress.FinalFare = &fare
+		}
+		if s.wallets == nil {
+			// Nothing to bill, so the trip is never left pending a charge.
+			progress.FareChargedAt = &now
+		}
 	}
+	change.Progress = progress
+	if err := s.repo.TransitionTrip(id, change); err != nil {
+		return nil, err
+	}
+	progress.ApplyTo(trip)
 
-	if needsWallet && trip != nil && trip.Status != TripStatusCompleted {
-		if _, _, err := s.wallets.DeductTripFare(ctx, trip.RiderID, trip.ServiceID); err != nil {
-			return err
+	switch change.To {
+	case TripStatusCompleted:
+		if trip.FinalFare != nil {
+			s.recordEvent(id, TripEventFare, TripFareEvent{Kind: TripFareFinal, Amount: *trip.FinalFare, Currency: trip.Currency, Basis: trip.FareBasis}, now)
 		}
-		if _, _, err := s.wallets.RewardTripCompletion(ctx, trip.RiderID); err != nil {
-			return err
+		if s.wallets != nil {
+			// The trip stays completed and uncharged when billing fails, and
+			// ChargePendingFares retries it.
+			if err := s.chargeFare(ctx, trip, now); err != nil {
+				log.Printf("charge fare for trip %s, left pending: %v", trip.ID, err)
+			}
 		}
+	case TripStatusCancelled:
+		cancellation := TripCancellationEvent{Actor: change.Actor, ActorID: change.ActorID, Reason: change.Reason}
+		if fee := s.cancellation.cancellationFee(trip, change); fee > 0 && s.wallets != nil {
+			// The cancellation stands even if the fee cannot be collected.
+			if _, err := s.wallets.ChargeCancellationFee(ctx, trip.RiderID, fee); err != nil {
+				log.Printf("charge cancellation fee for trip %s: %v", trip.ID, err)
+			} else {
+				cancellation.Fee = fee
+				feeProgress := TripProgress{CancellationFee: &fee}
+				if err := s.repo.SaveTripProgress(id, feeProgress); err != nil {
+					return nil, err
+				}
+				feeProgress.ApplyTo(trip)
+			}
+		}
+		s.recordEvent(id, TripEventCancellation, cancellation, now)
+		if cancellation.Fee > 0 {
+			s.recordEvent(id, TripEventFare, TripFareEvent{Kind: TripFareCancellation, Amount: cancellation.Fee, Currency: trip.Currency}, now)
+		}
+	}
+	s.clearStaleETA(trip, change)
+	if change.To == TripStatusCompleted || change.To == TripStatusCancelled {
+		// Finished trips get no more pings to clean up their tracking state.
+		s.arrivals.forget(id)
+		s.etaRefresh.forget(id)
 	}
-	if trip != nil {
-		trip.Status = status
+	trip.Status = change.To
+	s.notifyStatus(ctx, trip, change.To)
+	return trip, nil
+}
+
+// chargeFare bills a completed trip's final fare to the rider's wallet. The
+// charge is claimed on the trip first so that it is never billed twice; a
+// failed charge releases the claim and leaves the trip for ChargePendingFares.
+// Trips completed without a final fare are billed the wallet's flat fare,
+// which is then stored as their final fare.
+func (s *TripService) chargeFare(ctx context.Context, trip *Trip, now time.Time) error {
+	claimed, err := s.repo.ClaimFareCharge(trip.ID, now)
+	if err != nil || !claimed {
+		return err
+	}
+	var fare int64
+	if trip.FinalFare != nil {
+		fare = *trip.FinalFare
+	}
+	_, charged, err := s.wallets.DeductTripFare(ctx, trip.RiderID, trip.ServiceID, fare)
+	if err != nil {
+		if releaseErr := s.repo.ReleaseFareCharge(trip.ID); releaseErr != nil {
+			log.Printf("release fare charge of trip %s: %v", trip.ID, releaseErr)
+		}
+		return err
 	}
-	if s.notifier != nil && trip != nil {
-		if err := s.notifier.NotifyRiderStatusChange(ctx, trip, status); err != nil {
-			log.Printf("notify rider status: %v", err)
+	trip.FareChargedAt = &now
+	if trip.FinalFare == nil && charged > 0 {
+		progress := TripProgress{FinalFare: &charged}
+		if err := s.repo.SaveTripProgress(trip.ID, progress); err != nil {
+			log.Printf("save final fare of trip %s: %v", trip.ID, err)
 		}
+		progress.ApplyTo(trip)
+		s.recordEvent(trip.ID, TripEventFare, TripFareEvent{Kind: TripFareFinal, Amount: charged, Currency: trip.Currency}, now)
+	}
+	if _, _, err := s.wallets.RewardTripCompletion(ctx, trip.RiderID); err != nil {
+		log.Printf("reward trip %s completion: %v", trip.ID, err)
 	}
 	return nil
 }
 
+// ChargePendingFares retries billing completed trips whose charge failed, and
+// returns how many were charged.
+func (s *TripService) ChargePendingFares(ctx context.Context, limit int) (int, error) {
+	if s.wallets == nil {
+		return 0, nil
+	}
+	pending, err := s.repo.ListUnchargedTrips(limit)
+	if err != nil {
+		return 0, err
+	}
+	charged := 0
+	for _, trip := range pending {
+		if err := s.chargeFare(ctx, trip, time.Now().UTC()); err != nil {
+			log.Printf("charge fare for trip %s, left pending: %v", trip.ID, err)
+			continue
+		}
+		if trip.FareChargedAt != nil {
+			charged++
+		}
+	}
+	return charged, nil
+}
+
+func (s *TripService) notifyStatus(ctx context.Context, trip *Trip, status TripStatus) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.NotifyRiderStatusChange(ctx, trip, status); err != nil {
+		log.Printf("notify rider status: %v", err)
+	}
+}
+
+// DriverSync returns the trip operations the driver service needs, with
+// transitions going through Transition so trips drivers complete or cancel are
+// settled and charged like any other. Use it when both services share a process.
+func (s *TripService) DriverSync() TripSyncRepository {
+	return &tripServiceSync{TripRepository: s.repo, service: s}
+}
+
+type tripServiceSync struct {
+	TripRepository
+	service *TripService
+}
+
+func (t *tripServiceSync) TransitionTrip(id string, change TripStatusChange) error {
+	_, err := t.service.Transition(context.Background(), id, change)
+	return err
+}
+
 // AssignDriver links/unlinks a driver to the trip.
 func (s *TripService) AssignDriver(ctx context.Context, id string, driverID *string) error {
 	return s.repo.SetTripDriver(id, driverID)
 }
 
+// SaveProgress records lifecycle timestamps and fares reported by other services.
+func (s *TripService) SaveProgress(ctx context.Context, id string, progress TripProgress) error {
+	if progress.IsZero() {
+		return nil
+	}
+	return s.repo.SaveTripProgress(id, progress)
+}
+
+// Events returns the trip's history oldest first.
+func (s *TripService) Events(ctx context.Context, tripID string, filter TripEventFilter) ([]TripEvent, error) {
+	events, err := s.repo.ListTripEvents(tripID, filter.Types, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	visible := events[:0]
+	for _, event := range events {
+		if filter.allows(event) {
+			visible = append(visible, event)
+		}
+	}
+	return visible, nil
+}
+
+// RecordEvent appends an event reported by another service to the trip history.
+func (s *TripService) RecordEvent(ctx context.Context, event TripEvent) error {
+	if event.TripID == "" || event.Type == "" {
+		return errors.New("trip id and event type required")
+	}
+	return s.repo.AppendTripEvent(event)
+}
+
 // RecordLocation saves a location update.
 func (s *TripService) RecordLocation(ctx context.Context, tripID string, update LocationUpdate) error {
 	return s.repo.SaveLocation(tripID, update)
@@ -129,14 +406,65 @@ func (s *TripService) PurgeAll(_ context.Context) error {
 	return s.repo.PurgeAll()
 }
 
+// quoteTrip stores the route-based fare on trips booked with coordinates. Trips
+// that cannot be quoted keep the flat per-service fare.
+func (s *TripService) quoteTrip(ctx context.Context, trip *Trip) {
+	points := trip.Waypoints()
+	if s.fares == nil || points == nil {
+		return
+	}
+	quote, err := s.priceTrip(ctx, FareRequest{
+		RiderID:   trip.RiderID,
+		ServiceID: trip.ServiceID,
+		OriginLat: *trip.OriginLat,
+		OriginLng: *trip.OriginLng,
+		DestLat:   *trip.DestLat,
+		DestLng:   *trip.DestLng,
+		Stops:     points[1 : len(points)-1],
+	})
+	if err != nil {
+		log.Printf("quote trip fare: %v", err)
+		return
+	}
+	trip.QuotedFare = &quote.Total
+	trip.Currency = quote.Currency
+	trip.RouteDistanceMeters = &quote.DistanceMeters
+	trip.RouteDurationSeconds = &quote.DurationSeconds
+	trip.SurgeMultiplier = &quote.SurgeMultiplier
+	if quote.ZoneSurcharge > 0 {
+		trip.ZoneSurcharge = &quote.ZoneSurcharge
+	}
+}
+
+// recordEvent appends to the trip history. History is best effort: a failed
+// write is logged rather than failing the operation it describes.
+func (s *TripService) recordEvent(tripID string, eventType TripEventType, payload any, at time.Time) {
+	event, err := NewTripEvent(tripID, eventType, payload, at)
+	if err == nil {
+		err = s.repo.AppendTripEvent(event)
+	}
+	if err != nil {
+		log.Printf("record %s event for trip %s: %v", eventType, tripID, err)
+	}
+}
+
+func tripFare(trip *Trip) int64 {
+	if trip == nil || trip.QuotedFare == nil {
+		return 0
+	}
+	return *trip.QuotedFare
+}
+
 func isValidStatus(status TripStatus) bool {
 	switch status {
-	case TripStatusRequested,
+	case TripStatusScheduled,
+		TripStatusRequested,
 		TripStatusAccepted,
 		TripStatusArriving,
 		TripStatusInRide,
 		TripStatusCompleted,
-		TripStatusCancelled:
+		TripStatusCancelled,
+		TripStatusNoDriverFound:
 		return true
 	default:
 		return false
@@ -0,0 +1,373 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPickupOutsideServiceArea indicates the service does not pick riders up there.
+	ErrPickupOutsideServiceArea = errors.New("pickup is outside the service area")
+	// ErrDropoffOutsideServiceArea indicates the service does not drop riders off there.
+	ErrDropoffOutsideServiceArea = errors.New("destination is outside the drop-off area")
+	// ErrServiceAreaCoordinatesRequired indicates a trip without pickup or
+	// destination coordinates on a service restricted to service areas.
+	ErrServiceAreaCoordinatesRequired = errors.New("pickup and destination coordinates are required for this service")
+	// ErrPickupPointRequired indicates the pickup is in a zone that only allows
+	// pickups at designated points. See PickupPointError.
+	ErrPickupPointRequired = errors.New("pickups in this zone must be at a designated pickup point")
+	// ErrServiceAreaNotFound indicates the service area does not exist.
+	ErrServiceAreaNotFound = errors.New("service area not found")
+	// ErrInvalidServiceArea indicates a malformed service area.
+	ErrInvalidServiceArea = errors.New("invalid service area")
+)
+
+// pickupPointRadiusMeters is how close to a designated pickup point a pickup
+// must be.
+const pickupPointRadiusMeters = 75.0
+
+// ServiceAreaKind says what a service area governs.
+type ServiceAreaKind string
+
+const (
+	// ServiceAreaOperating is where a service picks riders up and drops them off.
+	ServiceAreaOperating ServiceAreaKind = "operating"
+	// ServiceAreaDropoff is where a service may also drop riders off, such as a
+	// terminal just outside its operating area.
+	ServiceAreaDropoff ServiceAreaKind = "dropoff"
+	// ServiceAreaZone is an airport or restricted zone that adds a surcharge to
+	// trips starting or ending in it, or only allows pickups at set points.
+	ServiceAreaZone ServiceAreaKind = "zone"
+)
+
+// GeoPoint is a polygon vertex.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// PickupPoint is a designated pickup spot inside a zone.
+type PickupPoint struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+// ServiceArea is an admin-managed polygon. Operating and drop-off areas belong
+// to one service; zones with no ServiceID apply to every service.
+type ServiceArea struct {
+	ID           string          `json:"id"`
+	ServiceID    string          `json:"serviceId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         ServiceAreaKind `json:"kind"`
+	Polygon      []GeoPoint      `json:"polygon"`
+	Surcharge    int64           `json:"surcharge,omitempty"`
+	PickupPoints []PickupPoint   `json:"pickupPoints,omitempty"`
+	Active       bool            `json:"active"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+}
+
+// Validate normalises the area and checks it can be used.
+func (a *ServiceArea) Validate() error {
+	a.ServiceID = strings.ToLower(strings.TrimSpace(a.ServiceID))
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidServiceArea)
+	}
+	switch a.Kind {
+	case ServiceAreaOperating, ServiceAreaDropoff:
+		if a.ServiceID == "" {
+			return fmt.Errorf("%w: %s areas need a service id", ErrInvalidServiceArea, a.Kind)
+		}
+	case ServiceAreaZone:
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidServiceArea, a.Kind)
+	}
+	if len(a.Polygon) < 3 {
+		return fmt.Errorf("%w: polygon needs at least three points", ErrInvalidServiceArea)
+	}
+	for _, point := range a.Polygon {
+		if !validCoordinate(point.Lat, point.Lng) {
+			return fmt.Errorf("%w: invalid polygon point", ErrInvalidServiceArea)
+		}
+	}
+	for _, point := range a.PickupPoints {
+		if !validCoordinate(point.Lat, point.Lng) || !a.Contains(point.Lat, point.Lng) {
+			return fmt.Errorf("%w: pickup points must lie inside the zone", ErrInvalidServiceArea)
+		}
+	}
+	if a.Surcharge < 0 {
+		return fmt.Errorf("%w: surcharge must not be negative", ErrInvalidServiceArea)
+	}
+	return nil
+}
+
+// Contains reports whether the point lies inside the polygon.
+func (a *ServiceArea) Contains(lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(a.Polygon)-1; i < len(a.Polygon); j, i = i, i+1 {
+		pi, pj := a.Polygon[i], a.Polygon[j]
+		if (pi.Lat > lat) != (pj.Lat > lat) &&
+			lng < (pj.Lng-pi.Lng)*(lat-pi.Lat)/(pj.Lat-pi.Lat)+pi.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// nearPickupPoint reports whether the point is at one of the zone's pickup points.
+func (a *ServiceArea) nearPickupPoint(lat, lng float64) bool {
+	for _, point := range a.PickupPoints {
+		if haversineMeters(lat, lng, point.Lat, point.Lng) <= pickupPointRadiusMeters {
+			return true
+		}
+	}
+	return false
+}
+
+// PickupPointError lists where riders may be picked up in a zone.
+type PickupPointError struct {
+	Zone   string
+	Points []PickupPoint
+}
+
+func (e *PickupPointError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPickupPointRequired, e.Zone)
+}
+
+func (e *PickupPointError) Unwrap() error {
+	return ErrPickupPointRequired
+}
+
+// ServiceAreaRepository persists service areas.
+type ServiceAreaRepository interface {
+	ListServiceAreas(ctx context.Context) ([]*ServiceArea, error)
+	CreateServiceArea(ctx context.Context, area *ServiceArea) error
+	UpdateServiceArea(ctx context.Context, area *ServiceArea) error
+	DeleteServiceArea(ctx context.Context, id string) error
+}
+
+// ServiceAreaCatalog keeps the service areas in memory, reloading them from
+// the repository every refresh and right after this replica changes one.
+type ServiceAreaCatalog struct {
+	repo    ServiceAreaRepository
+	refresh time.Duration
+
+	mu       sync.RWMutex
+	areas    []*ServiceArea
+	loadedAt time.Time
+}
+
+// NewServiceAreaCatalog caches repo's areas for refresh.
+func NewServiceAreaCatalog(repo ServiceAreaRepository, refresh time.Duration) *ServiceAreaCatalog {
+	if refresh <= 0 {
+		refresh = time.Minute
+	}
+	return &ServiceAreaCatalog{repo: repo, refresh: refresh}
+}
+
+// List returns every service area.
+func (c *ServiceAreaCatalog) List(ctx context.Context) ([]*ServiceArea, error) {
+	return c.load(ctx)
+}
+
+// Create validates and stores a new area.
+func (c *ServiceAreaCatalog) Create(ctx context.Context, area *ServiceArea) error {
+	if err := area.Validate(); err != nil {
+		return err
+	}
+	if err := c.repo.CreateServiceArea(ctx, area); err != nil {
+		return err
+	}
+	c.invalidate()
+	return nil
+}
+
+// Update validates and replaces an area.
+func (c *ServiceAreaCatalog) Update(ctx context.Context, area *ServiceArea) error {
+	if err := area.Validate(); err != nil {
+		return err
+	}
+	if err := c.repo.UpdateServiceArea(ctx, area); err != nil {
+		return err
+	}
+	c.invalidate()
+	return nil
+}
+
+// Delete removes an area.
+func (c *ServiceAreaCatalog) Delete(ctx context.Context, id string) error {
+	if err := c.repo.DeleteServiceArea(ctx, id); err != nil {
+		return err
+	}
+	c.invalidate()
+	return nil
+}
+
+func (c *ServiceAreaCatalog) invalidate() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// load returns the cached areas, reloading them when they are older than the
+// refresh interval. A failed reload keeps serving the previous areas.
+func (c *ServiceAreaCatalog) load(ctx context.Context) ([]*ServiceArea, error) {
+	c.mu.RLock()
+	areas, loadedAt := c.areas, c.loadedAt
+	c.mu.RUnlock()
+	if !loadedAt.IsZero() && time.Since(loadedAt) < c.refresh {
+		return areas, nil
+	}
+	fresh, err := c.repo.ListServiceAreas(ctx)
+	if err != nil {
+		if areas == nil {
+			return nil, err
+		}
+		log.Printf("reload service areas, keeping the cached ones: %v", err)
+		return areas, nil
+	}
+	c.mu.Lock()
+	c.areas, c.loadedAt = fresh, time.Now()
+	c.mu.Unlock()
+	return fresh, nil
+}
+
+// Check validates a trip's pickup and drop-offs against the service's areas
+// and returns the zone surcharge it incurs. Services without operating or
+// drop-off areas may pick up and drop off anywhere.
+func (c *ServiceAreaCatalog) Check(ctx context.Context, serviceID string, pickup Waypoint, dropoffs []Waypoint) (int64, error) {
+	pickupAreas, dropoffAreas, zones, err := c.serviceAreas(ctx, serviceID)
+	if err != nil {
+		return 0, err
+	}
+	if len(pickupAreas) > 0 && !anyContains(pickupAreas, pickup) {
+		return 0, ErrPickupOutsideServiceArea
+	}
+	if len(dropoffAreas) > 0 {
+		for _, dropoff := range dropoffs {
+			if !anyContains(dropoffAreas, dropoff) {
+				return 0, ErrDropoffOutsideServiceArea
+			}
+		}
+	}
+
+	var surcharge int64
+	for _, zone := range zones {
+		atPickup := zone.Contains(pickup.Lat, pickup.Lng)
+		if atPickup && len(zone.PickupPoints) > 0 && !zone.nearPickupPoint(pickup.Lat, pickup.Lng) {
+			return 0, &PickupPointError{Zone: zone.Name, Points: zone.PickupPoints}
+		}
+		atDestination := len(dropoffs) > 0 && zone.Contains(dropoffs[len(dropoffs)-1].Lat, dropoffs[len(dropoffs)-1].Lng)
+		if atPickup || atDestination {
+			surcharge += zone.Surcharge
+		}
+	}
+	return surcharge, nil
+}
+
+// serviceAreas returns the active areas that apply to serviceID: where it may
+// pick up, where it may drop off, and the zones it passes surcharges for.
+func (c *ServiceAreaCatalog) serviceAreas(ctx context.Context, serviceID string) (pickupAreas, dropoffAreas, zones []*ServiceArea, err error) {
+	areas, err := c.load(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serviceID = strings.ToLower(strings.TrimSpace(serviceID))
+	for _, area := range areas {
+		if !area.Active {
+			continue
+		}
+		switch {
+		case area.Kind == ServiceAreaZone && (area.ServiceID == "" || area.ServiceID == serviceID):
+			zones = append(zones, area)
+		case area.ServiceID != serviceID:
+		case area.Kind == ServiceAreaOperating:
+			pickupAreas = append(pickupAreas, area)
+			dropoffAreas = append(dropoffAreas, area)
+		case area.Kind == ServiceAreaDropoff:
+			dropoffAreas = append(dropoffAreas, area)
+		}
+	}
+	return pickupAreas, dropoffAreas, zones, nil
+}
+
+// requiresCoordinates reports whether serviceID has operating or drop-off areas,
+// so its trips cannot be checked without pickup and destination coordinates.
+func (c *ServiceAreaCatalog) requiresCoordinates(ctx context.Context, serviceID string) (bool, error) {
+	pickupAreas, dropoffAreas, _, err := c.serviceAreas(ctx, serviceID)
+	if err != nil {
+		return false, err
+	}
+	return len(pickupAreas) > 0 || len(dropoffAreas) > 0, nil
+}
+
+func anyContains(areas []*ServiceArea, point Waypoint) bool {
+	for _, area := range areas {
+		if area.Contains(point.Lat, point.Lng) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithServiceAreas restricts where trips may start and end and applies zone
+// surcharges.
+func WithServiceAreas(areas *ServiceAreaCatalog) TripServiceOption {
+	return func(s *TripService) {
+		s.areas = areas
+	}
+}
+
+// checkServiceArea rejects trips picking up or dropping off outside the
+// service's areas. Trips without pickup or destination coordinates are only
+// accepted on services that are not restricted to areas.
+func (s *TripService) checkServiceArea(ctx context.Context, trip *Trip) error {
+	if s.areas == nil {
+		return nil
+	}
+	if trip.OriginLat == nil || trip.OriginLng == nil || trip.DestLat == nil || trip.DestLng == nil {
+		required, err := s.areas.requiresCoordinates(ctx, trip.ServiceID)
+		if err != nil {
+			return err
+		}
+		if required {
+			return ErrServiceAreaCoordinatesRequired
+		}
+		if trip.OriginLat == nil || trip.OriginLng == nil {
+			return nil
+		}
+	}
+	dropoffs := make([]Waypoint, 0, len(trip.Stops)+1)
+	for _, stop := range trip.Stops {
+		dropoffs = append(dropoffs, Waypoint{Lat: stop.Lat, Lng: stop.Lng})
+	}
+	if trip.DestLat != nil && trip.DestLng != nil {
+		dropoffs = append(dropoffs, Waypoint{Lat: *trip.DestLat, Lng: *trip.DestLng})
+	}
+	_, err := s.areas.Check(ctx, trip.ServiceID, Waypoint{Lat: *trip.OriginLat, Lng: *trip.OriginLng}, dropoffs)
+	return err
+}
+
+// zoneSurcharge checks the fare request against the service areas and returns
+// the zone surcharge it incurs.
+func (s *TripService) zoneSurcharge(ctx context.Context, req FareRequest) (int64, error) {
+	if s.areas == nil {
+		return 0, nil
+	}
+	dropoffs := append(append([]Waypoint(nil), req.Stops...), Waypoint{Lat: req.DestLat, Lng: req.DestLng})
+	return s.areas.Check(ctx, req.ServiceID, Waypoint{Lat: req.OriginLat, Lng: req.OriginLng}, dropoffs)
+}
+
+func tripZoneSurcharge(trip *Trip) int64 {
+	if trip == nil || trip.ZoneSurcharge == nil {
+		return 0
+	}
+	return *trip.ZoneSurcharge
+}
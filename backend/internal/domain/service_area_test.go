@@ -0,0 +1,125 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type memoryServiceAreas struct {
+	areas []*domain.ServiceArea
+	err   error
+}
+
+func (r *memoryServiceAreas) ListServiceAreas(ctx context.Context) ([]*domain.ServiceArea, error) {
+	return r.areas, r.err
+}
+
+func (r *memoryServiceAreas) CreateServiceArea(ctx context.Context, area *domain.ServiceArea) error {
+	r.areas = append(r.areas, area)
+	return nil
+}
+
+func (r *memoryServiceAreas) UpdateServiceArea(ctx context.Context, area *domain.ServiceArea) error {
+	return nil
+}
+
+func (r *memoryServiceAreas) DeleteServiceArea(ctx context.Context, id string) error {
+	return nil
+}
+
+func square(minLat, minLng, maxLat, maxLng float64) []domain.GeoPoint {
+	return []domain.GeoPoint{{Lat: minLat, Lng: minLng}, {Lat: minLat, Lng: maxLng}, {Lat: maxLat, Lng: maxLng}, {Lat: maxLat, Lng: minLng}}
+}
+
+func TestTripServiceEnforcesServiceAreas(t *testing.T) {
+	repo := &memoryServiceAreas{}
+	catalog := domain.NewServiceAreaCatalog(repo, time.Hour)
+	ctx := context.Background()
+	require.NoError(t, catalog.Create(ctx, &domain.ServiceArea{
+		ServiceID: "UIT-Bike", Name: "Campus", Kind: domain.ServiceAreaOperating,
+		Polygon: square(10.86, 106.77, 10.89, 106.81), Active: true,
+	}))
+	require.NoError(t, catalog.Create(ctx, &domain.ServiceArea{
+		Name: "Airport", Kind: domain.ServiceAreaZone, Surcharge: 10000,
+		Polygon:      square(10.81, 106.65, 10.82, 106.67),
+		PickupPoints: []domain.PickupPoint{{Name: "Arrivals", Lat: 10.815, Lng: 106.66}},
+		Active:       true,
+	}))
+	require.ErrorIs(t, catalog.Create(ctx, &domain.ServiceArea{
+		Name: "Pickup point outside", Kind: domain.ServiceAreaZone,
+		Polygon:      square(10.81, 106.65, 10.82, 106.67),
+		PickupPoints: []domain.PickupPoint{{Name: "Elsewhere", Lat: 10.9, Lng: 106.9}},
+	}), domain.ErrInvalidServiceArea)
+
+	wallet := &recordingWallet{}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(&detachedRepo{stubRepo: newStubRepo()}, wallet, nil,
+		domain.WithFareEstimator(estimator),
+		domain.WithServiceAreas(catalog))
+
+	newTrip := func(serviceID string, originLat, originLng, destLat, destLng float64) *domain.Trip {
+		return &domain.Trip{
+			RiderID:    "rider-1",
+			ServiceID:  serviceID,
+			OriginText: "A",
+			DestText:   "B",
+			OriginLat:  &originLat,
+			OriginLng:  &originLng,
+			DestLat:    &destLat,
+			DestLng:    &destLng,
+		}
+	}
+
+	// uit-bike is campus-only.
+	require.NoError(t, service.Create(ctx, newTrip("uit-bike", 10.87, 106.80, 10.88, 106.78)))
+	require.ErrorIs(t, service.Create(ctx, newTrip("uit-bike", 10.77, 106.70, 10.88, 106.78)), domain.ErrPickupOutsideServiceArea)
+	require.ErrorIs(t, service.Create(ctx, newTrip("uit-bike", 10.87, 106.80, 10.77, 106.70)), domain.ErrDropoffOutsideServiceArea)
+
+	// Area-restricted services need coordinates to check.
+	noDestination := newTrip("uit-bike", 10.87, 106.80, 0, 0)
+	noDestination.DestLat, noDestination.DestLng = nil, nil
+	require.ErrorIs(t, service.Create(ctx, noDestination), domain.ErrServiceAreaCoordinatesRequired)
+	textOnly := &domain.Trip{RiderID: "rider-1", ServiceID: "uit-car", OriginText: "A", DestText: "B"}
+	require.NoError(t, service.Create(ctx, textOnly))
+
+	// Services without areas go anywhere, but airport pickups must be at a pickup point.
+	err := service.Create(ctx, newTrip("uit-car", 10.811, 106.651, 10.88, 106.78))
+	var pickupPoint *domain.PickupPointError
+	require.True(t, errors.As(err, &pickupPoint))
+	require.ErrorIs(t, err, domain.ErrPickupPointRequired)
+	require.Equal(t, "Arrivals", pickupPoint.Points[0].Name)
+
+	quote, err := service.EstimateFare(ctx, domain.FareRequest{
+		RiderID: "rider-1", ServiceID: "uit-car",
+		OriginLat: 10.8152, OriginLng: 106.6601, DestLat: 10.88, DestLng: 106.78,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10000), quote.ZoneSurcharge)
+	require.Equal(t, int64(106000), quote.Total)
+
+	trip := newTrip("uit-car", 10.88, 106.78, 10.8152, 106.6601)
+	require.NoError(t, service.Create(ctx, trip))
+	require.Equal(t, int64(10000), *trip.ZoneSurcharge, "drop-offs in the zone are surcharged too")
+	require.Equal(t, int64(106000), wallet.ensured)
+
+	// A failed reload keeps the cached areas.
+	repo.err = errors.New("database down")
+	cached := domain.NewServiceAreaCatalog(repo, time.Nanosecond)
+	_, err = cached.List(ctx)
+	require.Error(t, err)
+	repo.err = nil
+	areas, err := cached.List(ctx)
+	require.NoError(t, err)
+	repo.err = errors.New("database down")
+	stale, err := cached.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, areas, stale)
+}
@@ -2,6 +2,8 @@ package domain_test
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -14,6 +16,9 @@ type stubRepo struct {
 	trips        map[string]*domain.Trip
 	statuses     map[string]domain.TripStatus
 	lastLocation *domain.LocationUpdate
+	locations    []domain.LocationUpdate
+	changes      []domain.TripStatusChange
+	events       []domain.TripEvent
 }
 
 var _ domain.TripRepository = (*stubRepo)(nil)
@@ -39,13 +44,18 @@ func (s *stubRepo) GetTrip(id string) (*domain.Trip, error) {
 	return trip, nil
 }
 
-func (s *stubRepo) UpdateTripStatus(id string, status domain.TripStatus) error {
+func (s *stubRepo) TransitionTrip(id string, change domain.TripStatusChange) error {
 	trip, ok := s.trips[id]
 	if !ok {
 		return domain.ErrTripNotFound
 	}
-	s.statuses[id] = status
-	trip.Status = status
+	if trip.Status != change.From {
+		return domain.ErrInvalidTransition
+	}
+	s.statuses[id] = change.To
+	s.changes = append(s.changes, change)
+	trip.Status = change.To
+	change.Progress.ApplyTo(trip)
 	return nil
 }
 
@@ -58,7 +68,76 @@ func (s *stubRepo) SetTripDriver(id string, driverID *string) error {
 	return nil
 }
 
+func (s *stubRepo) SaveTripProgress(id string, progress domain.TripProgress) error {
+	trip, ok := s.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	progress.ApplyTo(trip)
+	return nil
+}
+
+func (s *stubRepo) ClaimFareCharge(id string, at time.Time) (bool, error) {
+	trip, ok := s.trips[id]
+	if !ok || trip.Status != domain.TripStatusCompleted || trip.FareChargedAt != nil {
+		return false, nil
+	}
+	trip.FareChargedAt = &at
+	return true, nil
+}
+
+func (s *stubRepo) ReleaseFareCharge(id string) error {
+	trip, ok := s.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	trip.FareChargedAt = nil
+	return nil
+}
+
+func (s *stubRepo) ListUnchargedTrips(limit int) ([]*domain.Trip, error) {
+	items := make([]*domain.Trip, 0)
+	for _, trip := range s.trips {
+		if trip.Status == domain.TripStatusCompleted && trip.FareChargedAt == nil {
+			items = append(items, trip)
+		}
+	}
+	return items, nil
+}
+
+func (s *stubRepo) MarkTripStopReached(id string, index int, at time.Time) error {
+	trip, ok := s.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	stop, ok := trip.Stop(index)
+	if !ok {
+		return domain.ErrStopNotFound
+	}
+	stop.ArrivedAt = &at
+	return nil
+}
+
+func (s *stubRepo) SaveMatchedTrail(id string, trail *domain.MatchedTrail) error {
+	trip, ok := s.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	trip.MatchedTrail = trail
+	return nil
+}
+
+func (s *stubRepo) SaveTripETA(id string, eta *domain.TripETA) error {
+	trip, ok := s.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	trip.ETA = eta
+	return nil
+}
+
 func (s *stubRepo) SaveLocation(tripID string, update domain.LocationUpdate) error {
+	s.locations = append(s.locations, update)
 	s.lastLocation = &update
 	return nil
 }
@@ -67,6 +146,36 @@ func (s *stubRepo) GetLatestLocation(tripID string) (*domain.LocationUpdate, err
 	return s.lastLocation, nil
 }
 
+func (s *stubRepo) ListLocations(tripID string, from, to time.Time) ([]domain.LocationUpdate, error) {
+	items := make([]domain.LocationUpdate, 0, len(s.locations))
+	for _, update := range s.locations {
+		if !update.Timestamp.Before(from) && !update.Timestamp.After(to) {
+			items = append(items, update)
+		}
+	}
+	return items, nil
+}
+
+func (s *stubRepo) AppendTripEvent(event domain.TripEvent) error {
+	event.ID = fmt.Sprintf("event-%d", len(s.events)+1)
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *stubRepo) ListTripEvents(tripID string, types []domain.TripEventType, limit int) ([]domain.TripEvent, error) {
+	items := make([]domain.TripEvent, 0, len(s.events))
+	for _, event := range s.events {
+		if event.TripID != tripID {
+			continue
+		}
+		if len(types) > 0 && !slices.Contains(types, event.Type) {
+			continue
+		}
+		items = append(items, event)
+	}
+	return items, nil
+}
+
 func (s *stubRepo) ListTrips(userID string, role string, limit, offset int) ([]*domain.Trip, int64, error) {
 	items := make([]*domain.Trip, 0, len(s.trips))
 	for _, trip := range s.trips {
@@ -75,6 +184,26 @@ func (s *stubRepo) ListTrips(userID string, role string, limit, offset int) ([]*
 	return items, int64(len(items)), nil
 }
 
+func (s *stubRepo) ListScheduledTrips(riderID string, limit int) ([]*domain.Trip, error) {
+	items := make([]*domain.Trip, 0)
+	for _, trip := range s.trips {
+		if trip.RiderID == riderID && trip.Status == domain.TripStatusScheduled {
+			items = append(items, trip)
+		}
+	}
+	return items, nil
+}
+
+func (s *stubRepo) ListDueScheduledTrips(before time.Time, limit int) ([]*domain.Trip, error) {
+	items := make([]*domain.Trip, 0)
+	for _, trip := range s.trips {
+		if trip.Status == domain.TripStatusScheduled && trip.ScheduledAt != nil && !trip.ScheduledAt.After(before) {
+			items = append(items, trip)
+		}
+	}
+	return items, nil
+}
+
 func (s *stubRepo) PurgeAll() error {
 	s.trips = make(map[string]*domain.Trip)
 	s.statuses = make(map[string]domain.TripStatus)
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// MaxTripStops caps the intermediate stops a rider can add to one trip.
+const MaxTripStops = 3
+
+var (
+	// ErrInvalidStops rejects stops without a label or valid coordinates, or too many of them.
+	ErrInvalidStops = errors.New("stops need a label and valid coordinates, at most 3 per trip")
+	// ErrStopNotFound indicates the trip has no stop at the given index.
+	ErrStopNotFound = errors.New("trip stop not found")
+	// ErrStopOutOfOrder rejects reaching a stop before the ones preceding it.
+	ErrStopOutOfOrder = errors.New("earlier stops must be reached first")
+	// ErrStopAlreadyReached indicates the stop already has an arrival recorded.
+	ErrStopAlreadyReached = errors.New("trip stop already reached")
+)
+
+// TripStop is an intermediate waypoint between pickup and drop-off. Stops are
+// visited in Index order.
+type TripStop struct {
+	Index     int        `json:"index"`
+	Text      string     `json:"text"`
+	Lat       float64    `json:"lat"`
+	Lng       float64    `json:"lng"`
+	ArrivedAt *time.Time `json:"arrivedAt,omitempty"`
+}
+
+// Waypoint is a coordinate a route passes through.
+type Waypoint struct {
+	Lat float64
+	Lng float64
+}
+
+// normalizeStops validates the stops of a new trip and numbers them in the
+// order given.
+func normalizeStops(stops []TripStop) ([]TripStop, error) {
+	if len(stops) == 0 {
+		return nil, nil
+	}
+	if len(stops) > MaxTripStops {
+		return nil, ErrInvalidStops
+	}
+	normalized := make([]TripStop, 0, len(stops))
+	for i, stop := range stops {
+		text := strings.TrimSpace(stop.Text)
+		if text == "" || !validCoordinate(stop.Lat, stop.Lng) {
+			return nil, ErrInvalidStops
+		}
+		normalized = append(normalized, TripStop{Index: i, Text: text, Lat: stop.Lat, Lng: stop.Lng})
+	}
+	return normalized, nil
+}
+
+func validCoordinate(lat, lng float64) bool {
+	return !math.IsNaN(lat) && !math.IsNaN(lng) && lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// Stop returns the stop at index.
+func (t *Trip) Stop(index int) (*TripStop, bool) {
+	for i := range t.Stops {
+		if t.Stops[i].Index == index {
+			return &t.Stops[i], true
+		}
+	}
+	return nil, false
+}
+
+// checkStopArrival verifies the stop at index can be marked reached: the ride
+// is under way, the stop exists and has not been reached, and every earlier
+// stop has.
+func (t *Trip) checkStopArrival(index int) error {
+	stop, ok := t.Stop(index)
+	if !ok {
+		return ErrStopNotFound
+	}
+	if t.Status != TripStatusInRide {
+		return ErrInvalidTransition
+	}
+	if stop.ArrivedAt != nil {
+		return ErrStopAlreadyReached
+	}
+	for _, earlier := range t.Stops {
+		if earlier.Index < index && earlier.ArrivedAt == nil {
+			return ErrStopOutOfOrder
+		}
+	}
+	return nil
+}
+
+// ReachStop records the driver arriving at an intermediate stop during the
+// ride. Stops must be reached in order; the arrival is added to the trip's
+// history by the repository.
+func (s *TripService) ReachStop(ctx context.Context, tripID string, index int, at time.Time) (*Trip, error) {
+	trip, err := s.repo.GetTrip(tripID)
+	if err != nil {
+		return nil, err
+	}
+	if err := trip.checkStopArrival(index); err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	if err := s.repo.MarkTripStopReached(tripID, index, at); err != nil {
+		return nil, err
+	}
+	stop, _ := trip.Stop(index)
+	stop.ArrivedAt = &at
+	return trip, nil
+}
+
+// ReachStop records the assigned driver arriving at an intermediate stop.
+func (s *DriverService) ReachStop(ctx context.Context, tripID, driverID string, index int) (*Trip, error) {
+	trip, err := s.trips.GetTrip(tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip.DriverID == nil || *trip.DriverID != driverID {
+		return nil, ErrTransitionNotPermitted
+	}
+	if err := trip.checkStopArrival(index); err != nil {
+		return nil, err
+	}
+	at := time.Now().UTC()
+	if err := s.trips.MarkTripStopReached(tripID, index, at); err != nil {
+		return nil, err
+	}
+	stop, _ := trip.Stop(index)
+	stop.ArrivedAt = &at
+	return trip, nil
+}
+
+// Waypoints lists pickup, stops and drop-off, or nil when the trip lacks
+// pickup or drop-off coordinates.
+func (t *Trip) Waypoints() []Waypoint {
+	if t.OriginLat == nil || t.OriginLng == nil || t.DestLat == nil || t.DestLng == nil {
+		return nil
+	}
+	points := make([]Waypoint, 0, len(t.Stops)+2)
+	points = append(points, Waypoint{Lat: *t.OriginLat, Lng: *t.OriginLng})
+	for _, stop := range t.Stops {
+		points = append(points, Waypoint{Lat: stop.Lat, Lng: stop.Lng})
+	}
+	return append(points, Waypoint{Lat: *t.DestLat, Lng: *t.DestLng})
+}
@@ -0,0 +1,101 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type waypointRouteEstimator struct {
+	stubRouteEstimator
+	waypoints []domain.Waypoint
+}
+
+func (s *waypointRouteEstimator) EstimateRouteVia(ctx context.Context, waypoints []domain.Waypoint) (*domain.RouteEstimate, error) {
+	s.waypoints = waypoints
+	return &domain.RouteEstimate{DistanceMeters: 9000, DurationSeconds: 1500}, nil
+}
+
+func multiStopTrip() *domain.Trip {
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	return &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+		Stops: []domain.TripStop{
+			{Text: "Pharmacy", Lat: 10.872, Lng: 106.795},
+			{Text: "Bookstore", Lat: 10.876, Lng: 106.790},
+		},
+	}
+}
+
+func TestFareEstimatorPricesRouteThroughStops(t *testing.T) {
+	legs := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 2000, DurationSeconds: 300},
+	})
+	quote, err := legs.Estimate(context.Background(), domain.FareRequest{
+		ServiceID: "uit-bike",
+		OriginLat: 10.87, OriginLng: 106.80,
+		DestLat: 10.88, DestLng: 106.78,
+		Stops: []domain.Waypoint{{Lat: 10.872, Lng: 106.795}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4000.0, quote.DistanceMeters)
+	require.Equal(t, 600.0, quote.DurationSeconds)
+
+	routes := &waypointRouteEstimator{}
+	service := domain.NewTripService(newStubRepo(), nil, nil, domain.WithFareEstimator(domain.NewFareEstimator(routes)))
+	trip := multiStopTrip()
+	require.NoError(t, service.Create(context.Background(), trip))
+	require.Len(t, routes.waypoints, 4)
+	require.Equal(t, domain.Waypoint{Lat: 10.876, Lng: 106.790}, routes.waypoints[2])
+	require.Equal(t, 9000.0, *trip.RouteDistanceMeters)
+	require.Equal(t, 1, trip.Stops[1].Index)
+}
+
+func TestTripServiceRejectsInvalidStops(t *testing.T) {
+	service := domain.NewTripService(newStubRepo(), nil, nil)
+
+	trip := multiStopTrip()
+	trip.Stops[0].Text = " "
+	require.ErrorIs(t, service.Create(context.Background(), trip), domain.ErrInvalidStops)
+
+	trip = multiStopTrip()
+	trip.Stops = append(trip.Stops, trip.Stops...)
+	require.ErrorIs(t, service.Create(context.Background(), trip), domain.ErrInvalidStops)
+}
+
+func TestTripServiceReachStopsInOrder(t *testing.T) {
+	service := domain.NewTripService(newStubRepo(), nil, nil)
+	trip := multiStopTrip()
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	_, err := service.ReachStop(context.Background(), trip.ID, 0, trip.CreatedAt)
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusAccepted))
+	require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, domain.TripStatusInRide))
+
+	_, err = service.ReachStop(context.Background(), trip.ID, 1, trip.CreatedAt)
+	require.ErrorIs(t, err, domain.ErrStopOutOfOrder)
+	_, err = service.ReachStop(context.Background(), trip.ID, 5, trip.CreatedAt)
+	require.ErrorIs(t, err, domain.ErrStopNotFound)
+
+	updated, err := service.ReachStop(context.Background(), trip.ID, 0, trip.CreatedAt)
+	require.NoError(t, err)
+	require.NotNil(t, updated.Stops[0].ArrivedAt)
+	_, err = service.ReachStop(context.Background(), trip.ID, 0, trip.CreatedAt)
+	require.ErrorIs(t, err, domain.ErrStopAlreadyReached)
+
+	updated, err = service.ReachStop(context.Background(), trip.ID, 1, trip.CreatedAt)
+	require.NoError(t, err)
+	require.NotNil(t, updated.Stops[1].ArrivedAt)
+}
@@ -0,0 +1,214 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrQuoteExpired indicates the fare quote is unknown, expired or already used.
+	ErrQuoteExpired = errors.New("fare quote expired")
+	// ErrQuoteMismatch indicates the trip differs from the one that was quoted.
+	ErrQuoteMismatch = errors.New("fare quote does not match the trip")
+)
+
+const (
+	// DefaultQuoteTTL is how long a rider may book at a quoted price.
+	DefaultQuoteTTL = 2 * time.Minute
+	// quoteMatchMeters is how far a booked pickup or drop-off may be from the
+	// quoted one.
+	quoteMatchMeters = 250.0
+)
+
+// SurgePricer returns the demand multiplier for a service around a pickup.
+type SurgePricer interface {
+	SurgeMultiplier(ctx context.Context, serviceID string, lat, lng float64) (float64, error)
+}
+
+// LockedQuote is a quote handed to a rider together with what was priced.
+type LockedQuote struct {
+	Quote   FareQuote   `json:"quote"`
+	RiderID string      `json:"riderId"`
+	Request FareRequest `json:"request"`
+}
+
+// QuoteStore keeps issued quotes until they expire. TakeQuote returns and
+// removes a quote in one step, so concurrent bookings cannot both get it, and
+// returns ErrQuoteExpired for quotes it no longer holds.
+type QuoteStore interface {
+	SaveQuote(ctx context.Context, quote *LockedQuote, ttl time.Duration) error
+	TakeQuote(ctx context.Context, id string) (*LockedQuote, error)
+}
+
+// WithSurgePricing applies pricer's multiplier to fare quotes.
+func WithSurgePricing(pricer SurgePricer) TripServiceOption {
+	return func(s *TripService) {
+		s.surge = pricer
+	}
+}
+
+// WithQuoteStore keeps issued quotes in store for ttl. Without it quotes are
+// held in memory, which only works with a single trip-service replica.
+func WithQuoteStore(store QuoteStore, ttl time.Duration) TripServiceOption {
+	return func(s *TripService) {
+		if store != nil {
+			s.quotes = store
+		}
+		if ttl > 0 {
+			s.quoteTTL = ttl
+		}
+	}
+}
+
+// priceTrip quotes the route, applies the current surge at the pickup and adds
+// any airport or restricted zone surcharge.
+func (s *TripService) priceTrip(ctx context.Context, req FareRequest) (*FareQuote, error) {
+	quote, err := s.fares.Estimate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if s.surge != nil {
+		multiplier, err := s.surge.SurgeMultiplier(ctx, quote.ServiceID, req.OriginLat, req.OriginLng)
+		if err != nil {
+			log.Printf("surge for %s quote: %v", quote.ServiceID, err)
+		} else {
+			ApplySurge(quote, multiplier)
+		}
+	}
+	surcharge, err := s.zoneSurcharge(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// Zone surcharges are flat fees and are not surged.
+	quote.ZoneSurcharge = surcharge
+	quote.Total += surcharge
+	return quote, nil
+}
+
+// issueQuote stores the quote for the rider so a trip can be booked at its
+// price until it expires.
+func (s *TripService) issueQuote(ctx context.Context, req FareRequest, quote *FareQuote) error {
+	expiresAt := time.Now().UTC().Add(s.quoteTTL)
+	quote.QuoteID = uuid.NewString()
+	quote.ExpiresAt = &expiresAt
+	return s.quotes.SaveQuote(ctx, &LockedQuote{Quote: *quote, RiderID: req.RiderID, Request: req}, s.quoteTTL)
+}
+
+// applyQuote takes the quote out of the store, so it books at most one trip,
+// and locks its fare and surge into a trip being created. The caller hands the
+// quote to restoreQuote if the trip is not created after all.
+func (s *TripService) applyQuote(ctx context.Context, trip *Trip, now time.Time) (*LockedQuote, error) {
+	locked, err := s.quotes.TakeQuote(ctx, trip.QuoteID)
+	if err != nil {
+		return nil, err
+	}
+	quote := locked.Quote
+	if quote.ExpiresAt != nil && now.After(*quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+	if !quoteMatches(locked, trip) {
+		s.restoreQuote(ctx, locked, now)
+		return nil, ErrQuoteMismatch
+	}
+	trip.QuotedFare = &quote.Total
+	trip.Currency = quote.Currency
+	trip.RouteDistanceMeters = &quote.DistanceMeters
+	trip.RouteDurationSeconds = &quote.DurationSeconds
+	trip.SurgeMultiplier = &quote.SurgeMultiplier
+	if quote.ZoneSurcharge > 0 {
+		trip.ZoneSurcharge = &quote.ZoneSurcharge
+	}
+	return locked, nil
+}
+
+// restoreQuote puts back a quote taken for a trip that was not created, for
+// the rest of its lifetime.
+func (s *TripService) restoreQuote(ctx context.Context, locked *LockedQuote, now time.Time) {
+	if locked == nil {
+		return
+	}
+	ttl := s.quoteTTL
+	if locked.Quote.ExpiresAt != nil {
+		ttl = locked.Quote.ExpiresAt.Sub(now)
+	}
+	if ttl <= 0 {
+		return
+	}
+	if err := s.quotes.SaveQuote(ctx, locked, ttl); err != nil {
+		log.Printf("restore fare quote %s: %v", locked.Quote.QuoteID, err)
+	}
+}
+
+// quoteMatches reports whether trip is the one the rider was quoted: same
+// service, and pickup, stops and destination each within quoteMatchMeters.
+func quoteMatches(locked *LockedQuote, trip *Trip) bool {
+	req := locked.Request
+	if locked.RiderID != trip.RiderID ||
+		locked.Quote.ServiceID != strings.ToLower(strings.TrimSpace(trip.ServiceID)) ||
+		len(req.Stops) != len(trip.Stops) {
+		return false
+	}
+	near := func(lat, lng *float64, quotedLat, quotedLng float64) bool {
+		return lat != nil && lng != nil && haversineMeters(*lat, *lng, quotedLat, quotedLng) <= quoteMatchMeters
+	}
+	for i, stop := range trip.Stops {
+		if !near(&stop.Lat, &stop.Lng, req.Stops[i].Lat, req.Stops[i].Lng) {
+			return false
+		}
+	}
+	return near(trip.OriginLat, trip.OriginLng, req.OriginLat, req.OriginLng) &&
+		near(trip.DestLat, trip.DestLng, req.DestLat, req.DestLng)
+}
+
+func tripSurge(trip *Trip) float64 {
+	if trip == nil || trip.SurgeMultiplier == nil {
+		return 1
+	}
+	return *trip.SurgeMultiplier
+}
+
+// memoryQuoteStore holds quotes in process.
+type memoryQuoteStore struct {
+	mu     sync.Mutex
+	quotes map[string]memoryQuote
+}
+
+type memoryQuote struct {
+	quote     LockedQuote
+	expiresAt time.Time
+}
+
+func newMemoryQuoteStore() *memoryQuoteStore {
+	return &memoryQuoteStore{quotes: make(map[string]memoryQuote)}
+}
+
+func (m *memoryQuoteStore) SaveQuote(_ context.Context, quote *LockedQuote, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, held := range m.quotes {
+		if now.After(held.expiresAt) {
+			delete(m.quotes, id)
+		}
+	}
+	m.quotes[quote.Quote.QuoteID] = memoryQuote{quote: *quote, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *memoryQuoteStore) TakeQuote(_ context.Context, id string) (*LockedQuote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	held, ok := m.quotes[id]
+	delete(m.quotes, id)
+	if !ok || time.Now().After(held.expiresAt) {
+		return nil, ErrQuoteExpired
+	}
+	quote := held.quote
+	return &quote, nil
+}
@@ -0,0 +1,113 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+type stubSurge struct {
+	multiplier float64
+}
+
+func (s *stubSurge) SurgeMultiplier(ctx context.Context, serviceID string, lat, lng float64) (float64, error) {
+	return s.multiplier, nil
+}
+
+func TestTripServiceLocksQuotedSurge(t *testing.T) {
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	wallet := &recordingWallet{}
+	pricer := &stubSurge{multiplier: 1.5}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(repo, wallet, nil,
+		domain.WithFareEstimator(estimator),
+		domain.WithSurgePricing(pricer),
+		domain.WithQuoteStore(nil, time.Minute))
+	ctx := context.Background()
+
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	quote, err := service.EstimateFare(ctx, domain.FareRequest{
+		RiderID:   "rider-1",
+		ServiceID: "uit-bike",
+		OriginLat: originLat,
+		OriginLng: originLng,
+		DestLat:   destLat,
+		DestLng:   destLng,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, quote.QuoteID)
+	require.WithinDuration(t, time.Now().Add(time.Minute), *quote.ExpiresAt, 5*time.Second)
+	require.Equal(t, 1.5, quote.SurgeMultiplier)
+	// The 36500 ride fare surges by half; the booking fee does not.
+	require.Equal(t, int64(18250), quote.SurgeFare)
+	require.Equal(t, int64(56750), quote.Total)
+
+	newTrip := func(riderID string) *domain.Trip {
+		return &domain.Trip{
+			RiderID:    riderID,
+			ServiceID:  "uit-bike",
+			OriginText: "UIT",
+			DestText:   "KTX",
+			OriginLat:  &originLat,
+			OriginLng:  &originLng,
+			DestLat:    &destLat,
+			DestLng:    &destLng,
+			QuoteID:    quote.QuoteID,
+		}
+	}
+	require.ErrorIs(t, service.Create(ctx, newTrip("rider-2")), domain.ErrQuoteMismatch)
+
+	// A booking that fails after taking the quote puts it back.
+	wallet.balanceErr = domain.ErrWalletInsufficientFunds
+	require.ErrorIs(t, service.Create(ctx, newTrip("rider-1")), domain.ErrWalletInsufficientFunds)
+	wallet.balanceErr = nil
+
+	// Surge rising after the quote does not change the booked price.
+	pricer.multiplier = 3
+	trip := newTrip("rider-1")
+	require.NoError(t, service.Create(ctx, trip))
+	require.Equal(t, int64(56750), *trip.QuotedFare)
+	require.Equal(t, 1.5, *trip.SurgeMultiplier)
+	require.Equal(t, int64(56750), wallet.ensured)
+
+	require.ErrorIs(t, service.Create(ctx, newTrip("rider-1")), domain.ErrQuoteExpired, "quotes book one trip")
+
+	require.NoError(t, service.UpdateStatus(ctx, trip.ID, domain.TripStatusAccepted))
+	require.NoError(t, service.UpdateStatus(ctx, trip.ID, domain.TripStatusInRide))
+	require.NoError(t, service.UpdateStatus(ctx, trip.ID, domain.TripStatusCompleted))
+	require.Equal(t, int64(56750), wallet.deducted)
+}
+
+func TestQuotedTripMustKeepItsStops(t *testing.T) {
+	service := domain.NewTripService(&detachedRepo{stubRepo: newStubRepo()}, nil, nil,
+		domain.WithFareEstimator(domain.NewFareEstimator(&stubRouteEstimator{
+			estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+		})),
+		domain.WithQuoteStore(nil, time.Minute))
+	ctx := context.Background()
+
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	quote, err := service.EstimateFare(ctx, domain.FareRequest{
+		RiderID: "rider-1", ServiceID: "uit-bike",
+		OriginLat: originLat, OriginLng: originLng, DestLat: destLat, DestLng: destLng,
+		Stops: []domain.Waypoint{{Lat: 10.875, Lng: 106.79}},
+	})
+	require.NoError(t, err)
+
+	newTrip := func(stopLat float64) *domain.Trip {
+		return &domain.Trip{
+			RiderID: "rider-1", ServiceID: "uit-bike", OriginText: "UIT", DestText: "KTX",
+			OriginLat: &originLat, OriginLng: &originLng, DestLat: &destLat, DestLng: &destLng,
+			Stops:   []domain.TripStop{{Text: "Stop", Lat: stopLat, Lng: 106.79}},
+			QuoteID: quote.QuoteID,
+		}
+	}
+	require.ErrorIs(t, service.Create(ctx, newTrip(10.95)), domain.ErrQuoteMismatch)
+	require.NoError(t, service.Create(ctx, newTrip(10.875)))
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// FareBasis records which distance a completed trip was billed on.
+type FareBasis string
+
+const (
+	FareBasisRoute  FareBasis = "route"
+	FareBasisActual FareBasis = "actual"
+)
+
+// FareSettlementConfig tunes how the in-ride location trail settles the fare.
+type FareSettlementConfig struct {
+	// DistanceTolerance is the fraction the travelled distance may differ from the
+	// route estimate before the trip is billed on the travelled distance.
+	DistanceTolerance float64
+	// MinSegmentMeters drops pings closer than this to the last accepted point,
+	// which is GPS jitter while the vehicle is stopped.
+	MinSegmentMeters float64
+	// MaxSpeedMPS drops pings implying a faster jump than any real vehicle makes.
+	MaxSpeedMPS float64
+}
+
+// DefaultFareSettlementConfig returns the baseline trail filtering and tolerance.
+func DefaultFareSettlementConfig() FareSettlementConfig {
+	return FareSettlementConfig{
+		DistanceTolerance: 0.15,
+		MinSegmentMeters:  10,
+		MaxSpeedMPS:       50,
+	}
+}
+
+// WithFareSettlement overrides the actual-distance billing settings.
+func WithFareSettlement(cfg FareSettlementConfig) TripServiceOption {
+	return func(s *TripService) {
+		if cfg.DistanceTolerance >= 0 {
+			s.settlement.DistanceTolerance = cfg.DistanceTolerance
+		}
+		if cfg.MinSegmentMeters >= 0 {
+			s.settlement.MinSegmentMeters = cfg.MinSegmentMeters
+		}
+		if cfg.MaxSpeedMPS > 0 {
+			s.settlement.MaxSpeedMPS = cfg.MaxSpeedMPS
+		}
+	}
+}
+
+// MatchedTrail is a location trail snapped to the road network. Coordinates
+// are [lng, lat] pairs.
+type MatchedTrail struct {
+	Coordinates    [][]float64 `json:"coordinates"`
+	DistanceMeters float64     `json:"distanceMeters"`
+	// Snapped is false when no map-matching backend was available and the
+	// trail was only smoothed.
+	Snapped bool `json:"snapped"`
+}
+
+// TrailMatcher snaps a raw location trail to the road network.
+type TrailMatcher interface {
+	MatchTrail(ctx context.Context, points []LocationUpdate) (*MatchedTrail, error)
+}
+
+// WithTrailMatching snaps the in-ride trail to roads when a trip completes.
+// The snapped distance is billed instead of the filtered raw trail, and the
+// snapped geometry is kept for track exports.
+func WithTrailMatching(matcher TrailMatcher) TripServiceOption {
+	return func(s *TripService) {
+		s.trails = matcher
+	}
+}
+
+// TrailDistance sums the distance travelled along a location trail, skipping
+// stationary jitter and impossible jumps.
+func TrailDistance(points []LocationUpdate, cfg FareSettlementConfig) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	ordered := make([]LocationUpdate, len(points))
+	copy(ordered, points)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+	})
+
+	total := 0.0
+	last := ordered[0]
+	for _, point := range ordered[1:] {
+		segment := haversineMeters(last.Latitude, last.Longitude, point.Latitude, point.Longitude)
+		if segment < cfg.MinSegmentMeters {
+			continue
+		}
+		if elapsed := point.Timestamp.Sub(last.Timestamp).Seconds(); cfg.MaxSpeedMPS > 0 && elapsed > 0 && segment/elapsed > cfg.MaxSpeedMPS {
+			continue
+		}
+		total += segment
+		last = point
+	}
+	return total
+}
+
+// Trail returns the locations recorded for the trip from booking until it
+// completed, or until now for trips still under way.
+func (s *TripService) Trail(ctx context.Context, trip *Trip) ([]LocationUpdate, error) {
+	if trip == nil {
+		return nil, ErrTripNotFound
+	}
+	until := time.Now().UTC()
+	if trip.CompletedAt != nil {
+		until = *trip.CompletedAt
+	}
+	return s.repo.ListLocations(trip.ID, trip.CreatedAt, until)
+}
+
+// settleFare works out what a completing trip is charged. The quoted fare stands
+// unless the in-ride trail differs from the route estimate by more than the
+// tolerance, in which case the trip is repriced on the travelled distance and
+// the time spent in the ride, at the surge and zone surcharge it was booked
+// with.
+func (s *TripService) settleFare(ctx context.Context, trip *Trip, until time.Time, progress *TripProgress) int64 {
+	fare := tripFare(trip)
+	if trip.QuotedFare != nil {
+		progress.FareBasis = FareBasisRoute
+	}
+	if trip.StartedAt == nil {
+		return fare
+	}
+	points, err := s.repo.ListLocations(trip.ID, *trip.StartedAt, until)
+	if err != nil {
+		log.Printf("settle fare for trip %s: %v", trip.ID, err)
+		return fare
+	}
+	if len(points) < 2 {
+		return fare
+	}
+	actual := TrailDistance(points, s.settlement)
+	// A trail that was only smoothed is kept for export but not billed.
+	if matched := s.matchTrail(ctx, trip, points); matched != nil && matched.Snapped {
+		actual = matched.DistanceMeters
+	}
+	progress.ActualDistanceMeters = &actual
+
+	if s.fares == nil || trip.RouteDistanceMeters == nil || *trip.RouteDistanceMeters <= 0 {
+		return fare
+	}
+	route := *trip.RouteDistanceMeters
+	if math.Abs(actual-route)/route <= s.settlement.DistanceTolerance {
+		return fare
+	}
+	rule, err := s.fares.Rule(trip.ServiceID)
+	if err != nil {
+		log.Printf("settle fare for trip %s: %v", trip.ID, err)
+		return fare
+	}
+	progress.FareBasis = FareBasisActual
+	repriced := PriceFare(rule, actual, until.Sub(*trip.StartedAt).Seconds())
+	ApplySurge(repriced, tripSurge(trip))
+	return repriced.Total + tripZoneSurcharge(trip)
+}
+
+// matchTrail snaps the in-ride trail and stores the result. It returns nil when
+// matching is off or fails, leaving billing on the raw trail.
+func (s *TripService) matchTrail(ctx context.Context, trip *Trip, points []LocationUpdate) *MatchedTrail {
+	if s.trails == nil {
+		return nil
+	}
+	matched, err := s.trails.MatchTrail(ctx, points)
+	if err != nil {
+		log.Printf("match trail of trip %s: %v", trip.ID, err)
+		return nil
+	}
+	if err := s.repo.SaveMatchedTrail(trip.ID, matched); err != nil {
+		log.Printf("save matched trail of trip %s: %v", trip.ID, err)
+	}
+	return matched
+}
+
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+	deltaLat := toRadians(lat2 - lat1)
+	deltaLng := toRadians(lng2 - lng1)
+	sinLat := math.Sin(deltaLat / 2)
+	sinLng := math.Sin(deltaLng / 2)
+	h := sinLat*sinLat + math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*sinLng*sinLng
+	return 2 * earthRadius * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
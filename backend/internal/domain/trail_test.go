@@ -0,0 +1,172 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+// northboundTrail returns pings roughly 1km apart, one minute apart.
+func northboundTrail(start time.Time, legs int) []domain.LocationUpdate {
+	points := make([]domain.LocationUpdate, 0, legs+1)
+	for i := 0; i <= legs; i++ {
+		points = append(points, domain.LocationUpdate{
+			Latitude:  10.80 + float64(i)*0.009,
+			Longitude: 106.80,
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return points
+}
+
+func TestTrailDistanceFiltersJitterAndJumps(t *testing.T) {
+	cfg := domain.DefaultFareSettlementConfig()
+	start := time.Now().UTC().Add(-time.Hour)
+	clean := northboundTrail(start, 4)
+	baseline := domain.TrailDistance(clean, cfg)
+	require.InDelta(t, 4000, baseline, 50)
+
+	noisy := append([]domain.LocationUpdate{}, clean...)
+	noisy = append(noisy,
+		// Stationary jitter a couple of meters from the first ping.
+		domain.LocationUpdate{Latitude: 10.80002, Longitude: 106.80001, Timestamp: start.Add(10 * time.Second)},
+		// A bogus fix 100km away one second later.
+		domain.LocationUpdate{Latitude: 11.70, Longitude: 106.80, Timestamp: start.Add(61 * time.Second)},
+	)
+	require.InDelta(t, baseline, domain.TrailDistance(noisy, cfg), 1)
+}
+
+func TestTripServiceBillsActualDistanceBeyondTolerance(t *testing.T) {
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	wallet := &recordingWallet{}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(repo, wallet, nil, domain.WithFareEstimator(estimator))
+
+	originLat, originLng, destLat, destLng := 10.80, 106.80, 10.86, 106.80
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	started := time.Now().UTC().Add(-10 * time.Minute)
+	repo.trips[trip.ID].StartedAt = &started
+	repo.trips[trip.ID].Status = domain.TripStatusInRide
+	trail := northboundTrail(started, 7)
+	for _, point := range trail {
+		require.NoError(t, service.RecordLocation(context.Background(), trip.ID, point))
+	}
+
+	// The ride took 25 minutes against the 15 quoted, and is billed for them.
+	completeTripAt(t, service, trip.ID, started.Add(25*time.Minute))
+
+	stored := repo.trips[trip.ID]
+	actual := domain.TrailDistance(trail, domain.DefaultFareSettlementConfig())
+	rule, err := estimator.Rule("uit-bike")
+	require.NoError(t, err)
+	expected := domain.PriceFare(rule, actual, 1500).Total
+	require.NotEqual(t, domain.PriceFare(rule, actual, 900).Total, expected)
+
+	require.Equal(t, domain.FareBasisActual, stored.FareBasis)
+	require.InDelta(t, actual, *stored.ActualDistanceMeters, 0.01)
+	require.Equal(t, 5500.0, *stored.RouteDistanceMeters)
+	require.Equal(t, expected, *stored.FinalFare)
+	require.Equal(t, expected, wallet.deducted)
+	require.NotEqual(t, *stored.QuotedFare, *stored.FinalFare)
+}
+
+// stubTrailMatcher snaps every trail to the same distance, or only smooths it
+// when smoothOnly is set.
+type stubTrailMatcher struct {
+	distance   float64
+	smoothOnly bool
+	points     int
+}
+
+func (m *stubTrailMatcher) MatchTrail(ctx context.Context, points []domain.LocationUpdate) (*domain.MatchedTrail, error) {
+	m.points = len(points)
+	return &domain.MatchedTrail{
+		Coordinates:    [][]float64{{106.80, 10.80}, {106.80, 10.86}},
+		DistanceMeters: m.distance,
+		Snapped:        !m.smoothOnly,
+	}, nil
+}
+
+func TestTripServiceBillsSnappedTrail(t *testing.T) {
+	stored := completeMatchedTrip(t, &stubTrailMatcher{distance: 7200})
+	require.Equal(t, domain.FareBasisActual, stored.FareBasis)
+	require.Equal(t, 7200.0, *stored.ActualDistanceMeters)
+	require.Equal(t, domain.PriceFare(bikeRule(t), 7200, 1500).Total, *stored.FinalFare)
+	require.NotNil(t, stored.MatchedTrail)
+	require.Equal(t, 7200.0, stored.MatchedTrail.DistanceMeters)
+
+	// A smoothed-only trail is stored but the raw trail is billed.
+	stored = completeMatchedTrip(t, &stubTrailMatcher{distance: 7200, smoothOnly: true})
+	require.NotNil(t, stored.MatchedTrail)
+	require.InDelta(t, 7000, *stored.ActualDistanceMeters, 100)
+	require.Equal(t, domain.PriceFare(bikeRule(t), *stored.ActualDistanceMeters, 1500).Total, *stored.FinalFare)
+}
+
+func bikeRule(t *testing.T) domain.FareRule {
+	t.Helper()
+	rule, err := domain.NewFareEstimator(nil).Rule("uit-bike")
+	require.NoError(t, err)
+	return rule
+}
+
+// completeMatchedTrip rides a 7km trail in 25 minutes on a trip quoted at 5.5km
+// and 15 minutes, and completes it with matcher snapping the trail.
+func completeMatchedTrip(t *testing.T, matcher *stubTrailMatcher) *domain.Trip {
+	t.Helper()
+	repo := &detachedRepo{stubRepo: newStubRepo()}
+	estimator := domain.NewFareEstimator(&stubRouteEstimator{
+		estimate: &domain.RouteEstimate{DistanceMeters: 5500, DurationSeconds: 900},
+	})
+	service := domain.NewTripService(repo, nil, nil, domain.WithFareEstimator(estimator), domain.WithTrailMatching(matcher))
+
+	originLat, originLng, destLat, destLng := 10.80, 106.80, 10.86, 106.80
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	started := time.Now().UTC().Add(-10 * time.Minute)
+	repo.trips[trip.ID].StartedAt = &started
+	repo.trips[trip.ID].Status = domain.TripStatusInRide
+	for _, point := range northboundTrail(started, 7) {
+		require.NoError(t, service.RecordLocation(context.Background(), trip.ID, point))
+	}
+
+	completeTripAt(t, service, trip.ID, started.Add(25*time.Minute))
+	require.Equal(t, 8, matcher.points)
+	return repo.trips[trip.ID]
+}
+
+func completeTripAt(t *testing.T, service *domain.TripService, tripID string, at time.Time) {
+	t.Helper()
+	_, err := service.Transition(context.Background(), tripID, domain.TripStatusChange{
+		To:    domain.TripStatusCompleted,
+		Actor: domain.ActorSystem,
+		At:    at,
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTransition      = errors.New("trip status transition not allowed")
+	ErrTransitionNotPermitted = errors.New("actor may not make this trip status transition")
+)
+
+// TransitionActor identifies who moved a trip between statuses.
+type TransitionActor string
+
+const (
+	ActorRider  TransitionActor = "rider"
+	ActorDriver TransitionActor = "driver"
+	ActorAdmin  TransitionActor = "admin"
+	ActorSystem TransitionActor = "system"
+)
+
+// TripStatusChange records one transition of a trip, who made it and why.
+type TripStatusChange struct {
+	From    TripStatus      `json:"from"`
+	To      TripStatus      `json:"to"`
+	Actor   TransitionActor `json:"actor"`
+	ActorID string          `json:"actorId,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+	At      time.Time       `json:"at"`
+
+	// Progress is stored in the same update as the status, so facts settled by
+	// the transition cannot be lost after it commits. It is not recorded in the
+	// trip history.
+	Progress TripProgress `json:"-"`
+}
+
+// tripTransitions is the trip lifecycle shared by the trip and driver services:
+// for each status, the statuses it may move to and the actors allowed to do it.
+var tripTransitions = map[TripStatus]map[TripStatus][]TransitionActor{
+	TripStatusScheduled: {
+		TripStatusRequested: {ActorAdmin, ActorSystem},
+		TripStatusCancelled: {ActorRider, ActorAdmin, ActorSystem},
+	},
+	TripStatusRequested: {
+		TripStatusScheduled:     {ActorSystem},
+		TripStatusAccepted:      {ActorDriver, ActorAdmin, ActorSystem},
+		TripStatusCancelled:     {ActorRider, ActorAdmin, ActorSystem},
+		TripStatusNoDriverFound: {ActorAdmin, ActorSystem},
+	},
+	TripStatusAccepted: {
+		TripStatusRequested: {ActorAdmin, ActorSystem},
+		TripStatusArriving:  {ActorDriver, ActorAdmin, ActorSystem},
+		TripStatusInRide:    {ActorDriver, ActorAdmin, ActorSystem},
+		TripStatusCancelled: {ActorRider, ActorDriver, ActorAdmin, ActorSystem},
+	},
+	TripStatusArriving: {
+		TripStatusInRide:    {ActorDriver, ActorAdmin, ActorSystem},
+		TripStatusCancelled: {ActorRider, ActorDriver, ActorAdmin, ActorSystem},
+	},
+	TripStatusInRide: {
+		TripStatusCompleted: {ActorDriver, ActorAdmin, ActorSystem},
+		TripStatusCancelled: {ActorDriver, ActorAdmin, ActorSystem},
+	},
+	TripStatusNoDriverFound: {
+		TripStatusCancelled: {ActorRider, ActorAdmin, ActorSystem},
+	},
+}
+
+// ValidateTransition checks the move from one status to another against the
+// lifecycle table and the actor making it.
+func ValidateTransition(from, to TripStatus, actor TransitionActor) error {
+	if !isValidStatus(to) {
+		return ErrInvalidStatus
+	}
+	actors, ok := tripTransitions[from][to]
+	if !ok {
+		return ErrInvalidTransition
+	}
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+	return ErrTransitionNotPermitted
+}
+
+// CanTransition reports whether actor may move a trip from one status to another.
+func CanTransition(from, to TripStatus, actor TransitionActor) bool {
+	return ValidateTransition(from, to, actor) == nil
+}
+
+// ActorForRole maps an authenticated role onto a transition actor.
+func ActorForRole(role string) TransitionActor {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "driver":
+		return ActorDriver
+	case "admin":
+		return ActorAdmin
+	default:
+		return ActorRider
+	}
+}
+
+// CancellationPolicy decides when a rider cancellation is charged.
+type CancellationPolicy struct {
+	// GracePeriod is how long after a driver accepts the rider may cancel for free.
+	GracePeriod time.Duration
+	// Fee is charged to riders cancelling after the grace period.
+	Fee int64
+}
+
+// DefaultCancellationPolicy returns the baseline grace period and fee.
+func DefaultCancellationPolicy() CancellationPolicy {
+	return CancellationPolicy{
+		GracePeriod: 2 * time.Minute,
+		Fee:         10000,
+	}
+}
+
+// WithCancellationPolicy overrides the rider cancellation fee rules.
+func WithCancellationPolicy(policy CancellationPolicy) TripServiceOption {
+	return func(s *TripService) {
+		if policy.GracePeriod >= 0 {
+			s.cancellation.GracePeriod = policy.GracePeriod
+		}
+		if policy.Fee >= 0 {
+			s.cancellation.Fee = policy.Fee
+		}
+	}
+}
+
+// cancellationFee returns the fee owed when the change cancels an accepted trip
+// on the rider's behalf after the grace period, or zero.
+func (p CancellationPolicy) cancellationFee(trip *Trip, change TripStatusChange) int64 {
+	if p.Fee <= 0 || change.Actor != ActorRider || change.To != TripStatusCancelled {
+		return 0
+	}
+	if change.From != TripStatusAccepted && change.From != TripStatusArriving {
+		return 0
+	}
+	if trip == nil || trip.AcceptedAt == nil || change.At.Sub(*trip.AcceptedAt) <= p.GracePeriod {
+		return 0
+	}
+	return p.Fee
+}
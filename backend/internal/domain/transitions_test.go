@@ -0,0 +1,115 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+func TestValidateTransitionEnforcesActors(t *testing.T) {
+	require.NoError(t, domain.ValidateTransition(domain.TripStatusAccepted, domain.TripStatusCancelled, domain.ActorRider))
+	require.NoError(t, domain.ValidateTransition(domain.TripStatusInRide, domain.TripStatusCompleted, domain.ActorDriver))
+	require.ErrorIs(t, domain.ValidateTransition(domain.TripStatusInRide, domain.TripStatusCancelled, domain.ActorRider), domain.ErrTransitionNotPermitted)
+	require.ErrorIs(t, domain.ValidateTransition(domain.TripStatusArriving, domain.TripStatusCompleted, domain.ActorDriver), domain.ErrInvalidTransition)
+	require.ErrorIs(t, domain.ValidateTransition(domain.TripStatusCompleted, domain.TripStatusCancelled, domain.ActorAdmin), domain.ErrInvalidTransition)
+	require.ErrorIs(t, domain.ValidateTransition(domain.TripStatusRequested, domain.TripStatus("lost"), domain.ActorSystem), domain.ErrInvalidStatus)
+}
+
+func TestTripServiceChargesLateRiderCancellation(t *testing.T) {
+	policy := domain.CancellationPolicy{GracePeriod: time.Minute, Fee: 15000}
+	cases := []struct {
+		name       string
+		acceptedAt time.Time
+		fee        int64
+	}{
+		{name: "within grace", acceptedAt: time.Now().UTC().Add(-30 * time.Second)},
+		{name: "after grace", acceptedAt: time.Now().UTC().Add(-5 * time.Minute), fee: 15000},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &detachedRepo{stubRepo: newStubRepo()}
+			wallet := &recordingWallet{}
+			service := domain.NewTripService(repo, wallet, nil, domain.WithCancellationPolicy(policy))
+
+			acceptedAt := tc.acceptedAt
+			repo.trips["trip-1"] = &domain.Trip{
+				ID:         "trip-1",
+				RiderID:    "rider-1",
+				ServiceID:  "uit-bike",
+				Status:     domain.TripStatusAccepted,
+				AcceptedAt: &acceptedAt,
+			}
+
+			trip, err := service.Transition(context.Background(), "trip-1", domain.TripStatusChange{
+				To:      domain.TripStatusCancelled,
+				Actor:   domain.ActorRider,
+				ActorID: "rider-1",
+				Reason:  "changed plans",
+			})
+			require.NoError(t, err)
+			require.Equal(t, domain.TripStatusCancelled, trip.Status)
+			require.Equal(t, tc.fee, wallet.cancelFee)
+			if tc.fee > 0 {
+				require.Equal(t, tc.fee, *repo.trips["trip-1"].CancellationFee)
+			} else {
+				require.Nil(t, repo.trips["trip-1"].CancellationFee)
+			}
+
+			require.Len(t, repo.changes, 1)
+			change := repo.changes[0]
+			require.Equal(t, domain.TripStatusAccepted, change.From)
+			require.Equal(t, domain.ActorRider, change.Actor)
+			require.Equal(t, "changed plans", change.Reason)
+		})
+	}
+}
+
+func TestTripServiceRejectsRiderCompletingTrip(t *testing.T) {
+	repo := newStubRepo()
+	service := domain.NewTripService(repo, nil, nil)
+	repo.trips["trip-1"] = &domain.Trip{ID: "trip-1", RiderID: "rider-1", Status: domain.TripStatusInRide}
+
+	_, err := service.Transition(context.Background(), "trip-1", domain.TripStatusChange{
+		To:    domain.TripStatusCompleted,
+		Actor: domain.ActorRider,
+	})
+	require.ErrorIs(t, err, domain.ErrTransitionNotPermitted)
+	require.Equal(t, domain.TripStatusInRide, repo.trips["trip-1"].Status)
+	require.Empty(t, repo.changes)
+}
+
+func TestDriverStatusUpdateRequiresAcceptedAssignment(t *testing.T) {
+	drivers := newFakeDriverRepo()
+	drivers.addDriver("driver-a", domain.DriverOnline)
+	drivers.addDriver("driver-b", domain.DriverOnline)
+	assignments := newFakeAssignmentRepo()
+	trips := newStubRepo()
+	trip := newDispatchTrip(trips)
+	service := domain.NewDriverService(drivers, assignments, trips, nil, nil)
+	ctx := context.Background()
+
+	// A rejected transition leaves no assignment behind.
+	_, err := service.UpdateTripStatus(ctx, trip.ID, "driver-b", domain.TripStatusArriving, "")
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+	require.Nil(t, trips.trips[trip.ID].DriverID)
+	assignment, err := assignments.GetByTripID(ctx, trip.ID)
+	require.NoError(t, err)
+	require.Nil(t, assignment)
+
+	_, err = service.AcceptTrip(ctx, trip.ID, "driver-a")
+	require.NoError(t, err)
+
+	// Another driver cannot take over the accepted trip.
+	_, err = service.UpdateTripStatus(ctx, trip.ID, "driver-b", domain.TripStatusArriving, "")
+	require.ErrorIs(t, err, domain.ErrTransitionNotPermitted)
+	require.Equal(t, "driver-a", *trips.trips[trip.ID].DriverID)
+	require.Equal(t, domain.TripStatusAccepted, trips.trips[trip.ID].Status)
+
+	updated, err := service.UpdateTripStatus(ctx, trip.ID, "driver-a", domain.TripStatusArriving, "")
+	require.NoError(t, err)
+	require.Equal(t, domain.TripStatusArriving, updated.Status)
+}
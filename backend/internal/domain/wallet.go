@@ -39,8 +39,10 @@ type WalletRepository interface {
 }
 
 // WalletOperations exposes the subset of wallet behaviours used by other services.
+// A fare of zero or less falls back to the configured fare for the service.
 type WalletOperations interface {
-	EnsureBalanceForTrip(ctx context.Context, userID, serviceID string) (int64, error)
-	DeductTripFare(ctx context.Context, userID, serviceID string) (*WalletSummary, int64, error)
+	EnsureBalanceForTrip(ctx context.Context, userID, serviceID string, fare int64) (int64, error)
+	DeductTripFare(ctx context.Context, userID, serviceID string, fare int64) (*WalletSummary, int64, error)
 	RewardTripCompletion(ctx context.Context, userID string) (*WalletSummary, int64, error)
+	ChargeCancellationFee(ctx context.Context, userID string, fee int64) (*WalletSummary, error)
 }
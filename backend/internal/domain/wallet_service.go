@@ -120,11 +120,13 @@ func (s *WalletService) TopUp(ctx context.Context, userID string, amount int64)
 }
 
 // EnsureBalanceForTrip enforces riders keep sufficient funds before booking.
-func (s *WalletService) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string) (int64, error) {
+func (s *WalletService) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string, fare int64) (int64, error) {
 	if userID == "" {
 		return 0, errors.New("user id required")
 	}
-	fare := s.fareForService(serviceID)
+	if fare <= 0 {
+		fare = s.fareForService(serviceID)
+	}
 	summary, err := s.repo.Get(ctx, userID)
 	if err != nil {
 		return 0, err
@@ -136,11 +138,13 @@ func (s *WalletService) EnsureBalanceForTrip(ctx context.Context, userID, servic
 }
 
 // DeductTripFare debits the rider wallet after trip completion.
-func (s *WalletService) DeductTripFare(ctx context.Context, userID, serviceID string) (*WalletSummary, int64, error) {
+func (s *WalletService) DeductTripFare(ctx context.Context, userID, serviceID string, fare int64) (*WalletSummary, int64, error) {
 	if userID == "" {
 		return nil, 0, errors.New("user id required")
 	}
-	fare := s.fareForService(serviceID)
+	if fare <= 0 {
+		fare = s.fareForService(serviceID)
+	}
 	summary, err := s.ApplyTransaction(ctx, &WalletTransaction{
 		UserID: userID,
 		Amount: fare,
@@ -149,6 +153,18 @@ func (s *WalletService) DeductTripFare(ctx context.Context, userID, serviceID st
 	return summary, fare, err
 }
 
+// ChargeCancellationFee debits the fee for a late rider cancellation.
+func (s *WalletService) ChargeCancellationFee(ctx context.Context, userID string, fee int64) (*WalletSummary, error) {
+	if userID == "" {
+		return nil, errors.New("user id required")
+	}
+	return s.ApplyTransaction(ctx, &WalletTransaction{
+		UserID: userID,
+		Amount: fee,
+		Type:   WalletTransactionTypeDeduction,
+	})
+}
+
 // RewardTripCompletion grants loyalty points/promotions after a trip.
 func (s *WalletService) RewardTripCompletion(ctx context.Context, userID string) (*WalletSummary, int64, error) {
 	if userID == "" {
@@ -119,17 +119,17 @@ func TestWalletServiceTripLifecycle(t *testing.T) {
 	service := domain.NewWalletService(repo)
 	ctx := context.Background()
 
-	_, err := service.EnsureBalanceForTrip(ctx, "rider-1", "uit-bike")
+	_, err := service.EnsureBalanceForTrip(ctx, "rider-1", "uit-bike", 0)
 	require.ErrorIs(t, err, domain.ErrWalletInsufficientFunds)
 
 	_, err = service.TopUp(ctx, "rider-1", 60000)
 	require.NoError(t, err)
 
-	fare, err := service.EnsureBalanceForTrip(ctx, "rider-1", "uit-bike")
+	fare, err := service.EnsureBalanceForTrip(ctx, "rider-1", "uit-bike", 0)
 	require.NoError(t, err)
 	require.Greater(t, fare, int64(0))
 
-	afterDeduct, deducted, err := service.DeductTripFare(ctx, "rider-1", "uit-bike")
+	afterDeduct, deducted, err := service.DeductTripFare(ctx, "rider-1", "uit-bike", 0)
 	require.NoError(t, err)
 	require.Equal(t, deducted, fare)
 	require.Equal(t, int64(60000)-fare, afterDeduct.Balance)
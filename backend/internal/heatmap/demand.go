@@ -0,0 +1,106 @@
+package heatmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultDemandKey = "heatmap:demand"
+
+// Request is one trip request origin in the demand log.
+type Request struct {
+	TripID    string
+	ServiceID string
+	Lat       float64
+	Lng       float64
+	At        time.Time
+}
+
+// DemandLog keeps recent trip request origins in a Redis sorted set scored by
+// request time, so every driver-service replica feeds and reads the same log.
+type DemandLog struct {
+	client    *redis.Client
+	key       string
+	retention time.Duration
+}
+
+// NewDemandLog connects to Redis. Requests older than retention are pruned as
+// new ones arrive.
+func NewDemandLog(addr, password string, db int, retention time.Duration) (*DemandLog, error) {
+	if addr == "" {
+		return nil, errors.New("redis address required")
+	}
+	if retention <= 0 {
+		retention = time.Hour
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping redis: %w", err)
+	}
+	return &DemandLog{client: client, key: defaultDemandKey, retention: retention}, nil
+}
+
+// Close releases the Redis connection.
+func (d *DemandLog) Close() error {
+	return d.client.Close()
+}
+
+// Retention reports how far back the log reaches.
+func (d *DemandLog) Retention() time.Duration {
+	return d.retention
+}
+
+// Add records a request. A redelivered request replaces its earlier entry.
+func (d *DemandLog) Add(ctx context.Context, req Request) error {
+	member := fmt.Sprintf("%s,%s,%s,%s",
+		strconv.FormatFloat(req.Lat, 'f', -1, 64), strconv.FormatFloat(req.Lng, 'f', -1, 64), req.ServiceID, req.TripID)
+	cutoff := req.At.Add(-d.retention)
+	pipe := d.client.Pipeline()
+	pipe.ZAdd(ctx, d.key, redis.Z{Score: float64(req.At.UnixMilli()), Member: member})
+	pipe.ZRemRangeByScore(ctx, d.key, "-inf", "("+strconv.FormatInt(cutoff.UnixMilli(), 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis record demand: %w", err)
+	}
+	return nil
+}
+
+// Since lists the requests made at or after since.
+func (d *DemandLog) Since(ctx context.Context, since time.Time) ([]Request, error) {
+	raw, err := d.client.ZRangeByScoreWithScores(ctx, d.key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis read demand: %w", err)
+	}
+	requests := make([]Request, 0, len(raw))
+	for _, item := range raw {
+		member, _ := item.Member.(string)
+		parts := strings.SplitN(member, ",", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(parts[0], 64)
+		lng, lngErr := strconv.ParseFloat(parts[1], 64)
+		if latErr != nil || lngErr != nil {
+			continue
+		}
+		requests = append(requests, Request{
+			TripID:    parts[3],
+			ServiceID: parts[2],
+			Lat:       lat,
+			Lng:       lng,
+			At:        time.UnixMilli(int64(item.Score)).UTC(),
+		})
+	}
+	return requests, nil
+}
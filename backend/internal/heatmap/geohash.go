@@ -0,0 +1,79 @@
+package heatmap
+
+import (
+	"errors"
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxResolution is the finest geohash precision a heatmap may use, cells of
+// roughly 5 meters.
+const MaxResolution = 9
+
+// Bounds is the latitude/longitude box a geohash cell covers.
+type Bounds struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// Encode returns the geohash cell of the given precision containing the point.
+func Encode(lat, lng float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+	var hash strings.Builder
+	hash.Grow(precision)
+	bits, value, even := 0, 0, true
+	for hash.Len() < precision {
+		coord, span := lng, &lngRange
+		if !even {
+			coord, span = lat, &latRange
+		}
+		mid := (span[0] + span[1]) / 2
+		value <<= 1
+		if coord >= mid {
+			value |= 1
+			span[0] = mid
+		} else {
+			span[1] = mid
+		}
+		even = !even
+		if bits++; bits == 5 {
+			hash.WriteByte(geohashAlphabet[value])
+			bits, value = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// Decode returns the box covered by a geohash cell.
+func Decode(hash string) (Bounds, error) {
+	if hash == "" {
+		return Bounds{}, errors.New("empty geohash")
+	}
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		value := strings.IndexByte(geohashAlphabet, hash[i])
+		if value < 0 {
+			return Bounds{}, errors.New("invalid geohash " + hash)
+		}
+		for bit := 4; bit >= 0; bit-- {
+			span := &lngRange
+			if !even {
+				span = &latRange
+			}
+			mid := (span[0] + span[1]) / 2
+			if value&(1<<bit) != 0 {
+				span[0] = mid
+			} else {
+				span[1] = mid
+			}
+			even = !even
+		}
+	}
+	return Bounds{MinLat: latRange[0], MinLng: lngRange[0], MaxLat: latRange[1], MaxLng: lngRange[1]}, nil
+}
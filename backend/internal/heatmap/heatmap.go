@@ -0,0 +1,130 @@
+// Package heatmap buckets online driver positions (supply) and trip request
+// origins (demand) into geohash cells so ops can see where riders wait.
+package heatmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/matching"
+)
+
+var (
+	ErrInvalidResolution = fmt.Errorf("resolution must be between 1 and %d", MaxResolution)
+	ErrInvalidWindow     = errors.New("window must be positive and within the demand retention")
+)
+
+// SupplySource lists the drivers currently available for dispatch.
+type SupplySource interface {
+	Positions(ctx context.Context) ([]*domain.DriverLocation, error)
+}
+
+// Cell is one geohash cell of a heatmap.
+type Cell struct {
+	Hash   string
+	Bounds Bounds
+	// Supply counts the drivers in the cell right now.
+	Supply int
+	// Demand counts the trips requested from the cell within the window.
+	Demand int
+}
+
+// Heatmap is a supply and demand snapshot.
+type Heatmap struct {
+	Resolution  int
+	Window      time.Duration
+	GeneratedAt time.Time
+	// Cells with any supply or demand, busiest shortfall first.
+	Cells []Cell
+}
+
+// Service records trip requests and aggregates heatmaps.
+type Service struct {
+	supply SupplySource
+	demand *DemandLog
+	now    func() time.Time
+}
+
+// NewService combines a driver supply source with a demand log. Either may be
+// nil, in which case that side of the heatmap stays empty.
+func NewService(supply SupplySource, demand *DemandLog) *Service {
+	return &Service{supply: supply, demand: demand, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// RecordRequest adds a queued trip's origin to the demand log. Events without
+// an origin position are ignored.
+func (s *Service) RecordRequest(ctx context.Context, event *matching.TripEvent) error {
+	if s.demand == nil || event == nil || event.OriginLat == nil || event.OriginLng == nil {
+		return nil
+	}
+	at := event.Requested
+	if at.IsZero() {
+		at = s.now()
+	}
+	return s.demand.Add(ctx, Request{
+		TripID:    event.TripID,
+		ServiceID: strings.ToLower(strings.TrimSpace(event.ServiceID)),
+		Lat:       *event.OriginLat,
+		Lng:       *event.OriginLng,
+		At:        at,
+	})
+}
+
+// Snapshot buckets current driver positions and the trips requested within
+// window into cells of the given geohash precision.
+func (s *Service) Snapshot(ctx context.Context, resolution int, window time.Duration) (*Heatmap, error) {
+	if resolution < 1 || resolution > MaxResolution {
+		return nil, ErrInvalidResolution
+	}
+	if window <= 0 || (s.demand != nil && window > s.demand.Retention()) {
+		return nil, ErrInvalidWindow
+	}
+	now := s.now()
+	cells := map[string]*Cell{}
+	cell := func(lat, lng float64) *Cell {
+		hash := Encode(lat, lng, resolution)
+		if existing, ok := cells[hash]; ok {
+			return existing
+		}
+		bounds, _ := Decode(hash)
+		created := &Cell{Hash: hash, Bounds: bounds}
+		cells[hash] = created
+		return created
+	}
+
+	if s.supply != nil {
+		drivers, err := s.supply.Positions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, loc := range drivers {
+			cell(loc.Latitude, loc.Longitude).Supply++
+		}
+	}
+	if s.demand != nil {
+		requests, err := s.demand.Since(ctx, now.Add(-window))
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range requests {
+			cell(req.Lat, req.Lng).Demand++
+		}
+	}
+
+	heatmap := &Heatmap{Resolution: resolution, Window: window, GeneratedAt: now, Cells: make([]Cell, 0, len(cells))}
+	for _, c := range cells {
+		heatmap.Cells = append(heatmap.Cells, *c)
+	}
+	slices.SortFunc(heatmap.Cells, func(a, b Cell) int {
+		if gap := (b.Demand - b.Supply) - (a.Demand - a.Supply); gap != 0 {
+			return gap
+		}
+		return strings.Compare(a.Hash, b.Hash)
+	})
+	return heatmap, nil
+}
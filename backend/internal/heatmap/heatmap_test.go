@@ -0,0 +1,88 @@
+package heatmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/matching"
+)
+
+type fakeSupply []*domain.DriverLocation
+
+func (f fakeSupply) Positions(ctx context.Context) ([]*domain.DriverLocation, error) {
+	return f, nil
+}
+
+func TestGeohashRoundTrip(t *testing.T) {
+	require.Equal(t, "u4pruydqqvj", Encode(57.64911, 10.40744, 11))
+	require.Equal(t, "w3gv", Encode(10.7769, 106.7009, 4))
+
+	bounds, err := Decode("w3gvk1")
+	require.NoError(t, err)
+	require.Less(t, bounds.MinLat, bounds.MaxLat)
+	require.Equal(t, "w3gvk1", Encode((bounds.MinLat+bounds.MaxLat)/2, (bounds.MinLng+bounds.MaxLng)/2, 6))
+
+	_, err = Decode("w3ga")
+	require.Error(t, err, "a is not a geohash digit")
+}
+
+func TestSnapshotBucketsSupplyAndDemandOverWindow(t *testing.T) {
+	server := miniredis.RunT(t)
+	demand, err := NewDemandLog(server.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	t.Cleanup(func() { demand.Close() })
+
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	// Two drivers downtown and one across the river.
+	supply := fakeSupply{
+		{DriverID: "d1", Latitude: 10.7769, Longitude: 106.7009},
+		{DriverID: "d2", Latitude: 10.7770, Longitude: 106.7010},
+		{DriverID: "d3", Latitude: 10.7870, Longitude: 106.7500},
+	}
+	service := NewService(supply, demand)
+	service.now = func() time.Time { return now }
+
+	request := func(id string, lat, lng float64, ago time.Duration) {
+		event := &matching.TripEvent{TripID: id, OriginLat: &lat, OriginLng: &lng, Requested: now.Add(-ago)}
+		require.NoError(t, service.RecordRequest(context.Background(), event))
+	}
+	for i, id := range []string{"t1", "t2", "t3"} {
+		request(id, 10.7870, 106.7500, time.Duration(i)*time.Minute)
+	}
+	request("t4", 10.7769, 106.7009, 2*time.Minute)
+	request("t5", 10.7769, 106.7009, 40*time.Minute)
+	request("t6", 10.7769, 106.7009, 2*time.Hour)
+	// Redelivered events are counted once.
+	request("t1", 10.7870, 106.7500, 0)
+	// Events without an origin are skipped.
+	require.NoError(t, service.RecordRequest(context.Background(), &matching.TripEvent{TripID: "t7", Requested: now}))
+
+	heatmap, err := service.Snapshot(context.Background(), 6, 15*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, heatmap.Cells, 2)
+	hotspot := heatmap.Cells[0]
+	require.Equal(t, Encode(10.7870, 106.7500, 6), hotspot.Hash)
+	require.Equal(t, 1, hotspot.Supply)
+	require.Equal(t, 3, hotspot.Demand)
+	require.Equal(t, 2, heatmap.Cells[1].Supply)
+	require.Equal(t, 1, heatmap.Cells[1].Demand)
+
+	heatmap, err = service.Snapshot(context.Background(), 6, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, heatmap.Cells[1].Demand, "the request two hours ago is outside the window")
+
+	heatmap, err = service.Snapshot(context.Background(), 3, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, heatmap.Cells, 1)
+	require.Equal(t, 3, heatmap.Cells[0].Supply)
+
+	_, err = service.Snapshot(context.Background(), 0, time.Hour)
+	require.ErrorIs(t, err, ErrInvalidResolution)
+	_, err = service.Snapshot(context.Background(), 6, 2*time.Hour)
+	require.ErrorIs(t, err, ErrInvalidWindow)
+}
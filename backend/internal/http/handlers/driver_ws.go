@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/realtime"
+)
+
+type driverInboundMessage struct {
+	Type     string   `json:"type"`
+	TripID   string   `json:"tripId,omitempty"`
+	Lat      float64  `json:"lat,omitempty"`
+	Lng      float64  `json:"lng,omitempty"`
+	Accuracy *float64 `json:"accuracy,omitempty"`
+	Heading  *float64 `json:"heading,omitempty"`
+	Speed    *float64 `json:"speed,omitempty"`
+}
+
+// driverOutboundMessage is pushed to a driver session. An "offer" carries the
+// assignment status; pending offers include the trip and the seconds left to
+// answer. "accepted", "declined" and "error" answer the driver's commands.
+type driverOutboundMessage struct {
+	Type             string                      `json:"type"`
+	TripID           string                      `json:"tripId,omitempty"`
+	Status           domain.TripAssignmentStatus `json:"status,omitempty"`
+	Trip             *tripResponse               `json:"trip,omitempty"`
+	ExpiresAt        *time.Time                  `json:"expiresAt,omitempty"`
+	ExpiresInSeconds *int                        `json:"expiresInSeconds,omitempty"`
+	Error            string                      `json:"error,omitempty"`
+	Timestamp        time.Time                   `json:"timestamp"`
+}
+
+// DriverSessions serves the driver-wide socket and delivers dispatch offers to
+// it. Offers go through the backplane keyed by driver ID, so the replica
+// running dispatch reaches a driver connected to any other replica.
+type DriverSessions struct {
+	backplane realtime.Backplane
+}
+
+var _ domain.DriverOfferPublisher = (*DriverSessions)(nil)
+
+// NewDriverSessions creates driver sessions fanned out through backplane, or
+// kept in process when backplane is nil.
+func NewDriverSessions(backplane realtime.Backplane) *DriverSessions {
+	if backplane == nil {
+		backplane = realtime.NewMemoryBackplane(realtime.DefaultHistorySize)
+	}
+	return &DriverSessions{backplane: backplane}
+}
+
+// RegisterDriverSessionRoutes wires the driver session socket under /v1.
+func RegisterDriverSessionRoutes(router gin.IRouter, service *domain.DriverService, sessions *DriverSessions) {
+	if service == nil || sessions == nil {
+		return
+	}
+	router.GET("/v1/drivers/me/ws", sessions.HandleWebsocket(service))
+}
+
+// PublishDriverOffer sends offer to every session of the offered driver.
+func (s *DriverSessions) PublishDriverOffer(ctx context.Context, offer domain.DriverOffer) error {
+	payload, err := json.Marshal(offerMessage(offer, time.Now().UTC()))
+	if err != nil {
+		return err
+	}
+	seq, err := s.backplane.NextSeq(ctx, offer.DriverID)
+	if err != nil {
+		return err
+	}
+	return s.backplane.Publish(ctx, offer.DriverID, realtime.Message{Seq: seq, Payload: payload})
+}
+
+func offerMessage(offer domain.DriverOffer, now time.Time) driverOutboundMessage {
+	msg := driverOutboundMessage{
+		Type:      "offer",
+		TripID:    offer.TripID,
+		Status:    offer.Status,
+		ExpiresAt: offer.ExpiresAt,
+		Timestamp: now,
+	}
+	if offer.Trip != nil {
+		trip := toTripResponse(offer.Trip, nil)
+		msg.Trip = &trip
+	}
+	if offer.ExpiresAt != nil {
+		remaining := int(math.Ceil(offer.ExpiresAt.Sub(now).Seconds()))
+		msg.ExpiresInSeconds = &remaining
+	}
+	return msg
+}
+
+// HandleWebsocket upgrades an authenticated driver to a session socket. The
+// driver receives offers as they are made and answers them with "accept" or
+// "decline"; "location" pings are recorded while the driver is online.
+// SECURITY: Only accepts authentication from JWT token, not query parameters.
+func (s *DriverSessions) HandleWebsocket(service *domain.DriverService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userVal, _ := c.Get("userID")
+		userID, _ := userVal.(string)
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		driver, err := service.Me(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(driverErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("upgrade driver websocket: %v", err)
+			return
+		}
+
+		session := &driverSession{
+			conn:     conn,
+			driverID: driver.ID,
+			send:     make(chan []byte, 16),
+			done:     make(chan struct{}),
+			ctx:      c.Request.Context(),
+		}
+		subscription, err := s.backplane.Subscribe(context.Background(), driver.ID, func(msg realtime.Message) {
+			session.enqueue(msg.Payload)
+		})
+		if err != nil {
+			log.Printf("subscribe driver session %s: %v", driver.ID, err)
+			conn.Close()
+			return
+		}
+		defer subscription.Close()
+
+		// Subscribe first so an offer made while looking up the pending one is
+		// not lost; the driver may then see the same offer twice.
+		if offer, err := service.PendingOffer(c.Request.Context(), driver.ID); err != nil {
+			log.Printf("load pending offer for driver %s: %v", driver.ID, err)
+		} else if offer != nil {
+			session.reply(offerMessage(*offer, time.Now().UTC()))
+		}
+
+		go session.writePump()
+		session.readPump(service)
+	}
+}
+
+type driverSession struct {
+	conn     *websocket.Conn
+	driverID string
+	send     chan []byte
+	done     chan struct{}
+	ctx      context.Context
+}
+
+func (s *driverSession) enqueue(payload []byte) {
+	select {
+	case s.send <- payload:
+	case <-s.done:
+	default:
+		log.Printf("driver session %s is not keeping up; dropping message", s.driverID)
+	}
+}
+
+func (s *driverSession) reply(msg driverOutboundMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("marshal driver ws message: %v", err)
+		return
+	}
+	s.enqueue(payload)
+}
+
+func (s *driverSession) readPump(service *domain.DriverService) {
+	defer func() {
+		close(s.done)
+		s.conn.Close()
+	}()
+	s.conn.SetReadLimit(maxMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetPongHandler(func(string) error {
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("driver ws unexpected close: %v", err)
+			}
+			return
+		}
+		var inbound driverInboundMessage
+		if err := json.Unmarshal(message, &inbound); err != nil {
+			log.Printf("driver ws parse message: %v", err)
+			continue
+		}
+		s.handle(service, inbound)
+	}
+}
+
+func (s *driverSession) handle(service *domain.DriverService, inbound driverInboundMessage) {
+	now := time.Now().UTC()
+	switch inbound.Type {
+	case "location":
+		location := &domain.DriverLocation{
+			DriverID:   s.driverID,
+			Latitude:   inbound.Lat,
+			Longitude:  inbound.Lng,
+			Accuracy:   inbound.Accuracy,
+			Heading:    inbound.Heading,
+			Speed:      inbound.Speed,
+			RecordedAt: now,
+		}
+		if err := service.RecordOnlineLocation(s.ctx, s.driverID, location); err != nil {
+			s.reply(driverOutboundMessage{Type: "error", Error: err.Error(), Timestamp: now})
+		}
+	case "accept", "decline":
+		if inbound.TripID == "" {
+			s.reply(driverOutboundMessage{Type: "error", Error: "tripId required", Timestamp: now})
+			return
+		}
+		s.answerOffer(service, inbound.Type, inbound.TripID, now)
+	}
+}
+
+func (s *driverSession) answerOffer(service *domain.DriverService, answer, tripID string, now time.Time) {
+	switch answer {
+	case "accept":
+		if _, err := service.AcceptTrip(s.ctx, tripID, s.driverID); err != nil {
+			s.reply(driverOutboundMessage{Type: "error", TripID: tripID, Error: err.Error(), Timestamp: now})
+			return
+		}
+		s.reply(driverOutboundMessage{Type: "accepted", TripID: tripID, Timestamp: now})
+	case "decline":
+		if _, err := service.DeclineTrip(s.ctx, tripID, s.driverID); err != nil {
+			s.reply(driverOutboundMessage{Type: "error", TripID: tripID, Error: err.Error(), Timestamp: now})
+			return
+		}
+		s.reply(driverOutboundMessage{Type: "declined", TripID: tripID, Timestamp: now})
+	}
+}
+
+func (s *driverSession) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		s.conn.Close()
+	}()
+	for {
+		select {
+		case <-s.done:
+			return
+		case payload := <-s.send:
+			s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/realtime"
+)
+
+func TestDriverSessionsDeliverOffersToTheDriver(t *testing.T) {
+	backplane := realtime.NewMemoryBackplane(realtime.DefaultHistorySize)
+	sessions := NewDriverSessions(backplane)
+	session := &driverSession{driverID: "driver-1", send: make(chan []byte, 1), done: make(chan struct{})}
+	_, err := backplane.Subscribe(context.Background(), "driver-1", func(msg realtime.Message) {
+		session.enqueue(msg.Payload)
+	})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().UTC().Add(20 * time.Second)
+	require.NoError(t, sessions.PublishDriverOffer(context.Background(), domain.DriverOffer{
+		TripID:    "trip-1",
+		DriverID:  "driver-1",
+		Status:    domain.TripAssignmentPending,
+		Trip:      &domain.Trip{ID: "trip-1", OriginText: "UIT", DestText: "KTX"},
+		ExpiresAt: &expiresAt,
+	}))
+	require.NoError(t, sessions.PublishDriverOffer(context.Background(), domain.DriverOffer{TripID: "trip-2", DriverID: "driver-2"}))
+
+	var msg driverOutboundMessage
+	require.NoError(t, json.Unmarshal(<-session.send, &msg))
+	require.Equal(t, "offer", msg.Type)
+	require.Equal(t, domain.TripAssignmentPending, msg.Status)
+	require.Equal(t, "UIT", msg.Trip.OriginText)
+	require.Equal(t, 20, *msg.ExpiresInSeconds)
+	require.Empty(t, session.send)
+}
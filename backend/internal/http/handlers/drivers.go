@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -32,12 +33,27 @@ func RegisterDriverRoutes(router *gin.Engine, service *domain.DriverService) {
 	}
 }
 
+// RegisterDriverAdminRoutes registers driver management endpoints on an admin group.
+func RegisterDriverAdminRoutes(router gin.IRoutes, service *domain.DriverService) {
+	if router == nil || service == nil {
+		return
+	}
+	handler := &DriverHandler{service: service}
+	router.PUT("/drivers/:id/services", handler.setEligibleServices)
+}
+
+type driverServicesRequest struct {
+	Services []string `json:"services"`
+}
+
 type driverVehiclePayload struct {
 	Make        string `json:"make" binding:"required"`
 	Model       string `json:"model" binding:"required"`
 	Color       string `json:"color" binding:"required"`
 	Year        int    `json:"year"`
 	PlateNumber string `json:"plateNumber" binding:"required"`
+	Category    string `json:"category"`
+	Capacity    int    `json:"capacity"`
 }
 
 type createDriverRequest struct {
@@ -61,18 +77,19 @@ type updateDriverStatusRequest struct {
 }
 
 type driverResponse struct {
-	ID            string                  `json:"id"`
-	UserID        string                  `json:"userId"`
-	FullName      string                  `json:"fullName"`
-	Phone         string                  `json:"phone"`
-	LicenseNumber string                  `json:"licenseNumber"`
-	AvatarURL     *string                 `json:"avatarUrl,omitempty"`
-	Rating        float64                 `json:"rating"`
-	Vehicle       *vehicleResponse        `json:"vehicle,omitempty"`
-	Status        *driverStatusResponse   `json:"status,omitempty"`
-	Location      *driverLocationResponse `json:"location,omitempty"`
-	CreatedAt     string                  `json:"createdAt"`
-	UpdatedAt     string                  `json:"updatedAt"`
+	ID               string                  `json:"id"`
+	UserID           string                  `json:"userId"`
+	FullName         string                  `json:"fullName"`
+	Phone            string                  `json:"phone"`
+	LicenseNumber    string                  `json:"licenseNumber"`
+	AvatarURL        *string                 `json:"avatarUrl,omitempty"`
+	Rating           float64                 `json:"rating"`
+	Vehicle          *vehicleResponse        `json:"vehicle,omitempty"`
+	EligibleServices []string                `json:"eligibleServices,omitempty"`
+	Status           *driverStatusResponse   `json:"status,omitempty"`
+	Location         *driverLocationResponse `json:"location,omitempty"`
+	CreatedAt        string                  `json:"createdAt"`
+	UpdatedAt        string                  `json:"updatedAt"`
 }
 
 type vehicleResponse struct {
@@ -81,6 +98,8 @@ type vehicleResponse struct {
 	Color       string `json:"color"`
 	Year        int    `json:"year"`
 	PlateNumber string `json:"plateNumber"`
+	Category    string `json:"category,omitempty"`
+	Capacity    int    `json:"capacity,omitempty"`
 }
 
 type driverStatusResponse struct {
@@ -121,9 +140,11 @@ func (h *DriverHandler) register(c *gin.Context) {
 	driver, err := h.service.Register(c.Request.Context(), userID, input)
 	if err != nil {
 		status := http.StatusInternalServerError
-		switch err {
-		case domain.ErrDriverAlreadyExists:
+		switch {
+		case errors.Is(err, domain.ErrDriverAlreadyExists):
 			status = http.StatusConflict
+		case errors.Is(err, domain.ErrInvalidVehicle):
+			status = http.StatusBadRequest
 		default:
 			if strings.Contains(err.Error(), "full name required") {
 				status = http.StatusBadRequest
@@ -179,6 +200,8 @@ func (h *DriverHandler) updateProfile(c *gin.Context) {
 		status := http.StatusInternalServerError
 		if err == domain.ErrDriverNotFound {
 			status = http.StatusNotFound
+		} else if errors.Is(err, domain.ErrInvalidVehicle) {
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
@@ -277,6 +300,29 @@ func (h *DriverHandler) searchNearby(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// setEligibleServices replaces the services the driver may be dispatched. An
+// empty list returns the driver to vehicle-based eligibility.
+func (h *DriverHandler) setEligibleServices(c *gin.Context) {
+	var req driverServicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	driver, err := h.service.SetEligibleServices(c.Request.Context(), c.Param("id"), req.Services)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownService):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrDriverNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update driver services"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, toDriverResponse(driver))
+}
+
 func payloadToVehicle(payload *driverVehiclePayload) *domain.Vehicle {
 	if payload == nil {
 		return nil
@@ -287,20 +333,23 @@ func payloadToVehicle(payload *driverVehiclePayload) *domain.Vehicle {
 		Color:       payload.Color,
 		Year:        payload.Year,
 		PlateNumber: payload.PlateNumber,
+		Category:    domain.VehicleCategory(payload.Category),
+		Capacity:    payload.Capacity,
 	}
 }
 
 func toDriverResponse(driver *domain.Driver) driverResponse {
 	resp := driverResponse{
-		ID:            driver.ID,
-		UserID:        driver.UserID,
-		FullName:      driver.FullName,
-		Phone:         driver.Phone,
-		LicenseNumber: driver.LicenseNumber,
-		AvatarURL:     driver.AvatarURL,
-		Rating:        driver.Rating,
-		CreatedAt:     driver.CreatedAt.UTC().Format(time.RFC3339),
-		UpdatedAt:     driver.UpdatedAt.UTC().Format(time.RFC3339),
+		ID:               driver.ID,
+		UserID:           driver.UserID,
+		FullName:         driver.FullName,
+		Phone:            driver.Phone,
+		LicenseNumber:    driver.LicenseNumber,
+		AvatarURL:        driver.AvatarURL,
+		Rating:           driver.Rating,
+		EligibleServices: driver.EligibleServices,
+		CreatedAt:        driver.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:        driver.UpdatedAt.UTC().Format(time.RFC3339),
 	}
 	if driver.Vehicle != nil {
 		resp.Vehicle = &vehicleResponse{
@@ -309,6 +358,8 @@ func toDriverResponse(driver *domain.Driver) driverResponse {
 			Color:       driver.Vehicle.Color,
 			Year:        driver.Vehicle.Year,
 			PlateNumber: driver.Vehicle.PlateNumber,
+			Category:    string(driver.Vehicle.Category),
+			Capacity:    driver.Vehicle.Capacity,
 		}
 	}
 	if driver.Status != nil {
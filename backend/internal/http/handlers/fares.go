@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/routing"
+)
+
+type fareEstimateRequest struct {
+	ServiceID   string  `json:"serviceId" binding:"required"`
+	Origin      *latLng `json:"origin" binding:"required"`
+	Destination *latLng `json:"destination" binding:"required"`
+}
+
+// RegisterFareRoutes registers fare estimation endpoints under /v1.
+func RegisterFareRoutes(router gin.IRouter, service *domain.TripService) {
+	if router == nil || service == nil {
+		return
+	}
+	handler := &fareHandler{service: service}
+	router.POST("/v1/fares/estimate", handler.estimate)
+}
+
+type fareHandler struct {
+	service *domain.TripService
+}
+
+func (h *fareHandler) estimate(c *gin.Context) {
+	if userIDFromContext(c) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errAuthRequired})
+		return
+	}
+	var req fareEstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validLatLng(req.Origin.Lat, req.Origin.Lng) || !validLatLng(req.Destination.Lat, req.Destination.Lng) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat/lng must be valid coordinates"})
+		return
+	}
+
+	quote, err := h.service.EstimateFare(c.Request.Context(), domain.FareRequest{
+		RiderID:   userIDFromContext(c),
+		ServiceID: req.ServiceID,
+		OriginLat: req.Origin.Lat,
+		OriginLng: req.Origin.Lng,
+		DestLat:   req.Destination.Lat,
+		DestLng:   req.Destination.Lng,
+	})
+	if err != nil {
+		if writeServiceAreaError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, domain.ErrFareRuleNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown service"})
+		case errors.Is(err, domain.ErrFareEstimationUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, routing.ErrRouteNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "no route found"})
+		default:
+			log.Printf("fare estimate failed: %v", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "routing service unavailable"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/heatmap"
+)
+
+const (
+	defaultHeatmapResolution = 6
+	defaultHeatmapWindow     = 15 * time.Minute
+)
+
+// HeatmapSource aggregates driver supply and trip demand into cells.
+type HeatmapSource interface {
+	Snapshot(ctx context.Context, resolution int, window time.Duration) (*heatmap.Heatmap, error)
+}
+
+// RegisterHeatmapRoutes registers GET /heatmap on an admin group.
+func RegisterHeatmapRoutes(router gin.IRoutes, source HeatmapSource) {
+	if router == nil || source == nil {
+		return
+	}
+	router.GET("/heatmap", func(c *gin.Context) {
+		resolution := defaultHeatmapResolution
+		if raw := c.Query("resolution"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": heatmap.ErrInvalidResolution.Error()})
+				return
+			}
+			resolution = parsed
+		}
+		window := defaultHeatmapWindow
+		if raw := c.Query("window"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration such as 15m"})
+				return
+			}
+			window = parsed
+		}
+
+		snapshot, err := source.Snapshot(c.Request.Context(), resolution, window)
+		if err != nil {
+			if errors.Is(err, heatmap.ErrInvalidResolution) || errors.Is(err, heatmap.ErrInvalidWindow) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build heatmap"})
+			return
+		}
+		c.JSON(http.StatusOK, buildHeatmapGeoJSON(snapshot))
+	})
+}
+
+// buildHeatmapGeoJSON emits each cell as a Polygon with its supply and demand
+// counts.
+func buildHeatmapGeoJSON(snapshot *heatmap.Heatmap) gin.H {
+	features := make([]geoJSONFeature, 0, len(snapshot.Cells))
+	for _, cell := range snapshot.Cells {
+		b := cell.Bounds
+		ring := [][]float64{
+			{b.MinLng, b.MinLat}, {b.MaxLng, b.MinLat}, {b.MaxLng, b.MaxLat}, {b.MinLng, b.MaxLat}, {b.MinLng, b.MinLat},
+		}
+		features = append(features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONGeometry{Type: "Polygon", Coordinates: [][][]float64{ring}},
+			Properties: map[string]any{
+				"cell":   cell.Hash,
+				"supply": cell.Supply,
+				"demand": cell.Demand,
+			},
+		})
+	}
+	return gin.H{
+		"type":        "FeatureCollection",
+		"features":    features,
+		"resolution":  snapshot.Resolution,
+		"window":      snapshot.Window.String(),
+		"generatedAt": snapshot.GeneratedAt,
+	}
+}
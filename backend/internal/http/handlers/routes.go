@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"math"
 	"net/http"
@@ -29,6 +30,19 @@ type routeResponse struct {
 	Steps       []routeStep    `json:"steps"`
 }
 
+// maxMatrixPoints caps each side of a matrix request.
+const maxMatrixPoints = 25
+
+type matrixRequest struct {
+	Sources      []latLng `json:"sources" binding:"required,min=1,dive"`
+	Destinations []latLng `json:"destinations" binding:"required,min=1,dive"`
+}
+
+type matrixResponse struct {
+	Distances [][]*float64 `json:"distances"`
+	Durations [][]*float64 `json:"durations"`
+}
+
 type routeStep struct {
 	Name        string    `json:"name"`
 	Instruction string    `json:"instruction"`
@@ -37,17 +51,23 @@ type routeStep struct {
 	Duration    float64   `json:"duration"`
 }
 
-// RegisterRouteRoutes registers /routes endpoint on the provided router.
+// RegisterRouteRoutes registers /routes endpoint on the provided router, and
+// /routes/matrix when the provider can answer matrix lookups.
 func RegisterRouteRoutes(router gin.IRouter, provider RoutingProvider) {
 	if router == nil || provider == nil {
 		return
 	}
 	handler := &routeHandler{provider: provider}
 	router.POST("/routes", handler.getRoute)
+	if matrix, ok := provider.(MatrixProvider); ok {
+		handler.matrix = matrix
+		router.POST("/routes/matrix", handler.getMatrix)
+	}
 }
 
 type routeHandler struct {
 	provider RoutingProvider
+	matrix   MatrixProvider
 }
 
 func (h *routeHandler) getRoute(c *gin.Context) {
@@ -90,6 +110,47 @@ func (h *routeHandler) getRoute(c *gin.Context) {
 	})
 }
 
+func (h *routeHandler) getMatrix(c *gin.Context) {
+	var req matrixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Sources) > maxMatrixPoints || len(req.Destinations) > maxMatrixPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d sources and %d destinations are allowed", maxMatrixPoints, maxMatrixPoints)})
+		return
+	}
+	sources, ok := toRoutingCoordinates(req.Sources)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat/lng must be valid coordinates"})
+		return
+	}
+	destinations, ok := toRoutingCoordinates(req.Destinations)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat/lng must be valid coordinates"})
+		return
+	}
+
+	matrix, err := h.matrix.Table(c.Request.Context(), sources, destinations)
+	if err != nil {
+		log.Printf("routing matrix lookup failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "routing service unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, matrixResponse{Distances: matrix.Distances, Durations: matrix.Durations})
+}
+
+func toRoutingCoordinates(points []latLng) ([]routing.Coordinate, bool) {
+	coordinates := make([]routing.Coordinate, 0, len(points))
+	for _, point := range points {
+		if !validLatLng(point.Lat, point.Lng) {
+			return nil, false
+		}
+		coordinates = append(coordinates, routing.Coordinate{Lat: point.Lat, Lng: point.Lng})
+	}
+	return coordinates, true
+}
+
 func validLatLng(lat, lng float64) bool {
 	return !math.IsNaN(lat) && !math.IsNaN(lng) &&
 		lat <= 90 && lat >= -90 &&
@@ -137,3 +198,15 @@ func normalizeLocation(loc []float64) []float64 {
 type RoutingProvider interface {
 	GetRoute(ctx context.Context, origin, destination routing.Coordinate) (*routing.Route, error)
 }
+
+// WaypointProvider is implemented by routing providers that can route through
+// several waypoints in order.
+type WaypointProvider interface {
+	GetRouteVia(ctx context.Context, waypoints []routing.Coordinate) (*routing.Route, error)
+}
+
+// MatrixProvider is implemented by routing providers that can look up drive
+// distances and durations between many points at once.
+type MatrixProvider interface {
+	Table(ctx context.Context, sources, destinations []routing.Coordinate) (*routing.Matrix, error)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/domain"
+)
+
+type serviceAreaRequest struct {
+	ServiceID    string               `json:"serviceId"`
+	Name         string               `json:"name" binding:"required"`
+	Kind         string               `json:"kind" binding:"required"`
+	Polygon      []domain.GeoPoint    `json:"polygon" binding:"required"`
+	Surcharge    int64                `json:"surcharge"`
+	PickupPoints []domain.PickupPoint `json:"pickupPoints"`
+	Active       *bool                `json:"active"`
+}
+
+func (r serviceAreaRequest) toArea(id string) *domain.ServiceArea {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return &domain.ServiceArea{
+		ID:           id,
+		ServiceID:    r.ServiceID,
+		Name:         r.Name,
+		Kind:         domain.ServiceAreaKind(strings.ToLower(strings.TrimSpace(r.Kind))),
+		Polygon:      r.Polygon,
+		Surcharge:    r.Surcharge,
+		PickupPoints: r.PickupPoints,
+		Active:       active,
+	}
+}
+
+// RegisterServiceAreaAdminRoutes registers service-area endpoints on an admin group.
+func RegisterServiceAreaAdminRoutes(router gin.IRoutes, areas *domain.ServiceAreaCatalog) {
+	if router == nil || areas == nil {
+		return
+	}
+	handler := &serviceAreaHandler{areas: areas}
+	router.GET("/service-areas", handler.list)
+	router.POST("/service-areas", handler.create)
+	router.PUT("/service-areas/:id", handler.update)
+	router.DELETE("/service-areas/:id", handler.delete)
+}
+
+type serviceAreaHandler struct {
+	areas *domain.ServiceAreaCatalog
+}
+
+func (h *serviceAreaHandler) list(c *gin.Context) {
+	areas, err := h.areas.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load service areas"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": areas})
+}
+
+func (h *serviceAreaHandler) create(c *gin.Context) {
+	var req serviceAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	area := req.toArea("")
+	if err := h.areas.Create(c.Request.Context(), area); err != nil {
+		writeServiceAreaAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, area)
+}
+
+func (h *serviceAreaHandler) update(c *gin.Context) {
+	var req serviceAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	area := req.toArea(c.Param("id"))
+	if err := h.areas.Update(c.Request.Context(), area); err != nil {
+		writeServiceAreaAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, area)
+}
+
+func (h *serviceAreaHandler) delete(c *gin.Context) {
+	if err := h.areas.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		writeServiceAreaAdminError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeServiceAreaAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidServiceArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrServiceAreaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save service area"})
+	}
+}
+
+// writeServiceAreaError answers requests rejected by the service areas with a
+// code clients can branch on. It reports whether err was such a rejection.
+func writeServiceAreaError(c *gin.Context, err error) bool {
+	var pickupPoint *domain.PickupPointError
+	switch {
+	case errors.As(err, &pickupPoint):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":        err.Error(),
+			"code":         "pickup_point_required",
+			"pickupPoints": pickupPoint.Points,
+		})
+	case errors.Is(err, domain.ErrPickupOutsideServiceArea):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "pickup_outside_service_area"})
+	case errors.Is(err, domain.ErrDropoffOutsideServiceArea):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "dropoff_outside_service_area"})
+	case errors.Is(err, domain.ErrServiceAreaCoordinatesRequired):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "coordinates_required"})
+	default:
+		return false
+	}
+	return true
+}
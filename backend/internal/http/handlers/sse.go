@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/realtime"
+)
+
+// sseKeepAlive is how often an idle event stream gets a comment line so
+// proxies do not time it out.
+const sseKeepAlive = 25 * time.Second
+
+// HandleStream serves the trip's hub messages as Server-Sent Events for
+// clients that cannot open a WebSocket. Each event carries the message
+// sequence as its id, so a reconnecting EventSource resumes through
+// Last-Event-ID the same way a WebSocket resumes with ?since.
+func (m *HubManager) HandleStream(service *domain.TripService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trip, userStr, roleStr, ok := m.authorizeTripStream(c, service)
+		if !ok {
+			return
+		}
+		raw := c.GetHeader("Last-Event-ID")
+		if raw == "" {
+			raw = c.Query("since")
+		}
+		since, err := parseSince(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be a sequence number"})
+			return
+		}
+
+		hub := m.acquire(trip.ID)
+		defer m.release(hub)
+		client := &Client{
+			hub:    hub,
+			send:   make(chan realtime.Message, 16),
+			role:   roleStr,
+			userID: userStr,
+			tripID: trip.ID,
+			ctx:    c.Request.Context(),
+		}
+		hub.register <- client
+		defer func() { hub.unregister <- client }()
+		client.resume(m.catchUp(c.Request.Context(), service, trip, roleStr, since))
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		for _, message := range client.backlog {
+			if !client.writeEvent(c.Writer, message) {
+				return
+			}
+		}
+		client.backlog = nil
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(sseKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case message, ok := <-client.send:
+				if !ok {
+					return
+				}
+				if client.seen(message) {
+					continue
+				}
+				if !client.writeEvent(c.Writer, message) {
+					return
+				}
+			case <-ticker.C:
+				if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeEvent writes message as one SSE event, using its sequence as the id.
+func (c *Client) writeEvent(w io.Writer, message realtime.Message) bool {
+	var err error
+	if message.Seq != 0 {
+		_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", message.Seq, message.Payload)
+	} else {
+		_, err = fmt.Fprintf(w, "data: %s\n\n", message.Payload)
+	}
+	return err == nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+)
+
+func TestHandleStreamResumesFromLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := domain.NewTripService(newFakeTripRepo(), nil, nil)
+	trip := &domain.Trip{RiderID: "rider-1", ServiceID: "uit-bike", OriginText: "UIT", DestText: "KTX"}
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	hubs := NewHubManager(service, nil)
+	for _, status := range []domain.TripStatus{domain.TripStatusAccepted, domain.TripStatusArriving} {
+		require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, status))
+		hubs.BroadcastStatus(trip.ID, status)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("userID", user)
+			c.Set("role", "rider")
+		}
+	})
+	router.GET("/v1/trips/:id/stream", hubs.HandleStream(service))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/trips/"+trip.ID+"/stream", nil)
+	req.Header.Set("X-Test-User", "rider-2")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// A cancelled request stops the stream once the backlog is written.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v1/trips/"+trip.ID+"/stream", nil).WithContext(ctx)
+	req.Header.Set("X-Test-User", "rider-1")
+	req.Header.Set("Last-Event-ID", "1")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	require.True(t, strings.HasPrefix(body, "id: 2\ndata: {"), body)
+	require.Contains(t, body, `"status":"arriving"`)
+	require.NotContains(t, body, `"status":"accepted"`)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/surge"
+)
+
+type surgeSettingsRequest struct {
+	Enabled       *bool    `json:"enabled"`
+	MaxMultiplier *float64 `json:"maxMultiplier"`
+}
+
+// RegisterSurgeAdminRoutes registers surge settings endpoints on an admin group.
+func RegisterSurgeAdminRoutes(router gin.IRoutes, settings *surge.Settings) {
+	if router == nil || settings == nil {
+		return
+	}
+	handler := &surgeAdminHandler{settings: settings}
+	router.GET("/surge", handler.list)
+	router.PUT("/surge/:serviceId", handler.update)
+}
+
+type surgeAdminHandler struct {
+	settings *surge.Settings
+}
+
+func (h *surgeAdminHandler) list(c *gin.Context) {
+	overrides, err := h.settings.Overrides(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load surge settings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"defaults": h.settings.Defaults(), "services": overrides})
+}
+
+// update changes the fields given for one service and keeps the others.
+func (h *surgeAdminHandler) update(c *gin.Context) {
+	serviceID := strings.TrimSpace(c.Param("serviceId"))
+	var req surgeSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current, err := h.settings.Get(c.Request.Context(), serviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load surge settings"})
+		return
+	}
+	if req.Enabled != nil {
+		current.Enabled = *req.Enabled
+	}
+	if req.MaxMultiplier != nil {
+		current.MaxMultiplier = *req.MaxMultiplier
+	}
+	if err := h.settings.Set(c.Request.Context(), serviceID, current); err != nil {
+		if errors.Is(err, surge.ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save surge settings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"serviceId": strings.ToLower(serviceID), "settings": current})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/domain"
+)
+
+type tripEventListResponse struct {
+	TripID string             `json:"tripId"`
+	Items  []domain.TripEvent `json:"items"`
+}
+
+// listTripEvents returns the trip timeline, filtered to the event types the
+// caller's role may read. ?types=status,fare narrows it further.
+func (h *TripHandler) listTripEvents(c *gin.Context) {
+	tripID := c.Param("id")
+	userID := userIDFromContext(c)
+	role := roleFromContext(c)
+
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errAuthRequired})
+		return
+	}
+
+	trip, err := h.service.Fetch(c.Request.Context(), tripID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errTripNotFound})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.canAccessTrip(trip, userID, role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	// The rider of the trip always gets the rider view, whatever their role claim.
+	viewer := role
+	if trip.RiderID == userID && role != "admin" {
+		viewer = "rider"
+	}
+
+	var requested []domain.TripEventType
+	for _, value := range strings.Split(c.Query("types"), ",") {
+		if trimmed := strings.ToLower(strings.TrimSpace(value)); trimmed != "" {
+			requested = append(requested, domain.TripEventType(trimmed))
+		}
+	}
+	resp := tripEventListResponse{TripID: tripID, Items: []domain.TripEvent{}}
+	filter := domain.TripEventFilter{
+		Types: domain.VisibleTripEventTypes(viewer, requested),
+		Limit: queryInt(c, "limit", 200, 1000),
+	}
+	if len(filter.Types) == 0 {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	if viewer == "driver" && trip.DriverID != nil {
+		filter.DriverID = *trip.DriverID
+	}
+
+	events, err := h.service.Events(c.Request.Context(), tripID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp.Items = append(resp.Items, events...)
+	c.JSON(http.StatusOK, resp)
+}
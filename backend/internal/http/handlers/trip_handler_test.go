@@ -82,6 +82,50 @@ func TestTripHandlerCreateTripInsufficientFunds(t *testing.T) {
 	require.Equal(t, http.StatusPaymentRequired, res.Code)
 }
 
+type staticQuoteStore struct {
+	quote *domain.LockedQuote
+}
+
+func (s *staticQuoteStore) SaveQuote(ctx context.Context, quote *domain.LockedQuote, ttl time.Duration) error {
+	s.quote = quote
+	return nil
+}
+
+func (s *staticQuoteStore) TakeQuote(ctx context.Context, id string) (*domain.LockedQuote, error) {
+	if s.quote == nil || s.quote.Quote.QuoteID != id {
+		return nil, domain.ErrQuoteExpired
+	}
+	quote := s.quote
+	s.quote = nil
+	return quote, nil
+}
+
+func TestTripHandlerCreateTripRejectsMismatchedQuote(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	quotes := &staticQuoteStore{quote: &domain.LockedQuote{
+		Quote:   domain.FareQuote{QuoteID: "quote-1", ServiceID: "uit-bike", Total: 40000},
+		RiderID: "someone-else",
+		Request: domain.FareRequest{OriginLat: 10.87, OriginLng: 106.80, DestLat: 10.88, DestLng: 106.78},
+	}}
+	service := domain.NewTripService(newFakeTripRepo(), nil, nil, domain.WithQuoteStore(quotes, time.Minute))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", "rider-1")
+	})
+	RegisterTripRoutes(router, service, nil, NewHubManager(service, nil), nil)
+
+	payload := `{"originText":"UIT","destText":"KTX","serviceId":"uit-bike","originLat":10.87,"originLng":106.80,"destLat":10.88,"destLng":106.78,"quoteId":"quote-1"}`
+	res := performTripRequest(t, router, http.MethodPost, "/v1/trips", payload, "rider-1")
+	require.Equal(t, http.StatusConflict, res.Code)
+	require.Contains(t, res.Body.String(), "does not match")
+
+	res = performTripRequest(t, router, http.MethodPost, "/v1/trips", `{"originText":"UIT","destText":"KTX","serviceId":"uit-bike","quoteId":"quote-gone"}`, "rider-1")
+	require.Equal(t, http.StatusConflict, res.Code)
+	require.Contains(t, res.Body.String(), "expired")
+}
+
 func TestTripHandlerGetAndUpdateTrip(t *testing.T) {
 	t.Parallel()
 	gin.SetMode(gin.TestMode)
@@ -195,12 +239,16 @@ func (r *fakeTripRepo) GetTrip(id string) (*domain.Trip, error) {
 	return nil, domain.ErrTripNotFound
 }
 
-func (r *fakeTripRepo) UpdateTripStatus(id string, status domain.TripStatus) error {
+func (r *fakeTripRepo) TransitionTrip(id string, change domain.TripStatusChange) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if trip, ok := r.trips[id]; ok {
-		trip.Status = status
+		if trip.Status != change.From {
+			return domain.ErrInvalidTransition
+		}
+		trip.Status = change.To
 		trip.UpdatedAt = time.Now().UTC()
+		change.Progress.ApplyTo(trip)
 		return nil
 	}
 	return domain.ErrTripNotFound
@@ -221,6 +269,91 @@ func (r *fakeTripRepo) SetTripDriver(id string, driverID *string) error {
 	return domain.ErrTripNotFound
 }
 
+func (r *fakeTripRepo) SaveTripProgress(id string, progress domain.TripProgress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if trip, ok := r.trips[id]; ok {
+		progress.ApplyTo(trip)
+		return nil
+	}
+	return domain.ErrTripNotFound
+}
+
+func (r *fakeTripRepo) ClaimFareCharge(id string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trip, ok := r.trips[id]
+	if !ok || trip.Status != domain.TripStatusCompleted || trip.FareChargedAt != nil {
+		return false, nil
+	}
+	trip.FareChargedAt = &at
+	return true, nil
+}
+
+func (r *fakeTripRepo) ReleaseFareCharge(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trip, ok := r.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	trip.FareChargedAt = nil
+	return nil
+}
+
+func (r *fakeTripRepo) ListUnchargedTrips(limit int) ([]*domain.Trip, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	filtered := make([]*domain.Trip, 0)
+	for _, id := range r.order {
+		trip := r.trips[id]
+		if trip.Status == domain.TripStatusCompleted && trip.FareChargedAt == nil {
+			filtered = append(filtered, cloneTrip(trip))
+		}
+	}
+	return filtered, nil
+}
+
+func (r *fakeTripRepo) SaveMatchedTrail(id string, trail *domain.MatchedTrail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trip, ok := r.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	trip.MatchedTrail = trail
+	return nil
+}
+
+func (r *fakeTripRepo) SaveTripETA(id string, eta *domain.TripETA) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trip, ok := r.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	trip.ETA = eta
+	return nil
+}
+
+func (r *fakeTripRepo) MarkTripStopReached(id string, index int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trip, ok := r.trips[id]
+	if !ok {
+		return domain.ErrTripNotFound
+	}
+	stop, ok := trip.Stop(index)
+	if !ok {
+		return domain.ErrStopNotFound
+	}
+	if stop.ArrivedAt != nil {
+		return domain.ErrStopAlreadyReached
+	}
+	stop.ArrivedAt = &at
+	return nil
+}
+
 func (r *fakeTripRepo) SaveLocation(tripID string, update domain.LocationUpdate) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -242,6 +375,10 @@ func (r *fakeTripRepo) GetLatestLocation(tripID string) (*domain.LocationUpdate,
 	return nil, nil
 }
 
+func (r *fakeTripRepo) ListLocations(tripID string, from, to time.Time) ([]domain.LocationUpdate, error) {
+	return nil, nil
+}
+
 func (r *fakeTripRepo) ListTrips(userID, role string, limit, offset int) ([]*domain.Trip, int64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -277,14 +414,14 @@ type stubWalletOps struct {
 	ensureErr error
 }
 
-func (s *stubWalletOps) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string) (int64, error) {
+func (s *stubWalletOps) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string, fare int64) (int64, error) {
 	if s.ensureErr != nil {
 		return 0, s.ensureErr
 	}
 	return 15000, nil
 }
 
-func (s *stubWalletOps) DeductTripFare(ctx context.Context, userID, serviceID string) (*domain.WalletSummary, int64, error) {
+func (s *stubWalletOps) DeductTripFare(ctx context.Context, userID, serviceID string, fare int64) (*domain.WalletSummary, int64, error) {
 	return nil, 0, nil
 }
 
@@ -292,15 +429,36 @@ func (s *stubWalletOps) RewardTripCompletion(ctx context.Context, userID string)
 	return nil, 0, nil
 }
 
+func (s *stubWalletOps) ChargeCancellationFee(ctx context.Context, userID string, fee int64) (*domain.WalletSummary, error) {
+	return nil, nil
+}
+
 func cloneTrip(src *domain.Trip) *domain.Trip {
 	copyTrip := *src
 	if src.DriverID != nil {
 		driver := *src.DriverID
 		copyTrip.DriverID = &driver
 	}
+	copyTrip.Stops = append([]domain.TripStop(nil), src.Stops...)
 	return &copyTrip
 }
 
+func (f *fakeTripRepo) AppendTripEvent(event domain.TripEvent) error {
+	return nil
+}
+
+func (f *fakeTripRepo) ListTripEvents(tripID string, types []domain.TripEventType, limit int) ([]domain.TripEvent, error) {
+	return nil, nil
+}
+
+func (f *fakeTripRepo) ListScheduledTrips(riderID string, limit int) ([]*domain.Trip, error) {
+	return nil, nil
+}
+
+func (f *fakeTripRepo) ListDueScheduledTrips(before time.Time, limit int) ([]*domain.Trip, error) {
+	return nil, nil
+}
+
 func (f *fakeTripRepo) PurgeAll() error {
 	return nil
 }
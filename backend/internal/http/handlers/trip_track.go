@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/routing"
+)
+
+const (
+	trackFormatGeoJSON = "geojson"
+	trackFormatGPX     = "gpx"
+)
+
+// RegisterTripTrackRoutes registers the trip track export under /v1.
+func RegisterTripTrackRoutes(router gin.IRouter, service *domain.TripService, driverService *domain.DriverService, provider RoutingProvider) {
+	if router == nil || service == nil {
+		return
+	}
+	handler := &tripTrackHandler{
+		trips:    &TripHandler{service: service, driverService: driverService},
+		provider: provider,
+	}
+	router.GET("/v1/trips/:id/track", handler.export)
+}
+
+type tripTrackHandler struct {
+	trips    *TripHandler
+	provider RoutingProvider
+}
+
+// export returns the recorded trail, the trail snapped to roads once the trip
+// completed, and the planned route of a trip as GeoJSON or GPX, for map
+// rendering and GIS tools.
+func (h *tripTrackHandler) export(c *gin.Context) {
+	tripID := c.Param("id")
+	userID := userIDFromContext(c)
+	role := roleFromContext(c)
+
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errAuthRequired})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", trackFormatGeoJSON)))
+	if format != trackFormatGeoJSON && format != trackFormatGPX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be geojson or gpx"})
+		return
+	}
+
+	trip, err := h.trips.service.Fetch(c.Request.Context(), tripID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errTripNotFound})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.trips.canAccessTrip(trip, userID, role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	trail, err := h.trips.service.Trail(c.Request.Context(), trip)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	planned := h.plannedRoute(c, trip)
+
+	filename := fmt.Sprintf("trip-%s.%s", trip.ID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	if format == trackFormatGPX {
+		body, err := xml.MarshalIndent(buildTripGPX(trip, trail, planned), "", "  ")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/gpx+xml", append([]byte(xml.Header), body...))
+		return
+	}
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, buildTripGeoJSON(trip, trail, planned))
+}
+
+// plannedRoute looks up the route through the trip's pickup, stops and
+// drop-off. Tracks are still served when routing is unavailable, just without
+// the planned feature.
+func (h *tripTrackHandler) plannedRoute(c *gin.Context, trip *domain.Trip) *routing.Route {
+	points := trip.Waypoints()
+	if h.provider == nil || points == nil {
+		return nil
+	}
+	var (
+		route *routing.Route
+		err   error
+	)
+	if via, ok := h.provider.(WaypointProvider); ok {
+		waypoints := make([]routing.Coordinate, 0, len(points))
+		for _, point := range points {
+			waypoints = append(waypoints, routing.Coordinate{Lat: point.Lat, Lng: point.Lng})
+		}
+		route, err = via.GetRouteVia(c.Request.Context(), waypoints)
+	} else if len(points) == 2 {
+		route, err = h.provider.GetRoute(
+			c.Request.Context(),
+			routing.Coordinate{Lat: points[0].Lat, Lng: points[0].Lng},
+			routing.Coordinate{Lat: points[1].Lat, Lng: points[1].Lng},
+		)
+	} else {
+		return nil
+	}
+	if err != nil {
+		log.Printf("planned route for trip %s: %v", trip.ID, err)
+		return nil
+	}
+	return route
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// buildTripGeoJSON emits the travelled trail as a LineString with per-point
+// timestamps in coordTimes, followed by the snapped trail and the planned
+// route.
+func buildTripGeoJSON(trip *domain.Trip, trail []domain.LocationUpdate, planned *routing.Route) geoJSONFeatureCollection {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	if len(trail) > 0 {
+		coords := make([][]float64, 0, len(trail))
+		times := make([]string, 0, len(trail))
+		for _, point := range trail {
+			coords = append(coords, []float64{point.Longitude, point.Latitude})
+			times = append(times, point.Timestamp.UTC().Format(time.RFC3339))
+		}
+		geometry := geoJSONGeometry{Type: "LineString", Coordinates: coords}
+		if len(coords) == 1 {
+			geometry = geoJSONGeometry{Type: "Point", Coordinates: coords[0]}
+		}
+		properties := map[string]any{
+			"kind":       "actual",
+			"tripId":     trip.ID,
+			"status":     trip.Status,
+			"coordTimes": times,
+		}
+		if trip.ActualDistanceMeters != nil {
+			properties["distanceMeters"] = *trip.ActualDistanceMeters
+		}
+		if trip.StartedAt != nil {
+			properties["startedAt"] = trip.StartedAt.UTC().Format(time.RFC3339)
+		}
+		if trip.CompletedAt != nil {
+			properties["completedAt"] = trip.CompletedAt.UTC().Format(time.RFC3339)
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geometry,
+			Properties: properties,
+		})
+	}
+	if matched := trip.MatchedTrail; matched != nil {
+		if coords := normalizeCoordinates(matched.Coordinates); len(coords) >= 2 {
+			collection.Features = append(collection.Features, geoJSONFeature{
+				Type:     "Feature",
+				Geometry: geoJSONGeometry{Type: "LineString", Coordinates: coords},
+				Properties: map[string]any{
+					"kind":           "matched",
+					"tripId":         trip.ID,
+					"distanceMeters": matched.DistanceMeters,
+					"snapped":        matched.Snapped,
+				},
+			})
+		}
+	}
+	if planned != nil {
+		if coords := normalizeCoordinates(planned.Coordinates); len(coords) >= 2 {
+			collection.Features = append(collection.Features, geoJSONFeature{
+				Type:     "Feature",
+				Geometry: geoJSONGeometry{Type: "LineString", Coordinates: coords},
+				Properties: map[string]any{
+					"kind":            "planned",
+					"tripId":          trip.ID,
+					"distanceMeters":  planned.Distance,
+					"durationSeconds": planned.Duration,
+				},
+			})
+		}
+	}
+	return collection
+}
+
+type gpxDocument struct {
+	XMLName  xml.Name    `xml:"gpx"`
+	Version  string      `xml:"version,attr"`
+	Creator  string      `xml:"creator,attr"`
+	XMLNS    string      `xml:"xmlns,attr"`
+	Metadata gpxMetadata `xml:"metadata"`
+	Routes   []gpxRoute  `xml:"rte,omitempty"`
+	Tracks   []gpxTrack  `xml:"trk,omitempty"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string            `xml:"name"`
+	Segments []gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxTrackSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+}
+
+// buildTripGPX emits the planned route as a GPX route, the travelled trail as
+// a timestamped track and the snapped trail as a second track.
+func buildTripGPX(trip *domain.Trip, trail []domain.LocationUpdate, planned *routing.Route) gpxDocument {
+	doc := gpxDocument{
+		Version: "1.1",
+		Creator: "UITGo",
+		XMLNS:   "http://www.topografix.com/GPX/1/1",
+		Metadata: gpxMetadata{
+			Name: "Trip " + trip.ID,
+			Time: trip.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	}
+	if planned != nil {
+		route := gpxRoute{Name: "Planned route"}
+		for _, pair := range normalizeCoordinates(planned.Coordinates) {
+			route.Points = append(route.Points, gpxPoint{Lat: pair[1], Lon: pair[0]})
+		}
+		if len(route.Points) > 0 {
+			doc.Routes = append(doc.Routes, route)
+		}
+	}
+	if len(trail) > 0 {
+		segment := gpxTrackSegment{Points: make([]gpxPoint, 0, len(trail))}
+		for _, point := range trail {
+			segment.Points = append(segment.Points, gpxPoint{
+				Lat:  point.Latitude,
+				Lon:  point.Longitude,
+				Time: point.Timestamp.UTC().Format(time.RFC3339),
+			})
+		}
+		doc.Tracks = append(doc.Tracks, gpxTrack{Name: "Trip " + trip.ID, Segments: []gpxTrackSegment{segment}})
+	}
+	if trip.MatchedTrail != nil {
+		segment := gpxTrackSegment{}
+		for _, pair := range normalizeCoordinates(trip.MatchedTrail.Coordinates) {
+			segment.Points = append(segment.Points, gpxPoint{Lat: pair[1], Lon: pair[0]})
+		}
+		if len(segment.Points) > 0 {
+			doc.Tracks = append(doc.Tracks, gpxTrack{Name: "Trip " + trip.ID + " (matched)", Segments: []gpxTrackSegment{segment}})
+		}
+	}
+	return doc
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/routing"
+)
+
+type trailTripRepo struct {
+	*fakeTripRepo
+	trail []domain.LocationUpdate
+}
+
+func (r *trailTripRepo) ListLocations(tripID string, from, to time.Time) ([]domain.LocationUpdate, error) {
+	return r.trail, nil
+}
+
+type stubRoutingProvider struct {
+	route *routing.Route
+}
+
+func (p *stubRoutingProvider) GetRoute(ctx context.Context, origin, destination routing.Coordinate) (*routing.Route, error) {
+	return p.route, nil
+}
+
+type waypointRoutingProvider struct {
+	stubRoutingProvider
+	waypoints []routing.Coordinate
+}
+
+func (p *waypointRoutingProvider) GetRouteVia(ctx context.Context, waypoints []routing.Coordinate) (*routing.Route, error) {
+	p.waypoints = waypoints
+	return p.route, nil
+}
+
+func newTrackRouter(t *testing.T) (*gin.Engine, *domain.Trip) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	repo := &trailTripRepo{
+		fakeTripRepo: newFakeTripRepo(),
+		trail: []domain.LocationUpdate{
+			{Latitude: 10.870, Longitude: 106.800, Timestamp: start},
+			{Latitude: 10.875, Longitude: 106.795, Timestamp: start.Add(time.Minute)},
+			{Latitude: 10.880, Longitude: 106.780, Timestamp: start.Add(2 * time.Minute)},
+		},
+	}
+	service := domain.NewTripService(repo, nil, nil)
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	trip := &domain.Trip{
+		RiderID:    "rider-1",
+		ServiceID:  "uit-bike",
+		OriginText: "UIT",
+		DestText:   "KTX",
+		OriginLat:  &originLat,
+		OriginLng:  &originLng,
+		DestLat:    &destLat,
+		DestLng:    &destLng,
+	}
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	provider := &stubRoutingProvider{route: &routing.Route{
+		Distance:    2500,
+		Duration:    420,
+		Coordinates: [][]float64{{106.80, 10.87}, {106.79, 10.876}, {106.78, 10.88}},
+	}}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("userID", user)
+		}
+	})
+	RegisterTripTrackRoutes(router, service, nil, provider)
+	return router, trip
+}
+
+func TestTripTrackGeoJSON(t *testing.T) {
+	t.Parallel()
+	router, trip := newTrackRouter(t)
+
+	res := performTripRequest(t, router, http.MethodGet, "/v1/trips/"+trip.ID+"/track", "", "rider-1")
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Contains(t, res.Header().Get("Content-Type"), "application/geo+json")
+
+	var collection geoJSONFeatureCollection
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &collection))
+	require.Len(t, collection.Features, 2)
+	require.Equal(t, "LineString", collection.Features[0].Geometry.Type)
+	require.Equal(t, "actual", collection.Features[0].Properties["kind"])
+	require.Len(t, collection.Features[0].Properties["coordTimes"], 3)
+	require.Equal(t, "planned", collection.Features[1].Properties["kind"])
+}
+
+func TestTripTrackGPX(t *testing.T) {
+	t.Parallel()
+	router, trip := newTrackRouter(t)
+
+	res := performTripRequest(t, router, http.MethodGet, "/v1/trips/"+trip.ID+"/track?format=gpx", "", "rider-1")
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Contains(t, res.Header().Get("Content-Type"), "application/gpx+xml")
+
+	var doc gpxDocument
+	require.NoError(t, xml.Unmarshal(res.Body.Bytes(), &doc))
+	require.Len(t, doc.Tracks, 1)
+	require.Len(t, doc.Tracks[0].Segments[0].Points, 3)
+	require.Equal(t, "2025-03-01T08:01:00Z", doc.Tracks[0].Segments[0].Points[1].Time)
+	require.Len(t, doc.Routes, 1)
+	require.Len(t, doc.Routes[0].Points, 3)
+}
+
+func TestTripTrackRequiresTripAccess(t *testing.T) {
+	t.Parallel()
+	router, trip := newTrackRouter(t)
+
+	res := performTripRequest(t, router, http.MethodGet, "/v1/trips/"+trip.ID+"/track", "", "someone-else")
+	require.Equal(t, http.StatusForbidden, res.Code)
+
+	res = performTripRequest(t, router, http.MethodGet, "/v1/trips/"+trip.ID+"/track?format=kml", "", "rider-1")
+	require.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+func TestTripTrackIncludesMatchedTrail(t *testing.T) {
+	trip := &domain.Trip{
+		ID: "trip-1",
+		MatchedTrail: &domain.MatchedTrail{
+			Coordinates:    [][]float64{{106.80, 10.87}, {106.795, 10.874}, {106.78, 10.88}},
+			DistanceMeters: 2450,
+			Snapped:        true,
+		},
+	}
+
+	collection := buildTripGeoJSON(trip, nil, nil)
+	require.Len(t, collection.Features, 1)
+	require.Equal(t, "matched", collection.Features[0].Properties["kind"])
+	require.Equal(t, 2450.0, collection.Features[0].Properties["distanceMeters"])
+	require.Equal(t, true, collection.Features[0].Properties["snapped"])
+
+	doc := buildTripGPX(trip, nil, nil)
+	require.Len(t, doc.Tracks, 1)
+	require.Equal(t, "Trip trip-1 (matched)", doc.Tracks[0].Name)
+	require.Len(t, doc.Tracks[0].Segments[0].Points, 3)
+}
+
+func TestTripTrackPlannedRouteVisitsStops(t *testing.T) {
+	originLat, originLng, destLat, destLng := 10.87, 106.80, 10.88, 106.78
+	trip := &domain.Trip{
+		ID:        "trip-1",
+		OriginLat: &originLat,
+		OriginLng: &originLng,
+		DestLat:   &destLat,
+		DestLng:   &destLng,
+		Stops:     []domain.TripStop{{Index: 0, Text: "Library", Lat: 10.875, Lng: 106.79}},
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/trips/trip-1/track", nil)
+
+	provider := &waypointRoutingProvider{stubRoutingProvider: stubRoutingProvider{route: &routing.Route{Distance: 2500}}}
+	handler := &tripTrackHandler{provider: provider}
+	require.NotNil(t, handler.plannedRoute(c, trip))
+	require.Equal(t, []routing.Coordinate{
+		{Lat: 10.87, Lng: 106.80},
+		{Lat: 10.875, Lng: 106.79},
+		{Lat: 10.88, Lng: 106.78},
+	}, provider.waypoints)
+
+	// Providers that cannot route through stops leave the planned route out.
+	handler = &tripTrackHandler{provider: &provider.stubRoutingProvider}
+	require.Nil(t, handler.plannedRoute(c, trip))
+}
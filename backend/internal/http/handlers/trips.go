@@ -39,31 +39,57 @@ func RegisterTripRoutes(router gin.IRouter, service *domain.TripService, driverS
 	v1 := router.Group("/v1")
 	{
 		v1.GET("/trips", handler.listTrips)
+		v1.GET("/trips/scheduled", handler.listScheduledTrips)
 		createTripHandlers := append([]gin.HandlerFunc{}, createTripMiddlewares...)
 		createTripHandlers = append(createTripHandlers, handler.createTrip)
 		v1.POST("/trips", createTripHandlers...)
 		v1.GET("/trips/:id", handler.getTrip)
+		v1.GET("/trips/:id/events", handler.listTripEvents)
 		v1.PATCH("/trips/:id/status", handler.updateTripStatus)
+		v1.POST("/trips/:id/cancel", handler.cancelTrip)
 		v1.POST("/trips/:id/assign", handler.assignDriver)
 		v1.POST("/trips/:id/accept", handler.acceptTrip)
 		v1.POST("/trips/:id/decline", handler.declineTrip)
 		v1.POST("/trips/:id/status", handler.driverUpdateTripStatus)
 		v1.GET("/trips/:id/ws", hubs.HandleWebsocket(service))
+		v1.GET("/trips/:id/stream", hubs.HandleStream(service))
 	}
 }
 
 type createTripRequest struct {
-	OriginText string   `json:"originText" binding:"required"`
-	DestText   string   `json:"destText" binding:"required"`
-	ServiceID  string   `json:"serviceId" binding:"required"`
-	OriginLat  *float64 `json:"originLat"`
-	OriginLng  *float64 `json:"originLng"`
-	DestLat    *float64 `json:"destLat"`
-	DestLng    *float64 `json:"destLng"`
+	OriginText  string            `json:"originText" binding:"required"`
+	DestText    string            `json:"destText" binding:"required"`
+	ServiceID   string            `json:"serviceId" binding:"required"`
+	OriginLat   *float64          `json:"originLat"`
+	OriginLng   *float64          `json:"originLng"`
+	DestLat     *float64          `json:"destLat"`
+	DestLng     *float64          `json:"destLng"`
+	Stops       []tripStopRequest `json:"stops"`
+	ScheduledAt *time.Time        `json:"scheduledAt"`
+	QuoteID     string            `json:"quoteId"`
+}
+
+type tripStopRequest struct {
+	Text string  `json:"text"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
 }
 
 type updateStatusRequest struct {
 	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// driverStatusRequest either moves the trip to Status or, when Stop is set,
+// marks that intermediate stop as reached.
+type driverStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	Stop   *int   `json:"stop"`
+}
+
+type cancelTripRequest struct {
+	Reason string `json:"reason"`
 }
 
 type assignTripRequest struct {
@@ -71,20 +97,36 @@ type assignTripRequest struct {
 }
 
 type tripResponse struct {
-	ID           string                 `json:"id"`
-	RiderID      string                 `json:"riderId"`
-	DriverID     *string                `json:"driverId,omitempty"`
-	ServiceID    string                 `json:"serviceId"`
-	OriginText   string                 `json:"originText"`
-	DestText     string                 `json:"destText"`
-	OriginLat    *float64               `json:"originLat,omitempty"`
-	OriginLng    *float64               `json:"originLng,omitempty"`
-	DestLat      *float64               `json:"destLat,omitempty"`
-	DestLng      *float64               `json:"destLng,omitempty"`
-	Status       domain.TripStatus      `json:"status"`
-	CreatedAt    time.Time              `json:"createdAt"`
-	UpdatedAt    time.Time              `json:"updatedAt"`
-	LastLocation *domain.LocationUpdate `json:"lastLocation,omitempty"`
+	ID                   string                 `json:"id"`
+	RiderID              string                 `json:"riderId"`
+	DriverID             *string                `json:"driverId,omitempty"`
+	ServiceID            string                 `json:"serviceId"`
+	OriginText           string                 `json:"originText"`
+	DestText             string                 `json:"destText"`
+	OriginLat            *float64               `json:"originLat,omitempty"`
+	OriginLng            *float64               `json:"originLng,omitempty"`
+	DestLat              *float64               `json:"destLat,omitempty"`
+	DestLng              *float64               `json:"destLng,omitempty"`
+	Stops                []domain.TripStop      `json:"stops,omitempty"`
+	QuotedFare           *int64                 `json:"quotedFare,omitempty"`
+	FinalFare            *int64                 `json:"finalFare,omitempty"`
+	Currency             string                 `json:"currency,omitempty"`
+	RouteDistanceMeters  *float64               `json:"routeDistanceMeters,omitempty"`
+	RouteDurationSeconds *float64               `json:"routeDurationSeconds,omitempty"`
+	ActualDistanceMeters *float64               `json:"actualDistanceMeters,omitempty"`
+	FareBasis            domain.FareBasis       `json:"fareBasis,omitempty"`
+	SurgeMultiplier      *float64               `json:"surgeMultiplier,omitempty"`
+	ZoneSurcharge        *int64                 `json:"zoneSurcharge,omitempty"`
+	CancellationFee      *int64                 `json:"cancellationFee,omitempty"`
+	ScheduledAt          *time.Time             `json:"scheduledAt,omitempty"`
+	AcceptedAt           *time.Time             `json:"acceptedAt,omitempty"`
+	StartedAt            *time.Time             `json:"startedAt,omitempty"`
+	CompletedAt          *time.Time             `json:"completedAt,omitempty"`
+	ETA                  *domain.TripETA        `json:"eta,omitempty"`
+	Status               domain.TripStatus      `json:"status"`
+	CreatedAt            time.Time              `json:"createdAt"`
+	UpdatedAt            time.Time              `json:"updatedAt"`
+	LastLocation         *domain.LocationUpdate `json:"lastLocation,omitempty"`
 }
 
 type tripListResponse struct {
@@ -96,20 +138,36 @@ type tripListResponse struct {
 
 func toTripResponse(trip *domain.Trip, location *domain.LocationUpdate) tripResponse {
 	return tripResponse{
-		ID:           trip.ID,
-		RiderID:      trip.RiderID,
-		DriverID:     trip.DriverID,
-		ServiceID:    trip.ServiceID,
-		OriginText:   trip.OriginText,
-		DestText:     trip.DestText,
-		OriginLat:    trip.OriginLat,
-		OriginLng:    trip.OriginLng,
-		DestLat:      trip.DestLat,
-		DestLng:      trip.DestLng,
-		Status:       trip.Status,
-		CreatedAt:    trip.CreatedAt,
-		UpdatedAt:    trip.UpdatedAt,
-		LastLocation: location,
+		ID:                   trip.ID,
+		RiderID:              trip.RiderID,
+		DriverID:             trip.DriverID,
+		ServiceID:            trip.ServiceID,
+		OriginText:           trip.OriginText,
+		DestText:             trip.DestText,
+		OriginLat:            trip.OriginLat,
+		OriginLng:            trip.OriginLng,
+		DestLat:              trip.DestLat,
+		DestLng:              trip.DestLng,
+		Stops:                trip.Stops,
+		QuotedFare:           trip.QuotedFare,
+		FinalFare:            trip.FinalFare,
+		Currency:             trip.Currency,
+		RouteDistanceMeters:  trip.RouteDistanceMeters,
+		RouteDurationSeconds: trip.RouteDurationSeconds,
+		ActualDistanceMeters: trip.ActualDistanceMeters,
+		FareBasis:            trip.FareBasis,
+		SurgeMultiplier:      trip.SurgeMultiplier,
+		ZoneSurcharge:        trip.ZoneSurcharge,
+		CancellationFee:      trip.CancellationFee,
+		ScheduledAt:          trip.ScheduledAt,
+		AcceptedAt:           trip.AcceptedAt,
+		StartedAt:            trip.StartedAt,
+		CompletedAt:          trip.CompletedAt,
+		ETA:                  trip.ETA,
+		Status:               trip.Status,
+		CreatedAt:            trip.CreatedAt,
+		UpdatedAt:            trip.UpdatedAt,
+		LastLocation:         location,
 	}
 }
 
@@ -158,6 +216,32 @@ func (h *TripHandler) listTrips(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// listScheduledTrips returns the rider's upcoming scheduled trips, soonest first.
+func (h *TripHandler) listScheduledTrips(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return
+	}
+
+	limit := queryInt(c, "limit", 20, 100)
+	trips, err := h.service.ListScheduled(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list scheduled trips"})
+		return
+	}
+
+	resp := tripListResponse{
+		Items: make([]tripResponse, 0, len(trips)),
+		Total: int64(len(trips)),
+		Limit: limit,
+	}
+	for _, trip := range trips {
+		resp.Items = append(resp.Items, toTripResponse(trip, nil))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *TripHandler) createTrip(c *gin.Context) {
 	var req createTripRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -180,6 +264,14 @@ func (h *TripHandler) createTrip(c *gin.Context) {
 		OriginLng:  req.OriginLng,
 		DestLat:    req.DestLat,
 		DestLng:    req.DestLng,
+		QuoteID:    strings.TrimSpace(req.QuoteID),
+	}
+	for _, stop := range req.Stops {
+		trip.Stops = append(trip.Stops, domain.TripStop{Text: stop.Text, Lat: stop.Lat, Lng: stop.Lng})
+	}
+	if req.ScheduledAt != nil {
+		pickup := req.ScheduledAt.UTC()
+		trip.ScheduledAt = &pickup
 	}
 
 	if err := h.service.Create(c.Request.Context(), trip); err != nil {
@@ -187,10 +279,27 @@ func (h *TripHandler) createTrip(c *gin.Context) {
 			c.JSON(http.StatusPaymentRequired, gin.H{"error": "insufficient wallet balance"})
 			return
 		}
+		if errors.Is(err, domain.ErrQuoteExpired) {
+			c.JSON(http.StatusConflict, gin.H{"error": "fare quote expired, request a new quote"})
+			return
+		}
+		if errors.Is(err, domain.ErrQuoteMismatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": "fare quote does not match this trip, request a new quote"})
+			return
+		}
+		if writeServiceAreaError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Scheduled trips are dispatched by the scheduler once their pickup nears.
+	if trip.Status == domain.TripStatusScheduled {
+		c.JSON(http.StatusCreated, toTripResponse(trip, nil))
+		return
+	}
+
 	scheduled := false
 	if h.dispatcher != nil {
 		event := &matching.TripEvent{
@@ -200,6 +309,8 @@ func (h *TripHandler) createTrip(c *gin.Context) {
 			OriginText: trip.OriginText,
 			DestText:   trip.DestText,
 			Requested:  trip.CreatedAt,
+			OriginLat:  trip.OriginLat,
+			OriginLng:  trip.OriginLng,
 		}
 		if err := h.dispatcher.Publish(c.Request.Context(), event); err != nil {
 			log.Printf("dispatch trip %s failed: %v", trip.ID, err)
@@ -292,20 +403,14 @@ func (h *TripHandler) updateTripStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateStatus(c.Request.Context(), tripID, status); err != nil {
-		if errors.Is(err, domain.ErrTripNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": errTripNotFound})
-			return
-		}
-		if errors.Is(err, domain.ErrInvalidStatus) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
-			return
-		}
-		if errors.Is(err, domain.ErrWalletInsufficientFunds) {
-			c.JSON(http.StatusPaymentRequired, gin.H{"error": "insufficient wallet balance"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	change := domain.TripStatusChange{
+		To:      status,
+		Actor:   domain.ActorForRole(role),
+		ActorID: userID,
+		Reason:  strings.TrimSpace(req.Reason),
+	}
+	if _, err := h.service.Transition(c.Request.Context(), tripID, change); err != nil {
+		writeTransitionError(c, err)
 		return
 	}
 
@@ -326,6 +431,55 @@ func (h *TripHandler) updateTripStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, toTripResponse(trip, location))
 }
 
+// cancelTrip lets the rider (or an admin) cancel a trip. Riders cancelling after
+// the grace period past acceptance are charged the cancellation fee.
+func (h *TripHandler) cancelTrip(c *gin.Context) {
+	userID := userIDFromContext(c)
+	role := roleFromContext(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errAuthRequired})
+		return
+	}
+
+	tripID := c.Param("id")
+	var req cancelTripRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	trip, err := h.service.Fetch(c.Request.Context(), tripID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errTripNotFound})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if role != "admin" && trip.RiderID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	trip, err = h.service.Transition(c.Request.Context(), tripID, domain.TripStatusChange{
+		To:      domain.TripStatusCancelled,
+		Actor:   domain.ActorForRole(role),
+		ActorID: userID,
+		Reason:  strings.TrimSpace(req.Reason),
+	})
+	if err != nil {
+		writeTransitionError(c, err)
+		return
+	}
+
+	h.hubs.BroadcastStatus(tripID, domain.TripStatusCancelled)
+	location, _ := h.service.LatestLocation(c.Request.Context(), tripID)
+	c.JSON(http.StatusOK, toTripResponse(trip, location))
+}
+
 func (h *TripHandler) assignDriver(c *gin.Context) {
 	if h.driverService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "driver dispatch unavailable"})
@@ -429,13 +583,21 @@ func (h *TripHandler) driverUpdateTripStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "trip id required"})
 		return
 	}
-	var req updateStatusRequest
+	var req driverStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Stop != nil {
+		h.driverReachStop(c, tripID, driver.ID, *req.Stop)
+		return
+	}
 	status := domain.TripStatus(strings.ToLower(strings.TrimSpace(req.Status)))
-	trip, err := h.driverService.UpdateTripStatus(c.Request.Context(), tripID, driver.ID, status)
+	if status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status or stop required"})
+		return
+	}
+	trip, err := h.driverService.UpdateTripStatus(c.Request.Context(), tripID, driver.ID, status, strings.TrimSpace(req.Reason))
 	if err != nil {
 		statusCode := driverErrorStatus(err)
 		c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -446,6 +608,21 @@ func (h *TripHandler) driverUpdateTripStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, toTripResponse(trip, location))
 }
 
+// driverReachStop marks an intermediate stop as reached and pushes the arrival
+// to the trip's WebSocket subscribers.
+func (h *TripHandler) driverReachStop(c *gin.Context, tripID, driverID string, index int) {
+	trip, err := h.driverService.ReachStop(c.Request.Context(), tripID, driverID, index)
+	if err != nil {
+		c.JSON(driverErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if stop, ok := trip.Stop(index); ok {
+		h.hubs.BroadcastStopArrived(tripID, *stop)
+	}
+	location, _ := h.service.LatestLocation(c.Request.Context(), tripID)
+	c.JSON(http.StatusOK, toTripResponse(trip, location))
+}
+
 func (h *TripHandler) requireDriver(c *gin.Context) (*domain.Driver, bool) {
 	if h.driverService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "driver dispatch unavailable"})
@@ -471,10 +648,13 @@ func driverErrorStatus(err error) int {
 		return http.StatusNotFound
 	case domain.ErrWalletInsufficientFunds:
 		return http.StatusPaymentRequired
-	case domain.ErrDriverNotFound, domain.ErrTripAssignmentNotFound:
+	case domain.ErrDriverNotFound, domain.ErrTripAssignmentNotFound, domain.ErrStopNotFound:
 		return http.StatusNotFound
-	case domain.ErrDriverOffline, domain.ErrAssignmentConflict:
+	case domain.ErrDriverOffline, domain.ErrAssignmentConflict, domain.ErrAssignmentExpired, domain.ErrInvalidTransition,
+		domain.ErrStopOutOfOrder, domain.ErrStopAlreadyReached:
 		return http.StatusConflict
+	case domain.ErrTransitionNotPermitted, domain.ErrDriverIneligible:
+		return http.StatusForbidden
 	case domain.ErrInvalidStatus:
 		return http.StatusBadRequest
 	default:
@@ -482,6 +662,24 @@ func driverErrorStatus(err error) int {
 	}
 }
 
+// writeTransitionError maps trip lifecycle errors onto HTTP responses.
+func writeTransitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTripNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": errTripNotFound})
+	case errors.Is(err, domain.ErrInvalidStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+	case errors.Is(err, domain.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrTransitionNotPermitted):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrWalletInsufficientFunds):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "insufficient wallet balance"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // roleFromContext extracts the user role from gin context
 func roleFromContext(c *gin.Context) string {
 	if role, exists := c.Get("role"); exists {
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/realtime"
+)
+
+func decodeOutbound(t *testing.T, msg realtime.Message) outboundMessage {
+	t.Helper()
+	var out outboundMessage
+	require.NoError(t, json.Unmarshal(msg.Payload, &out))
+	return out
+}
+
+func TestHubManagerBroadcastReachesOtherReplicas(t *testing.T) {
+	backplane := realtime.NewMemoryBackplane(realtime.DefaultHistorySize)
+	replicaA := NewHubManager(nil, nil, WithBackplane(backplane))
+	replicaB := NewHubManager(nil, nil, WithBackplane(backplane))
+
+	local := &Client{send: make(chan realtime.Message, 1)}
+	remote := &Client{send: make(chan realtime.Message, 1)}
+	replicaA.acquire("trip-1").register <- local
+	replicaB.acquire("trip-1").register <- remote
+
+	replicaA.BroadcastStatus("trip-1", domain.TripStatusArriving)
+
+	for _, client := range []*Client{local, remote} {
+		select {
+		case payload := <-client.send:
+			msg := decodeOutbound(t, payload)
+			require.Equal(t, "status", msg.Type)
+			require.Equal(t, domain.TripStatusArriving, msg.Status)
+			require.Equal(t, uint64(1), msg.Seq)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for broadcast")
+		}
+	}
+	select {
+	case payload := <-local.send:
+		t.Fatalf("duplicate delivery: %s", payload.Payload)
+	default:
+	}
+}
+
+func TestHubManagerCatchUpReplaysOrSnapshots(t *testing.T) {
+	service := domain.NewTripService(newFakeTripRepo(), nil, nil)
+	trip := &domain.Trip{RiderID: "rider-1", ServiceID: "uit-bike", OriginText: "UIT", DestText: "KTX"}
+	require.NoError(t, service.Create(context.Background(), trip))
+
+	manager := NewHubManager(service, nil, WithBackplane(realtime.NewMemoryBackplane(2)))
+	for _, status := range []domain.TripStatus{domain.TripStatusAccepted, domain.TripStatusArriving, domain.TripStatusInRide} {
+		require.NoError(t, service.UpdateStatus(context.Background(), trip.ID, status))
+		manager.BroadcastStatus(trip.ID, status)
+	}
+
+	since := uint64(1)
+	missed, _ := manager.catchUp(context.Background(), service, trip, "rider", &since)
+	require.Len(t, missed, 2)
+	require.Equal(t, uint64(2), missed[0].Seq)
+	require.Equal(t, domain.TripStatusInRide, decodeOutbound(t, missed[1]).Status)
+
+	since = 0
+	snapshot, covered := manager.catchUp(context.Background(), service, trip, "rider", &since)
+	require.Len(t, snapshot, 1)
+	msg := decodeOutbound(t, snapshot[0])
+	require.Equal(t, "snapshot", msg.Type)
+	require.Equal(t, uint64(3), msg.Seq)
+	require.Equal(t, uint64(3), covered)
+	require.NotNil(t, msg.Trip)
+	require.Equal(t, domain.TripStatusInRide, msg.Trip.Status)
+}
+
+func TestHubManagerReleasesIdleHubs(t *testing.T) {
+	manager := NewHubManager(nil, nil)
+
+	// Broadcasting alone does not start a hub.
+	manager.BroadcastStatus("trip-1", domain.TripStatusAccepted)
+	require.Empty(t, manager.hubs)
+
+	first := manager.acquire("trip-1")
+	second := manager.acquire("trip-1")
+	require.Same(t, first, second)
+
+	manager.release(first)
+	require.Contains(t, manager.hubs, "trip-1")
+	manager.release(second)
+	require.Empty(t, manager.hubs)
+
+	select {
+	case <-first.done:
+	default:
+		t.Fatal("hub still running")
+	}
+	// The subscription is closed, so publishing no longer blocks on the hub.
+	manager.BroadcastStatus("trip-1", domain.TripStatusArriving)
+	require.NotSame(t, first, manager.acquire("trip-1"))
+}
+
+func TestClientSkipsOnlyCaughtUpMessages(t *testing.T) {
+	replayed := &Client{}
+	replayed.resume([]realtime.Message{{Seq: 3}, {Seq: 5}}, 0)
+	require.True(t, replayed.seen(realtime.Message{Seq: 5}))
+	require.False(t, replayed.seen(realtime.Message{Seq: 4}), "late publishes are still delivered")
+	require.True(t, replayed.seen(realtime.Message{Seq: 3}))
+	require.False(t, replayed.seen(realtime.Message{Seq: 3}), "each replayed message is skipped once")
+
+	snapshot := &Client{}
+	snapshot.resume([]realtime.Message{{Seq: 7}}, 7)
+	require.True(t, snapshot.seen(realtime.Message{Seq: 6}))
+	require.False(t, snapshot.seen(realtime.Message{Seq: 8}))
+}
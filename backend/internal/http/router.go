@@ -19,6 +19,7 @@ import (
 	"uitgo/backend/internal/http/middleware"
 	"uitgo/backend/internal/notification"
 	"uitgo/backend/internal/observability"
+	"uitgo/backend/internal/realtime"
 	"uitgo/backend/internal/routing"
 
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -26,8 +27,9 @@ import (
 
 // Server wraps the Gin engine and dependencies.
 type Server struct {
-	engine *gin.Engine
-	cfg    *config.Config
+	engine          *gin.Engine
+	cfg             *config.Config
+	schedulerCancel context.CancelFunc
 }
 
 // NewServer configures a Gin engine with routes and middleware.
@@ -66,9 +68,30 @@ func NewServer(cfg *config.Config, db *gorm.DB) (*Server, error) {
 	}
 	notificationSvc := notification.NewService(notificationRepo, deviceTokenRepo, pushSender)
 
-	tripService := domain.NewTripService(tripRepo, walletService, notificationSvc)
-	driverService := domain.NewDriverService(driverRepo, assignmentRepo, tripRepo, notificationSvc, nil)
-	hubManager := handlers.NewHubManager(tripService, driverRepo)
+	routeProvider, err := routing.NewProviderFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init routing provider: %w", err)
+	}
+	settlement := domain.DefaultFareSettlementConfig()
+	settlement.DistanceTolerance = cfg.FareDistanceTolerance
+	serviceAreas := domain.NewServiceAreaCatalog(dbrepo.NewServiceAreaRepository(db), cfg.ServiceAreaRefresh)
+	tripService := domain.NewTripService(tripRepo, walletService, notificationSvc,
+		domain.WithFareEstimator(domain.NewFareEstimator(routeProvider, domain.WithFareRules(cfg.FareRules))),
+		domain.WithFareSettlement(settlement),
+		domain.WithCancellationPolicy(domain.CancellationPolicy{GracePeriod: cfg.CancellationGrace, Fee: cfg.CancellationFee}),
+		domain.WithSchedule(domain.ScheduleConfig{MaxAhead: cfg.ScheduleMaxAhead, LeadTime: cfg.ScheduleLeadTime}),
+		domain.WithGeofence(domain.GeofenceConfig{
+			PickupRadiusMeters:      cfg.GeofencePickupRadius,
+			DestinationRadiusMeters: cfg.GeofenceDestRadius,
+			ExitMarginMeters:        cfg.GeofenceExitMargin,
+			ConfirmPings:            cfg.GeofenceConfirmPings,
+		}),
+		domain.WithLiveETA(routeProvider, domain.ETAConfig{MinInterval: cfg.ETARefreshInterval}),
+		domain.WithTrailMatching(routeProvider),
+		domain.WithServiceAreas(serviceAreas),
+	)
+	driverService := domain.NewDriverService(driverRepo, assignmentRepo, tripService.DriverSync(), notificationSvc, nil)
+	hubManager := handlers.NewHubManager(tripService, driverRepo, handlers.WithBackplane(realtime.NewMemoryBackplane(cfg.HubReplayBuffer)))
 	userRepo := domain.NewUserRepository(db)
 	refreshRepo := domain.NewRefreshTokenRepository(db)
 	authHandler, err := handlers.NewAuthHandler(cfg, userRepo, notificationRepo, driverService, refreshRepo)
@@ -80,7 +103,6 @@ func NewServer(cfg *config.Config, db *gorm.DB) (*Server, error) {
 	promotionRepo := dbrepo.NewPromotionRepository(db)
 	newsRepo := dbrepo.NewNewsRepository(db)
 	homeService := domain.NewHomeService(walletRepo, savedPlaceRepo, promotionRepo, newsRepo)
-	routeProvider := routing.NewClient(cfg.RoutingBaseURL, 8*time.Second, 5*time.Minute)
 
 	handlers.RegisterHealth(router)
 	handlers.RegisterRouteRoutes(router, routeProvider)
@@ -101,26 +123,91 @@ func NewServer(cfg *config.Config, db *gorm.DB) (*Server, error) {
 	adminGroup.Use(middleware.RequireRoles("admin"))
 	adminGroup.GET("/me", authHandler.Me)
 	handlers.RegisterAdminRoutes(adminGroup, userRepo, promotionRepo)
+	handlers.RegisterServiceAreaAdminRoutes(adminGroup, serviceAreas)
+	handlers.RegisterDriverAdminRoutes(adminGroup, driverService)
 	handlers.RegisterDriverRoutes(router, driverService)
+	handlers.RegisterDriverSessionRoutes(router, driverService, handlers.NewDriverSessions(nil))
 	handlers.RegisterTripRoutes(router, tripService, driverService, hubManager, nil, tripLimiter.Middleware("trip_create"))
+	handlers.RegisterFareRoutes(router, tripService)
+	handlers.RegisterTripTrackRoutes(router, tripService, driverService, routeProvider)
 	handlers.RegisterNotificationRoutes(router, notificationRepo, notificationSvc)
 	handlers.RegisterWalletRoutes(router, walletService)
 	handlers.RegisterHomeRoutes(router, homeService)
 
 	metrics.Expose(router)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	go releaseScheduledTrips(ctx, tripService, driverService, cfg.SchedulerInterval)
+	go chargePendingFares(ctx, tripService, cfg.SchedulerInterval)
+
 	return &Server{
-		engine: router,
-		cfg:    cfg,
+		engine:          router,
+		cfg:             cfg,
+		schedulerCancel: cancel,
 	}, nil
 }
 
 // Run starts the HTTP server.
 func (s *Server) Run() error {
 	addr := fmt.Sprintf(":%s", s.cfg.Port)
+	defer s.schedulerCancel()
 	return s.engine.Run(addr)
 }
 
+// releaseScheduledTrips periodically moves scheduled trips whose pickup is
+// within the lead time to requested and assigns them the next available driver.
+func releaseScheduledTrips(ctx context.Context, trips *domain.TripService, drivers *domain.DriverService, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		released, err := trips.ReleaseDueTrips(ctx, time.Now().UTC(), 100)
+		if err != nil {
+			log.Printf("release scheduled trips failed: %v", err)
+			continue
+		}
+		for _, trip := range released {
+			if _, err := drivers.AssignNextAvailableDriver(ctx, trip.ID); err != nil && !errors.Is(err, domain.ErrNoDriversAvailable) {
+				log.Printf("auto-assign driver for scheduled trip %s failed: %v", trip.ID, err)
+			}
+		}
+	}
+}
+
+// chargePendingFares periodically retries billing completed trips whose fare
+// charge failed when they were completed.
+func chargePendingFares(ctx context.Context, trips *domain.TripService, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		charged, err := trips.ChargePendingFares(ctx, 100)
+		if err != nil {
+			log.Printf("charge pending fares failed: %v", err)
+			continue
+		}
+		if charged > 0 {
+			log.Printf("charged %d pending trip fares", charged)
+		}
+	}
+}
+
 func seedAdminUser(ctx context.Context, cfg *config.Config, repo domain.UserRepository) {
 	if repo == nil {
 		return
@@ -101,8 +101,13 @@ func (g *GeoIndex) Remove(ctx context.Context, driverID string) error {
 	return nil
 }
 
-// Nearby returns the closest drivers to the provided coordinate.
-func (g *GeoIndex) Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int) ([]*domain.DriverLocation, error) {
+// staleOverfetch widens a nearby search that filters on location age, so stale
+// entries the sweeper has not evicted yet do not crowd out fresh ones.
+const staleOverfetch = 3
+
+// Nearby returns the closest drivers to the provided coordinate, skipping
+// locations older than maxAge unless it is zero.
+func (g *GeoIndex) Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int, maxAge time.Duration) ([]*domain.DriverLocation, error) {
 	if g == nil {
 		return nil, errors.New("geo index not configured")
 	}
@@ -115,6 +120,10 @@ func (g *GeoIndex) Nearby(ctx context.Context, lat, lng, radiusMeters float64, l
 	if limit <= 0 {
 		limit = 10
 	}
+	count := limit
+	if maxAge > 0 {
+		count = limit * staleOverfetch
+	}
 	query := &redis.GeoSearchLocationQuery{
 		GeoSearchQuery: redis.GeoSearchQuery{
 			Longitude:  lng,
@@ -122,7 +131,7 @@ func (g *GeoIndex) Nearby(ctx context.Context, lat, lng, radiusMeters float64, l
 			Radius:     radiusMeters,
 			RadiusUnit: "m",
 			Sort:       "ASC",
-			Count:      limit,
+			Count:      count,
 		},
 		WithDist: true,
 	}
@@ -154,14 +163,21 @@ func (g *GeoIndex) Nearby(ctx context.Context, lat, lng, radiusMeters float64, l
 			}
 		}
 	}
-	locations := make([]*domain.DriverLocation, 0, len(raw))
+	now := time.Now().UTC()
+	locations := make([]*domain.DriverLocation, 0, limit)
 	for _, item := range raw {
 		if item.Name == "" {
 			continue
 		}
 		recordedAt := timestamps[item.Name]
 		if recordedAt.IsZero() {
-			recordedAt = time.Now().UTC()
+			recordedAt = now
+		}
+		if maxAge > 0 && now.Sub(recordedAt) > maxAge {
+			continue
+		}
+		if len(locations) == limit {
+			break
 		}
 		loc := &domain.DriverLocation{
 			DriverID:   item.Name,
@@ -178,6 +194,91 @@ func (g *GeoIndex) Nearby(ctx context.Context, lat, lng, radiusMeters float64, l
 	return locations, nil
 }
 
+// positionsBatch caps how many drivers one GEOPOS call resolves.
+const positionsBatch = 500
+
+// Positions returns every driver currently in the index with their coordinates
+// and last location time.
+func (g *GeoIndex) Positions(ctx context.Context) ([]*domain.DriverLocation, error) {
+	if g == nil {
+		return nil, errors.New("geo index not configured")
+	}
+	ids, err := g.client.ZRange(ctx, g.geoKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis list drivers: %w", err)
+	}
+	now := time.Now().UTC()
+	locations := make([]*domain.DriverLocation, 0, len(ids))
+	for start := 0; start < len(ids); start += positionsBatch {
+		batch := ids[start:min(start+positionsBatch, len(ids))]
+		positions, err := g.client.GeoPos(ctx, g.geoKey, batch...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis geopos: %w", err)
+		}
+		meta, err := g.client.HMGet(ctx, g.metaKey, batch...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis driver location times: %w", err)
+		}
+		for idx, pos := range positions {
+			if pos == nil {
+				continue
+			}
+			recordedAt := parseTimestamp(meta[idx])
+			if recordedAt.IsZero() {
+				recordedAt = now
+			}
+			locations = append(locations, &domain.DriverLocation{
+				DriverID:   batch[idx],
+				Latitude:   pos.Latitude,
+				Longitude:  pos.Longitude,
+				RecordedAt: recordedAt,
+			})
+		}
+	}
+	return locations, nil
+}
+
+// Evict drops the driver from nearby searches but keeps their last location
+// time, so Stale still reports them. It reports whether the driver was listed.
+func (g *GeoIndex) Evict(ctx context.Context, driverID string) (bool, error) {
+	if g == nil {
+		return false, errors.New("geo index not configured")
+	}
+	removed, err := g.client.ZRem(ctx, g.geoKey, driverID).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis evict driver %s: %w", driverID, err)
+	}
+	return removed > 0, nil
+}
+
+// Stale lists drivers whose last location was recorded before olderThan,
+// including evicted ones.
+func (g *GeoIndex) Stale(ctx context.Context, olderThan time.Time) ([]*domain.DriverLocation, error) {
+	if g == nil {
+		return nil, errors.New("geo index not configured")
+	}
+	var (
+		stale  []*domain.DriverLocation
+		cursor uint64
+	)
+	for {
+		fields, next, err := g.client.HScan(ctx, g.metaKey, cursor, "", 500).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis scan driver locations: %w", err)
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			recordedAt := parseTimestamp(fields[i+1])
+			if !recordedAt.IsZero() && recordedAt.Before(olderThan) {
+				stale = append(stale, &domain.DriverLocation{DriverID: fields[i], RecordedAt: recordedAt})
+			}
+		}
+		if next == 0 {
+			return stale, nil
+		}
+		cursor = next
+	}
+}
+
 func parseTimestamp(value interface{}) time.Time {
 	switch v := value.(type) {
 	case string:
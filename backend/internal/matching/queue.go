@@ -13,6 +13,8 @@ type TripEvent struct {
 	OriginText string    `json:"originText"`
 	DestText   string    `json:"destText"`
 	Requested  time.Time `json:"requestedAt"`
+	OriginLat  *float64  `json:"originLat,omitempty"`
+	OriginLng  *float64  `json:"originLng,omitempty"`
 }
 
 // TripDispatcher publishes trip events for asynchronous processing.
@@ -74,7 +74,8 @@ func (s *Service) NotifyDriverTripAssigned(ctx context.Context, driver *domain.D
 	return s.send(ctx, driver.UserID, "trip.assigned", "New trip assigned", body, &trip.ID, data)
 }
 
-// NotifyRiderStatusChange alerts the rider when the driver arrives or when the trip completes.
+// NotifyRiderStatusChange alerts the rider when the driver arrives, when the ride reaches
+// the destination, when the trip completes, or when no driver could be found.
 func (s *Service) NotifyRiderStatusChange(ctx context.Context, trip *domain.Trip, status domain.TripStatus) error {
 	if s == nil || trip == nil || trip.RiderID == "" {
 		return nil
@@ -87,9 +88,15 @@ func (s *Service) NotifyRiderStatusChange(ctx context.Context, trip *domain.Trip
 	case domain.TripStatusArriving:
 		title = "Your driver has arrived"
 		body = fmt.Sprintf("Your driver is near %s.", strings.TrimSpace(trip.OriginText))
+	case domain.TripStatusArrived:
+		title = "You have arrived"
+		body = fmt.Sprintf("You are at %s.", strings.TrimSpace(trip.DestText))
 	case domain.TripStatusCompleted:
 		title = "Trip completed"
 		body = fmt.Sprintf("Hope you enjoyed your ride to %s.", strings.TrimSpace(trip.DestText))
+	case domain.TripStatusNoDriverFound:
+		title = "No driver available"
+		body = "We couldn't find a driver nearby. Please try again in a few minutes."
 	default:
 		return nil
 	}
@@ -0,0 +1,229 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultHistorySize is how many messages per trip are kept for replay.
+const DefaultHistorySize = 100
+
+// memorySweepInterval is how often MemoryBackplane looks for idle streams.
+const memorySweepInterval = time.Minute
+
+// ErrReplayGap indicates messages after the requested sequence are no longer
+// buffered, so the caller must resynchronise from a snapshot.
+var ErrReplayGap = errors.New("messages since sequence no longer available")
+
+// Message is one entry in a trip stream. Seq increases by one per message
+// published for the trip.
+type Message struct {
+	Seq     uint64
+	Payload []byte
+}
+
+// MessageHandler receives a message published for a trip.
+type MessageHandler func(msg Message)
+
+// Subscription stops delivery to its handler when closed.
+type Subscription interface {
+	Close() error
+}
+
+// Backplane fans trip messages out to every replica subscribed to the trip,
+// including the publisher itself, and keeps a bounded history per trip so
+// reconnecting clients can catch up.
+type Backplane interface {
+	// NextSeq reserves the next sequence number of the trip stream.
+	NextSeq(ctx context.Context, tripID string) (uint64, error)
+	// LastSeq returns the latest sequence number reserved for the trip.
+	LastSeq(ctx context.Context, tripID string) (uint64, error)
+	Publish(ctx context.Context, tripID string, msg Message) error
+	// Replay returns the buffered messages after since in sequence order, or
+	// ErrReplayGap when some of them have been evicted.
+	Replay(ctx context.Context, tripID string, since uint64) ([]Message, error)
+	Subscribe(ctx context.Context, tripID string, handler MessageHandler) (Subscription, error)
+	Close() error
+}
+
+// MemoryBackplane delivers messages within a single process. It suits a
+// single replica and tests. Like the Redis keys, a trip stream nobody
+// subscribes to is dropped once it has been idle for streamTTL.
+type MemoryBackplane struct {
+	mu          sync.RWMutex
+	historySize int
+	streams     map[string]*memoryStream
+	closed      bool
+	now         func() time.Time
+	swept       time.Time
+}
+
+type memoryStream struct {
+	seq     uint64
+	history []Message
+	subs    map[*memorySubscription]struct{}
+	// touched is when the stream was last published to, subscribed to or left.
+	touched time.Time
+}
+
+var _ Backplane = (*MemoryBackplane)(nil)
+
+// NewMemoryBackplane creates an in-process backplane keeping historySize
+// messages per trip, or DefaultHistorySize when historySize is not positive.
+func NewMemoryBackplane(historySize int) *MemoryBackplane {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &MemoryBackplane{historySize: historySize, streams: make(map[string]*memoryStream), now: time.Now}
+}
+
+func (b *MemoryBackplane) stream(tripID string) *memoryStream {
+	now := b.now()
+	b.evictIdle(now)
+	stream, ok := b.streams[tripID]
+	if !ok {
+		stream = &memoryStream{subs: make(map[*memorySubscription]struct{})}
+		b.streams[tripID] = stream
+	}
+	stream.touched = now
+	return stream
+}
+
+// evictIdle drops the streams without subscribers that have been idle past
+// streamTTL. The caller holds the write lock.
+func (b *MemoryBackplane) evictIdle(now time.Time) {
+	if now.Sub(b.swept) < memorySweepInterval {
+		return
+	}
+	b.swept = now
+	for tripID, stream := range b.streams {
+		if len(stream.subs) == 0 && now.Sub(stream.touched) > streamTTL {
+			delete(b.streams, tripID)
+		}
+	}
+}
+
+// NextSeq reserves the next sequence number of the trip stream.
+func (b *MemoryBackplane) NextSeq(ctx context.Context, tripID string) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, errors.New("backplane closed")
+	}
+	stream := b.stream(tripID)
+	stream.seq++
+	return stream.seq, nil
+}
+
+// LastSeq returns the latest sequence number reserved for the trip.
+func (b *MemoryBackplane) LastSeq(ctx context.Context, tripID string) (uint64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if stream, ok := b.streams[tripID]; ok {
+		return stream.seq, nil
+	}
+	return 0, nil
+}
+
+// Publish records msg in the trip history and hands it to every subscriber
+// of the trip before returning.
+func (b *MemoryBackplane) Publish(ctx context.Context, tripID string, msg Message) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return errors.New("backplane closed")
+	}
+	stream := b.stream(tripID)
+	// Concurrent publishers may finish out of order; keep history sorted.
+	i := len(stream.history)
+	for i > 0 && stream.history[i-1].Seq > msg.Seq {
+		i--
+	}
+	stream.history = slices.Insert(stream.history, i, msg)
+	if len(stream.history) > b.historySize {
+		stream.history = append([]Message(nil), stream.history[len(stream.history)-b.historySize:]...)
+	}
+	handlers := make([]MessageHandler, 0, len(stream.subs))
+	for sub := range stream.subs {
+		handlers = append(handlers, sub.handler)
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+// Replay returns the buffered messages after since.
+func (b *MemoryBackplane) Replay(ctx context.Context, tripID string, since uint64) ([]Message, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var last uint64
+	var history []Message
+	if stream, ok := b.streams[tripID]; ok {
+		last, history = stream.seq, stream.history
+	}
+	return replayFrom(history, since, last)
+}
+
+// Subscribe registers handler for messages published to the trip.
+func (b *MemoryBackplane) Subscribe(ctx context.Context, tripID string, handler MessageHandler) (Subscription, error) {
+	if handler == nil {
+		return nil, errors.New("handler required")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errors.New("backplane closed")
+	}
+	sub := &memorySubscription{backplane: b, tripID: tripID, handler: handler}
+	b.stream(tripID).subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close drops all subscriptions and history.
+func (b *MemoryBackplane) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.streams = make(map[string]*memoryStream)
+	return nil
+}
+
+type memorySubscription struct {
+	backplane *MemoryBackplane
+	tripID    string
+	handler   MessageHandler
+}
+
+func (s *memorySubscription) Close() error {
+	s.backplane.mu.Lock()
+	defer s.backplane.mu.Unlock()
+	if stream, ok := s.backplane.streams[s.tripID]; ok {
+		delete(stream.subs, s)
+		stream.touched = s.backplane.now()
+	}
+	return nil
+}
+
+// replayFrom picks the messages after since out of a history ordered by
+// sequence, whose newest reserved sequence is last.
+func replayFrom(history []Message, since, last uint64) ([]Message, error) {
+	if since == last {
+		return nil, nil
+	}
+	if since > last || len(history) == 0 || history[0].Seq > since+1 {
+		return nil, ErrReplayGap
+	}
+	missed := make([]Message, 0, len(history))
+	for _, msg := range history {
+		if msg.Seq > since {
+			missed = append(missed, msg)
+		}
+	}
+	return missed, nil
+}
@@ -0,0 +1,130 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func publishNext(t *testing.T, backplane Backplane, tripID, payload string) uint64 {
+	t.Helper()
+	seq, err := backplane.NextSeq(context.Background(), tripID)
+	require.NoError(t, err)
+	require.NoError(t, backplane.Publish(context.Background(), tripID, Message{Seq: seq, Payload: []byte(payload)}))
+	return seq
+}
+
+func TestMemoryBackplaneDeliversToTripSubscribers(t *testing.T) {
+	backplane := NewMemoryBackplane(DefaultHistorySize)
+	defer backplane.Close()
+
+	var got []Message
+	sub, err := backplane.Subscribe(context.Background(), "trip-1", func(msg Message) {
+		got = append(got, msg)
+	})
+	require.NoError(t, err)
+	_, err = backplane.Subscribe(context.Background(), "trip-2", func(msg Message) {
+		t.Fatalf("unexpected message for trip-2: %s", msg.Payload)
+	})
+	require.NoError(t, err)
+
+	publishNext(t, backplane, "trip-1", "hello")
+	require.Equal(t, []Message{{Seq: 1, Payload: []byte("hello")}}, got)
+
+	require.NoError(t, sub.Close())
+	publishNext(t, backplane, "trip-1", "again")
+	require.Len(t, got, 1)
+}
+
+func TestMemoryBackplaneReplaysBufferedMessages(t *testing.T) {
+	backplane := NewMemoryBackplane(2)
+	defer backplane.Close()
+
+	for _, payload := range []string{"one", "two", "three"} {
+		publishNext(t, backplane, "trip-1", payload)
+	}
+
+	missed, err := backplane.Replay(context.Background(), "trip-1", 1)
+	require.NoError(t, err)
+	require.Equal(t, []Message{{Seq: 2, Payload: []byte("two")}, {Seq: 3, Payload: []byte("three")}}, missed)
+
+	missed, err = backplane.Replay(context.Background(), "trip-1", 3)
+	require.NoError(t, err)
+	require.Empty(t, missed)
+
+	_, err = backplane.Replay(context.Background(), "trip-1", 0)
+	require.ErrorIs(t, err, ErrReplayGap)
+	_, err = backplane.Replay(context.Background(), "trip-1", 7)
+	require.ErrorIs(t, err, ErrReplayGap)
+}
+
+func TestMemoryBackplaneEvictsIdleStreams(t *testing.T) {
+	backplane := NewMemoryBackplane(DefaultHistorySize)
+	defer backplane.Close()
+	now := time.Now()
+	backplane.now = func() time.Time { return now }
+
+	publishNext(t, backplane, "idle", "one")
+	sub, err := backplane.Subscribe(context.Background(), "watched", func(Message) {})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	now = now.Add(streamTTL + time.Minute)
+	publishNext(t, backplane, "other", "one")
+
+	require.NotContains(t, backplane.streams, "idle")
+	require.Contains(t, backplane.streams, "watched", "streams with subscribers are kept")
+	last, err := backplane.LastSeq(context.Background(), "idle")
+	require.NoError(t, err)
+	require.Zero(t, last)
+}
+
+func TestRedisBackplaneFansOutAcrossClients(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) {
+			t.Skip("sockets not permitted in this environment")
+		}
+		require.NoError(t, err)
+	}
+	defer server.Close()
+
+	// Two backplanes stand in for two trip-service replicas.
+	replicaA, err := NewRedisBackplane(server.Addr(), "", 0, "", 2)
+	require.NoError(t, err)
+	defer replicaA.Close()
+	replicaB, err := NewRedisBackplane(server.Addr(), "", 0, "", 2)
+	require.NoError(t, err)
+	defer replicaB.Close()
+
+	received := make(chan Message, 1)
+	sub, err := replicaB.Subscribe(context.Background(), "trip-1", func(msg Message) {
+		received <- msg
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	publishNext(t, replicaA, "trip-1", `{"type":"status"}`)
+
+	select {
+	case msg := <-received:
+		require.Equal(t, uint64(1), msg.Seq)
+		require.JSONEq(t, `{"type":"status"}`, string(msg.Payload))
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for backplane message")
+	}
+
+	publishNext(t, replicaB, "trip-1", "two")
+	publishNext(t, replicaA, "trip-1", "three")
+
+	missed, err := replicaB.Replay(context.Background(), "trip-1", 1)
+	require.NoError(t, err)
+	require.Equal(t, []Message{{Seq: 2, Payload: []byte("two")}, {Seq: 3, Payload: []byte("three")}}, missed)
+	_, err = replicaA.Replay(context.Background(), "trip-1", 0)
+	require.ErrorIs(t, err, ErrReplayGap)
+}
@@ -0,0 +1,180 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultChannelPrefix = "trip:ws:"
+	// streamTTL bounds how long an idle trip's sequence and history are kept.
+	streamTTL = 24 * time.Hour
+)
+
+// RedisBackplane fans trip messages out across replicas with Redis pub/sub,
+// one channel per trip. Sequence numbers come from INCR and the replay
+// history is a sorted set scored by sequence, so any replica can serve a
+// reconnecting client.
+type RedisBackplane struct {
+	client      *redis.Client
+	prefix      string
+	historySize int
+}
+
+var _ Backplane = (*RedisBackplane)(nil)
+
+// NewRedisBackplane connects to Redis. Keys and channels are named
+// prefix+tripID; historySize messages per trip are kept for replay.
+func NewRedisBackplane(addr, password string, db int, prefix string, historySize int) (*RedisBackplane, error) {
+	if addr == "" {
+		return nil, errors.New("redis address required")
+	}
+	if prefix == "" {
+		prefix = defaultChannelPrefix
+	}
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping redis: %w", err)
+	}
+	return &RedisBackplane{client: client, prefix: prefix, historySize: historySize}, nil
+}
+
+// NextSeq reserves the next sequence number of the trip stream.
+func (b *RedisBackplane) NextSeq(ctx context.Context, tripID string) (uint64, error) {
+	key := b.seqKey(tripID)
+	var incr *redis.IntCmd
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, streamTTL)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(incr.Val()), nil
+}
+
+// LastSeq returns the latest sequence number reserved for the trip.
+func (b *RedisBackplane) LastSeq(ctx context.Context, tripID string) (uint64, error) {
+	seq, err := b.client.Get(ctx, b.seqKey(tripID)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return seq, err
+}
+
+// Publish stores msg in the trip history and sends it to every replica
+// subscribed to the trip.
+func (b *RedisBackplane) Publish(ctx context.Context, tripID string, msg Message) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	key := b.historyKey(tripID)
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(msg.Seq), Member: msg.Payload})
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-b.historySize-1))
+		pipe.Expire(ctx, key, streamTTL)
+		pipe.Publish(ctx, b.channel(tripID), encodeMessage(msg))
+		return nil
+	})
+	return err
+}
+
+// Replay returns the buffered messages after since.
+func (b *RedisBackplane) Replay(ctx context.Context, tripID string, since uint64) ([]Message, error) {
+	last, err := b.LastSeq(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := b.client.ZRangeWithScores(ctx, b.historyKey(tripID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	history := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		payload, _ := entry.Member.(string)
+		history = append(history, Message{Seq: uint64(entry.Score), Payload: []byte(payload)})
+	}
+	return replayFrom(history, since, last)
+}
+
+// Subscribe listens on the trip's channel until the subscription is closed.
+// It returns once Redis has confirmed the subscription, so messages published
+// afterwards are not missed.
+func (b *RedisBackplane) Subscribe(ctx context.Context, tripID string, handler MessageHandler) (Subscription, error) {
+	if handler == nil {
+		return nil, errors.New("handler required")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	pubsub := b.client.Subscribe(ctx, b.channel(tripID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribe trip %s: %w", tripID, err)
+	}
+	messages := pubsub.Channel()
+	go func() {
+		for raw := range messages {
+			msg, err := decodeMessage(raw.Payload)
+			if err != nil {
+				log.Printf("decode backplane message for trip %s: %v", tripID, err)
+				continue
+			}
+			handler(msg)
+		}
+	}()
+	return pubsub, nil
+}
+
+// Close shuts down the Redis client.
+func (b *RedisBackplane) Close() error {
+	if b == nil || b.client == nil {
+		return nil
+	}
+	return b.client.Close()
+}
+
+func (b *RedisBackplane) channel(tripID string) string {
+	return b.prefix + tripID
+}
+
+func (b *RedisBackplane) seqKey(tripID string) string {
+	return b.prefix + "seq:" + tripID
+}
+
+func (b *RedisBackplane) historyKey(tripID string) string {
+	return b.prefix + "history:" + tripID
+}
+
+// encodeMessage frames a message for pub/sub as "<seq>\n<payload>".
+func encodeMessage(msg Message) []byte {
+	framed := strconv.AppendUint(nil, msg.Seq, 10)
+	framed = append(framed, '\n')
+	return append(framed, msg.Payload...)
+}
+
+func decodeMessage(raw string) (Message, error) {
+	head, payload, ok := bytes.Cut([]byte(raw), []byte{'\n'})
+	if !ok {
+		return Message{}, errors.New("missing sequence header")
+	}
+	seq, err := strconv.ParseUint(string(head), 10, 64)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Seq: seq, Payload: payload}, nil
+}
@@ -0,0 +1,172 @@
+package routing
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultCacheSize   = 10000
+	defaultCachePrefix = "routing:"
+)
+
+var (
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "uitgo",
+		Subsystem: "routing",
+		Name:      "cache_hits_total",
+		Help:      "Route lookups answered from cache, by tier.",
+	}, []string{"tier"})
+	cacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "uitgo",
+		Subsystem: "routing",
+		Name:      "cache_misses_total",
+		Help:      "Route lookups the cache could not answer, by tier.",
+	}, []string{"tier"})
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits, cacheMisses)
+}
+
+type cachedRoute struct {
+	route     *Route
+	expiresAt time.Time
+	// partial entries come from table lookups and only know the distance and
+	// duration, so they cannot answer route lookups.
+	partial bool
+}
+
+// routeCache is a size-bounded LRU of routes. Expired entries are dropped when
+// they are read or reach the back of the list.
+type routeCache struct {
+	size  int
+	order *list.List // most recently used first
+	items map[string]*list.Element
+}
+
+type routeCacheItem struct {
+	key   string
+	entry cachedRoute
+}
+
+func newRouteCache(size int) *routeCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	return &routeCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *routeCache) get(key string, now time.Time) (cachedRoute, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		return cachedRoute{}, false
+	}
+	item := element.Value.(*routeCacheItem)
+	if now.After(item.entry.expiresAt) {
+		c.remove(element)
+		return cachedRoute{}, false
+	}
+	c.order.MoveToFront(element)
+	return item.entry, true
+}
+
+func (c *routeCache) set(key string, entry cachedRoute, now time.Time) {
+	if element, ok := c.items[key]; ok {
+		element.Value.(*routeCacheItem).entry = entry
+		c.order.MoveToFront(element)
+	} else {
+		c.items[key] = c.order.PushFront(&routeCacheItem{key: key, entry: entry})
+	}
+	for back := c.order.Back(); back != nil; back = c.order.Back() {
+		if c.order.Len() <= c.size && !now.After(back.Value.(*routeCacheItem).entry.expiresAt) {
+			break
+		}
+		c.remove(back)
+	}
+}
+
+func (c *routeCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*routeCacheItem).key)
+}
+
+func (c *routeCache) len() int {
+	return c.order.Len()
+}
+
+// RedisCache is a second route cache tier shared by every replica, so a route
+// looked up by one is a cache hit for the others.
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+type redisCacheEntry struct {
+	Route   *Route `json:"route"`
+	Partial bool   `json:"partial,omitempty"`
+}
+
+// NewRedisCache connects the shared route cache. Keys are namespaced by prefix.
+func NewRedisCache(addr, password string, db int, prefix string) (*RedisCache, error) {
+	if addr == "" {
+		return nil, errors.New("redis address required")
+	}
+	if prefix == "" {
+		prefix = defaultCachePrefix
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping redis: %w", err)
+	}
+	return &RedisCache{client: client, prefix: prefix}, nil
+}
+
+// Close releases the Redis connection.
+func (r *RedisCache) Close() error {
+	return r.client.Close()
+}
+
+// get treats Redis errors as misses: a shared cache outage only costs lookups.
+func (r *RedisCache) get(ctx context.Context, key string) (cachedRoute, bool) {
+	payload, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("routing cache read failed: %v", err)
+		}
+		return cachedRoute{}, false
+	}
+	var stored redisCacheEntry
+	if err := json.Unmarshal(payload, &stored); err != nil || stored.Route == nil {
+		return cachedRoute{}, false
+	}
+	return cachedRoute{route: stored.Route, partial: stored.Partial}, true
+}
+
+// set never lets a partial entry replace a full route cached by another
+// replica.
+func (r *RedisCache) set(ctx context.Context, key string, entry cachedRoute, ttl time.Duration) {
+	payload, err := json.Marshal(redisCacheEntry{Route: entry.route, Partial: entry.partial})
+	if err != nil {
+		return
+	}
+	if entry.partial {
+		err = r.client.SetNX(ctx, r.prefix+key, payload, ttl).Err()
+	} else {
+		err = r.client.Set(ctx, r.prefix+key, payload, ttl).Err()
+	}
+	if err != nil {
+		log.Printf("routing cache write failed: %v", err)
+	}
+}
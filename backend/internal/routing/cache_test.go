@@ -0,0 +1,137 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEngine answers every lookup with the same route once release is
+// closed, or with err when set, counting the lookups that reach it.
+type countingEngine struct {
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (e *countingEngine) route(ctx context.Context, waypoints []Coordinate) (*Route, error) {
+	e.calls.Add(1)
+	if e.release != nil {
+		<-e.release
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &Route{Distance: 1300, Duration: 180, Legs: []Leg{{Distance: 1300, Duration: 180}}}, nil
+}
+
+func TestRouteCacheEvictsLeastRecentlyUsedAndExpired(t *testing.T) {
+	now := time.Now()
+	fresh := cachedRoute{route: &Route{}, expiresAt: now.Add(time.Minute)}
+	cache := newRouteCache(2)
+
+	cache.set("a", fresh, now)
+	cache.set("b", fresh, now)
+	_, ok := cache.get("a", now)
+	require.True(t, ok)
+	cache.set("c", fresh, now)
+	require.Equal(t, 2, cache.len())
+	_, ok = cache.get("b", now)
+	require.False(t, ok, "b was least recently used")
+
+	later := now.Add(2 * time.Minute)
+	cache.set("d", cachedRoute{route: &Route{}, expiresAt: later.Add(time.Minute)}, later)
+	require.Equal(t, 1, cache.len(), "expired entries are dropped")
+	_, ok = cache.get("d", later)
+	require.True(t, ok)
+}
+
+func TestConcurrentLookupsShareOneRequest(t *testing.T) {
+	backend := &countingEngine{release: make(chan struct{})}
+	client := newClient(backend, time.Minute, 0, nil)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			route, err := client.GetRouteVia(context.Background(), contractWaypoints)
+			require.NoError(t, err)
+			require.Equal(t, 1300.0, route.Distance)
+		}()
+	}
+	require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+	require.EqualValues(t, 1, backend.calls.Load())
+}
+
+func TestRedisCacheSharesRoutesBetweenReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	newReplica := func() (*Client, *countingEngine) {
+		shared, err := NewRedisCache(server.Addr(), "", 0, "")
+		require.NoError(t, err)
+		t.Cleanup(func() { shared.Close() })
+		backend := &countingEngine{}
+		return newClient(backend, time.Minute, 0, shared), backend
+	}
+	first, firstBackend := newReplica()
+	second, secondBackend := newReplica()
+	ctx := context.Background()
+	hits := testutil.ToFloat64(cacheHits.WithLabelValues("redis"))
+
+	_, err := first.GetRouteVia(ctx, contractWaypoints)
+	require.NoError(t, err)
+	route, err := second.GetRouteVia(ctx, contractWaypoints)
+	require.NoError(t, err)
+	require.Equal(t, 1300.0, route.Distance)
+	require.EqualValues(t, 1, firstBackend.calls.Load())
+	require.Zero(t, secondBackend.calls.Load())
+	require.Equal(t, hits+1, testutil.ToFloat64(cacheHits.WithLabelValues("redis")))
+
+	// The second replica now answers from memory.
+	memoryHits := testutil.ToFloat64(cacheHits.WithLabelValues("memory"))
+	_, err = second.GetRouteVia(ctx, contractWaypoints)
+	require.NoError(t, err)
+	require.Equal(t, memoryHits+1, testutil.ToFloat64(cacheHits.WithLabelValues("memory")))
+
+	// Table pairs never replace a shared full route.
+	third, _ := newReplica()
+	third.savePair(ctx, cacheKey(contractWaypoints...), 1, 1)
+	fourth, fourthBackend := newReplica()
+	route, err = fourth.GetRouteVia(ctx, contractWaypoints)
+	require.NoError(t, err)
+	require.Equal(t, 1300.0, route.Distance)
+	require.Zero(t, fourthBackend.calls.Load())
+}
+
+func TestFallbackRoutesAreNotShared(t *testing.T) {
+	server := miniredis.RunT(t)
+	shared, err := NewRedisCache(server.Addr(), "", 0, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { shared.Close() })
+	backend := &countingEngine{err: errors.New("connection refused")}
+	client := newClient(backend, time.Hour, 0, shared)
+	ctx := context.Background()
+
+	route, err := client.GetRouteVia(ctx, contractWaypoints)
+	require.NoError(t, err)
+	require.NotEqual(t, 1300.0, route.Distance)
+	_, ok := shared.get(ctx, cacheKey(contractWaypoints...))
+	require.False(t, ok, "fallbacks stay out of the shared cache")
+
+	// The fallback is reused briefly, then the backend is asked again.
+	_, err = client.GetRouteVia(ctx, contractWaypoints)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, backend.calls.Load())
+	_, ok = client.cache.get(cacheKey(contractWaypoints...), time.Now().Add(fallbackCacheTTL+time.Second))
+	require.False(t, ok)
+}
@@ -0,0 +1,220 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const defaultRequestTimeout = 8 * time.Second
+
+// fallbackCacheTTL bounds how long a straight-line fallback route is reused.
+// Fallbacks stay in this replica's memory so the real route is fetched soon
+// after the backend recovers.
+const fallbackCacheTTL = 30 * time.Second
+
+// Client looks routes up in a routing backend and caches them in a bounded
+// LRU, optionally backed by a cache shared between replicas. Concurrent
+// lookups of the same route share one backend request. When the backend is
+// unavailable it falls back to a straight-line estimate.
+type Client struct {
+	engine   engine
+	fallback *offlineEngine
+	cacheTTL time.Duration
+	shared   *RedisCache
+	lookups  singleflight.Group
+
+	mu    sync.Mutex
+	cache *routeCache
+}
+
+// NewClient creates an OSRM routing client with optional caching.
+func NewClient(baseURL string, timeout, cacheTTL time.Duration) *Client {
+	return newClient(newOSRM(baseURL, "", timeout), cacheTTL, 0, nil)
+}
+
+func newClient(backend engine, cacheTTL time.Duration, cacheSize int, shared *RedisCache) *Client {
+	if cacheTTL < 0 {
+		cacheTTL = 0
+	}
+	return &Client{
+		engine:   backend,
+		fallback: newOffline(1, fallbackAverageSpeedMS),
+		cacheTTL: cacheTTL,
+		shared:   shared,
+		cache:    newRouteCache(cacheSize),
+	}
+}
+
+// GetRoute fetches a driving route between origin and destination.
+func (c *Client) GetRoute(ctx context.Context, origin, destination Coordinate) (*Route, error) {
+	return c.GetRouteVia(ctx, []Coordinate{origin, destination})
+}
+
+// GetRouteVia fetches a driving route visiting the waypoints in order, from
+// the first to the last. The route has one leg per consecutive pair.
+func (c *Client) GetRouteVia(ctx context.Context, waypoints []Coordinate) (*Route, error) {
+	if len(waypoints) < 2 {
+		return nil, errors.New("route needs at least two waypoints")
+	}
+	key := cacheKey(waypoints...)
+	if cached, ok := c.fromCache(ctx, key); ok && !cached.partial {
+		return cached.route, nil
+	}
+
+	// The lookup is shared by every caller waiting on it, so one caller giving
+	// up must not fail the others.
+	shared := context.WithoutCancel(ctx)
+	result, err, _ := c.lookups.Do(key, func() (any, error) {
+		route, err := c.engine.route(shared, waypoints)
+		if err != nil {
+			if errors.Is(err, ErrRouteNotFound) {
+				return nil, err
+			}
+			log.Printf("routing upstream unavailable, using fallback: %v", err)
+			if route, err = c.fallback.route(shared, waypoints); err != nil {
+				return nil, err
+			}
+			if c.cacheTTL > 0 {
+				c.storeMemory(key, cachedRoute{route: route}, min(c.cacheTTL, fallbackCacheTTL))
+			}
+			return route, nil
+		}
+		c.saveCache(shared, key, route)
+		return route, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Route), nil
+}
+
+// fromCache looks key up in memory, then in the shared tier. Shared hits are
+// kept in memory for the next lookup.
+func (c *Client) fromCache(ctx context.Context, key string) (cachedRoute, bool) {
+	if c.cacheTTL == 0 {
+		return cachedRoute{}, false
+	}
+	c.mu.Lock()
+	entry, ok := c.cache.get(key, time.Now())
+	c.mu.Unlock()
+	if ok {
+		cacheHits.WithLabelValues("memory").Inc()
+		return entry, true
+	}
+	cacheMisses.WithLabelValues("memory").Inc()
+	if c.shared == nil {
+		return cachedRoute{}, false
+	}
+	if entry, ok = c.shared.get(ctx, key); !ok {
+		cacheMisses.WithLabelValues("redis").Inc()
+		return cachedRoute{}, false
+	}
+	cacheHits.WithLabelValues("redis").Inc()
+	c.storeMemory(key, entry, c.cacheTTL)
+	return entry, true
+}
+
+func (c *Client) saveCache(ctx context.Context, key string, route *Route) {
+	c.storeCache(ctx, key, cachedRoute{route: route})
+}
+
+// savePair caches the distance and duration of a pair unless a full route is
+// already cached for it.
+func (c *Client) savePair(ctx context.Context, key string, distance, duration float64) {
+	if c.cacheTTL == 0 {
+		return
+	}
+	c.mu.Lock()
+	cached, ok := c.cache.get(key, time.Now())
+	c.mu.Unlock()
+	if ok && !cached.partial {
+		return
+	}
+	route := &Route{Distance: distance, Duration: duration, Legs: []Leg{{Distance: distance, Duration: duration}}}
+	c.storeCache(ctx, key, cachedRoute{route: route, partial: true})
+}
+
+func (c *Client) storeCache(ctx context.Context, key string, entry cachedRoute) {
+	if c.cacheTTL == 0 || entry.route == nil {
+		return
+	}
+	c.storeMemory(key, entry, c.cacheTTL)
+	if c.shared != nil {
+		c.shared.set(ctx, key, entry, c.cacheTTL)
+	}
+}
+
+func (c *Client) storeMemory(key string, entry cachedRoute, ttl time.Duration) {
+	now := time.Now()
+	entry.expiresAt = now.Add(ttl)
+	c.mu.Lock()
+	c.cache.set(key, entry, now)
+	c.mu.Unlock()
+}
+
+func cacheKey(waypoints ...Coordinate) string {
+	parts := make([]string, 0, len(waypoints))
+	for _, point := range waypoints {
+		parts = append(parts, fmt.Sprintf("%.5f,%.5f", point.Lat, point.Lng))
+	}
+	return strings.Join(parts, "|")
+}
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+func normalizeBaseURL(baseURL, fallback string) string {
+	baseURL = strings.TrimSpace(baseURL)
+	if baseURL == "" {
+		baseURL = fallback
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// fetchJSON sends req and returns the response status. The body is decoded
+// into out only for the given statuses, which backends use to explain errors.
+func fetchJSON(client *http.Client, req *http.Request, out any, decode ...int) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("routing request: %w", err)
+	}
+	defer resp.Body.Close()
+	if !slices.Contains(decode, resp.StatusCode) {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("decode routing response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+func normalizeLocation(location []float64) []float64 {
+	if len(location) < 2 {
+		return nil
+	}
+	return []float64{location[0], location[1]}
+}
+
+func prettify(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "_", " ")
+	if text == "" {
+		return ""
+	}
+	lower := strings.ToLower(text)
+	return strings.ToUpper(lower[:1]) + lower[1:]
+}
@@ -0,0 +1,319 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The contract route runs from contractWaypoints[0] through [1] to [2] along
+// contractShape, with the leg totals in contractLegs.
+var (
+	contractWaypoints = []Coordinate{{Lat: 10.87, Lng: 106.80}, {Lat: 10.876, Lng: 106.79}, {Lat: 10.88, Lng: 106.78}}
+	contractShape     = [][]float64{{106.80, 10.87}, {106.795, 10.873}, {106.79, 10.876}, {106.785, 10.878}, {106.78, 10.88}}
+	contractLegs      = []Leg{{Distance: 1300, Duration: 180}, {Distance: 1500, Duration: 200}}
+)
+
+// contractFixture fakes one backend. Backends without a server make no
+// requests and are only held to the parts of the contract that apply.
+type contractFixture struct {
+	// route checks the request asks for contractWaypoints in order and answers
+	// with the contract route.
+	route func(t *testing.T, w http.ResponseWriter, r *http.Request)
+	// noRoute answers as the backend does when the points cannot be connected.
+	noRoute http.HandlerFunc
+}
+
+var contractFixtures = map[string]contractFixture{
+	"osrm":        {route: osrmRouteFixture, noRoute: osrmNoRouteFixture},
+	"valhalla":    {route: valhallaRouteFixture, noRoute: valhallaNoRouteFixture},
+	"graphhopper": {route: graphHopperRouteFixture, noRoute: graphHopperNoRouteFixture},
+	"offline":     {},
+}
+
+func TestProvidersHonourTheRoutingContract(t *testing.T) {
+	for _, backend := range Backends() {
+		fixture, ok := contractFixtures[backend]
+		require.True(t, ok, "backend %q has no contract fixture", backend)
+		t.Run(backend, func(t *testing.T) {
+			runContract(t, backend, fixture)
+		})
+	}
+}
+
+func runContract(t *testing.T, backend string, fixture contractFixture) {
+	var (
+		mode     atomic.Value
+		requests atomic.Int32
+	)
+	mode.Store("route")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch mode.Load() {
+		case "route":
+			fixture.route(t, w, r)
+		case "no-route":
+			fixture.noRoute(w, r)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	provider, err := NewProvider(ProviderOptions{Backend: backend, BaseURL: server.URL, Timeout: time.Second, CacheTTL: time.Minute})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("normalizes the route", func(t *testing.T) {
+		route, err := provider.GetRouteVia(ctx, contractWaypoints)
+		require.NoError(t, err)
+		requireContractRoute(t, route)
+		if fixture.route != nil {
+			require.InDeltaSlice(t, legDistances(contractLegs), legDistances(route.Legs), 0.5)
+		}
+	})
+
+	t.Run("rejects a single waypoint", func(t *testing.T) {
+		_, err := provider.GetRouteVia(ctx, contractWaypoints[:1])
+		require.Error(t, err)
+	})
+
+	if fixture.route == nil {
+		t.Run("reports no route", func(t *testing.T) {
+			_, err := provider.GetRoute(ctx, contractWaypoints[0], contractWaypoints[0])
+			require.ErrorIs(t, err, ErrRouteNotFound)
+		})
+		return
+	}
+
+	t.Run("serves repeated lookups from the cache", func(t *testing.T) {
+		before := requests.Load()
+		_, err := provider.GetRouteVia(ctx, contractWaypoints)
+		require.NoError(t, err)
+		require.Equal(t, before, requests.Load())
+	})
+
+	t.Run("reports no route", func(t *testing.T) {
+		mode.Store("no-route")
+		_, err := provider.GetRoute(ctx, contractWaypoints[0], contractWaypoints[2])
+		require.ErrorIs(t, err, ErrRouteNotFound)
+	})
+
+	t.Run("falls back while the backend is down", func(t *testing.T) {
+		mode.Store("down")
+		route, err := provider.GetRoute(ctx, contractWaypoints[1], contractWaypoints[2])
+		require.NoError(t, err)
+		require.InDelta(t, haversineDistance(contractWaypoints[1], contractWaypoints[2]), route.Distance, 0.5)
+		require.Len(t, route.Legs, 1)
+	})
+}
+
+func requireContractRoute(t *testing.T, route *Route) {
+	t.Helper()
+	require.Greater(t, route.Distance, 0.0)
+	require.Greater(t, route.Duration, 0.0)
+	require.Len(t, route.Legs, len(contractWaypoints)-1)
+	var distance, duration float64
+	for _, leg := range route.Legs {
+		require.Greater(t, leg.Distance, 0.0)
+		distance += leg.Distance
+		duration += leg.Duration
+	}
+	require.InDelta(t, route.Distance, distance, 0.5)
+	require.InDelta(t, route.Duration, duration, 0.5)
+
+	require.NotEmpty(t, route.Coordinates)
+	first, last := contractWaypoints[0], contractWaypoints[len(contractWaypoints)-1]
+	require.InDeltaSlice(t, []float64{first.Lng, first.Lat}, route.Coordinates[0], 1e-5)
+	require.InDeltaSlice(t, []float64{last.Lng, last.Lat}, route.Coordinates[len(route.Coordinates)-1], 1e-5)
+	for _, pair := range route.Coordinates {
+		require.Len(t, pair, 2)
+	}
+
+	require.NotEmpty(t, route.Steps)
+	for _, step := range route.Steps {
+		require.NotEmpty(t, step.Instruction)
+		require.Len(t, step.Location, 2)
+		require.InDelta(t, 106.79, step.Location[0], 0.02, "location is [lng, lat]")
+		require.InDelta(t, 10.875, step.Location[1], 0.02, "location is [lng, lat]")
+	}
+}
+
+func legDistances(legs []Leg) []float64 {
+	distances := make([]float64, 0, len(legs))
+	for _, leg := range legs {
+		distances = append(distances, leg.Distance)
+	}
+	return distances
+}
+
+func requireContractWaypoints(t *testing.T, got []Coordinate) {
+	t.Helper()
+	require.Len(t, got, len(contractWaypoints))
+	for i, point := range got {
+		require.InDelta(t, contractWaypoints[i].Lat, point.Lat, 1e-6)
+		require.InDelta(t, contractWaypoints[i].Lng, point.Lng, 1e-6)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func osrmRouteFixture(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/route/v1/driving/")
+	var got []Coordinate
+	for _, pair := range strings.Split(path, ";") {
+		var lng, lat float64
+		_, err := fmt.Sscanf(pair, "%f,%f", &lng, &lat)
+		require.NoError(t, err)
+		got = append(got, Coordinate{Lat: lat, Lng: lng})
+	}
+	requireContractWaypoints(t, got)
+
+	step := func(name, kind string, location []float64, leg Leg) map[string]any {
+		return map[string]any{
+			"name": name, "distance": leg.Distance, "duration": leg.Duration,
+			"maneuver": map[string]any{"type": kind, "location": location},
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"code": "Ok",
+		"routes": []any{map[string]any{
+			"distance": 2800, "duration": 380,
+			"geometry": map[string]any{"coordinates": contractShape},
+			"legs": []any{
+				map[string]any{"distance": 1300, "duration": 180, "steps": []any{
+					step("Le Van Viet", "depart", contractShape[0], contractLegs[0]),
+					step("", "arrive", contractShape[2], Leg{}),
+				}},
+				map[string]any{"distance": 1500, "duration": 200, "steps": []any{
+					step("Xa Lo Ha Noi", "depart", contractShape[2], contractLegs[1]),
+					step("", "arrive", contractShape[4], Leg{}),
+				}},
+			},
+		}},
+	})
+}
+
+func osrmNoRouteFixture(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{"code": "NoRoute", "message": "Impossible route between points"})
+}
+
+func valhallaRouteFixture(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	require.Equal(t, http.MethodPost, r.Method)
+	require.Equal(t, "/route", r.URL.Path)
+	var body valhallaRequest
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	var got []Coordinate
+	for _, location := range body.Locations {
+		got = append(got, Coordinate{Lat: location.Lat, Lng: location.Lon})
+	}
+	requireContractWaypoints(t, got)
+
+	maneuver := func(instruction string, begin int, leg Leg) map[string]any {
+		return map[string]any{
+			"instruction": instruction, "street_names": []string{"Le Van Viet"},
+			"length": leg.Distance / 1000, "time": leg.Duration, "begin_shape_index": begin,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"trip": map[string]any{
+		"summary": map[string]any{"length": 2.8, "time": 380},
+		"legs": []any{
+			map[string]any{
+				"summary":   map[string]any{"length": 1.3, "time": 180},
+				"shape":     encodePolyline(contractShape[:3], 6),
+				"maneuvers": []any{maneuver("Drive west.", 0, contractLegs[0]), maneuver("You have arrived at your stop.", 2, Leg{})},
+			},
+			map[string]any{
+				"summary":   map[string]any{"length": 1.5, "time": 200},
+				"shape":     encodePolyline(contractShape[2:], 6),
+				"maneuvers": []any{maneuver("Drive west.", 0, contractLegs[1]), maneuver("You have arrived at your destination.", 2, Leg{})},
+			},
+		},
+	}})
+}
+
+func valhallaNoRouteFixture(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{"error_code": 442, "error": "No path could be found for input", "status_code": 400})
+}
+
+func graphHopperRouteFixture(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	require.Equal(t, "/route", r.URL.Path)
+	var got []Coordinate
+	for _, point := range r.URL.Query()["point"] {
+		parts := strings.Split(point, ",")
+		require.Len(t, parts, 2)
+		lat, err := strconv.ParseFloat(parts[0], 64)
+		require.NoError(t, err)
+		lng, err := strconv.ParseFloat(parts[1], 64)
+		require.NoError(t, err)
+		got = append(got, Coordinate{Lat: lat, Lng: lng})
+	}
+	requireContractWaypoints(t, got)
+
+	instruction := func(text string, sign, from int, distance, seconds float64) map[string]any {
+		return map[string]any{
+			"text": text, "street_name": "Le Van Viet", "sign": sign,
+			"interval": []int{from, from + 1}, "distance": distance, "time": seconds * 1000,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"paths": []any{map[string]any{
+		"distance": 2800, "time": 380000,
+		"points": map[string]any{"coordinates": contractShape},
+		"instructions": []any{
+			instruction("Continue onto Le Van Viet", 0, 0, 700, 100),
+			instruction("Waypoint 1", graphHopperViaReached, 1, 600, 80),
+			instruction("Keep left", -7, 2, 1500, 200),
+			instruction("Arrive at destination", graphHopperFinish, 4, 0, 0),
+		},
+	}}})
+}
+
+func graphHopperNoRouteFixture(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"message": "Connection between locations not found",
+		"hints":   []any{map[string]any{"details": "com.graphhopper.util.exceptions.ConnectionNotFoundException"}},
+	})
+}
+
+func encodePolyline(coords [][]float64, precision int) string {
+	factor := 1.0
+	for i := 0; i < precision; i++ {
+		factor *= 10
+	}
+	var (
+		out              strings.Builder
+		prevLat, prevLng int
+	)
+	encode := func(value int) {
+		value <<= 1
+		if value < 0 {
+			value = ^value
+		}
+		for value >= 0x20 {
+			out.WriteByte(byte((0x20 | (value & 0x1f)) + 63))
+			value >>= 5
+		}
+		out.WriteByte(byte(value + 63))
+	}
+	for _, pair := range coords {
+		lat := int(math.Round(pair[1] * factor))
+		lng := int(math.Round(pair[0] * factor))
+		encode(lat - prevLat)
+		encode(lng - prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return out.String()
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"context"
+
+	"uitgo/backend/internal/domain"
+)
+
+var (
+	_ domain.RouteEstimator         = (*Client)(nil)
+	_ domain.WaypointRouteEstimator = (*Client)(nil)
+	_ domain.MatrixRouteEstimator   = (*Client)(nil)
+	_ domain.TrailMatcher           = (*Client)(nil)
+)
+
+// EstimateRoute returns the driving distance and duration used for fare quotes.
+func (c *Client) EstimateRoute(ctx context.Context, originLat, originLng, destLat, destLng float64) (*domain.RouteEstimate, error) {
+	route, err := c.GetRoute(ctx, Coordinate{Lat: originLat, Lng: originLng}, Coordinate{Lat: destLat, Lng: destLng})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.RouteEstimate{
+		DistanceMeters:  route.Distance,
+		DurationSeconds: route.Duration,
+	}, nil
+}
+
+// EstimateRouteVia returns the driving distance and duration through the
+// waypoints in order, used to quote multi-stop trips.
+func (c *Client) EstimateRouteVia(ctx context.Context, waypoints []domain.Waypoint) (*domain.RouteEstimate, error) {
+	route, err := c.GetRouteVia(ctx, toCoordinates(waypoints))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.RouteEstimate{
+		DistanceMeters:  route.Distance,
+		DurationSeconds: route.Duration,
+	}, nil
+}
+
+// EstimateMatrix returns the driving distance and duration from every source
+// to every destination, used to rank dispatch candidates by drive time.
+func (c *Client) EstimateMatrix(ctx context.Context, sources, destinations []domain.Waypoint) ([][]*domain.RouteEstimate, error) {
+	matrix, err := c.Table(ctx, toCoordinates(sources), toCoordinates(destinations))
+	if err != nil {
+		return nil, err
+	}
+	estimates := make([][]*domain.RouteEstimate, len(sources))
+	for i := range sources {
+		estimates[i] = make([]*domain.RouteEstimate, len(destinations))
+		for j := range destinations {
+			if distance, duration, ok := matrix.Lookup(i, j); ok {
+				estimates[i][j] = &domain.RouteEstimate{DistanceMeters: distance, DurationSeconds: duration}
+			}
+		}
+	}
+	return estimates, nil
+}
+
+// MatchTrail snaps a trip's location trail to the road network.
+func (c *Client) MatchTrail(ctx context.Context, points []domain.LocationUpdate) (*domain.MatchedTrail, error) {
+	trace := make([]TracePoint, 0, len(points))
+	for _, point := range points {
+		trace = append(trace, TracePoint{Coordinate: Coordinate{Lat: point.Latitude, Lng: point.Longitude}, Time: point.Timestamp})
+	}
+	matching, err := c.Match(ctx, trace)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.MatchedTrail{
+		Coordinates:    matching.Coordinates,
+		DistanceMeters: matching.Distance,
+		Snapped:        matching.Snapped,
+	}, nil
+}
+
+func toCoordinates(waypoints []domain.Waypoint) []Coordinate {
+	coords := make([]Coordinate, 0, len(waypoints))
+	for _, point := range waypoints {
+		coords = append(coords, Coordinate{Lat: point.Lat, Lng: point.Lng})
+	}
+	return coords
+}
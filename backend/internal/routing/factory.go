@@ -0,0 +1,98 @@
+package routing
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"uitgo/backend/internal/config"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+
+// ProviderOptions selects and configures a routing backend.
+type ProviderOptions struct {
+	Backend string
+	BaseURL string
+	APIKey  string
+	// Profile is the backend's vehicle profile: the OSRM profile, Valhalla
+	// costing or GraphHopper profile. Each backend has a driving default.
+	Profile  string
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	// CacheSize bounds the in-memory route cache, 10000 entries by default.
+	CacheSize int
+	// SharedCache, when set, shares cached routes between replicas.
+	SharedCache *RedisCache
+	// RoadFactor and AverageSpeedKmh tune the offline backend.
+	RoadFactor      float64
+	AverageSpeedKmh float64
+}
+
+// backends builds the engine for each supported ROUTING_PROVIDER.
+var backends = map[string]func(opts ProviderOptions) engine{
+	"osrm": func(opts ProviderOptions) engine {
+		return newOSRM(opts.BaseURL, opts.Profile, opts.Timeout)
+	},
+	"valhalla": func(opts ProviderOptions) engine {
+		return newValhalla(opts.BaseURL, opts.Profile, opts.APIKey, opts.Timeout)
+	},
+	"graphhopper": func(opts ProviderOptions) engine {
+		return newGraphHopper(opts.BaseURL, opts.Profile, opts.APIKey, opts.Timeout)
+	},
+	"offline": func(opts ProviderOptions) engine {
+		return newOffline(opts.RoadFactor, opts.AverageSpeedKmh/3.6)
+	},
+}
+
+// Backends lists the routing backends NewProvider accepts.
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewProvider provisions the requested routing backend, OSRM by default.
+func NewProvider(opts ProviderOptions) (*Client, error) {
+	backend := strings.TrimSpace(strings.ToLower(opts.Backend))
+	if backend == "" {
+		backend = "osrm"
+	}
+	build, ok := backends[backend]
+	if !ok {
+		return nil, fmt.Errorf("routing: unsupported backend %q", opts.Backend)
+	}
+	return newClient(build(opts), opts.CacheTTL, opts.CacheSize, opts.SharedCache), nil
+}
+
+// NewProviderFromConfig provisions the routing backend selected by
+// ROUTING_PROVIDER. With ROUTING_CACHE_REDIS the route cache is shared through
+// Redis; if Redis is unreachable each replica keeps its own cache.
+func NewProviderFromConfig(cfg *config.Config) (*Client, error) {
+	var shared *RedisCache
+	if cfg.RoutingCacheRedis {
+		cache, err := NewRedisCache(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, defaultCachePrefix)
+		if err != nil {
+			log.Printf("warn: shared route cache unavailable, caching per replica: %v", err)
+		} else {
+			shared = cache
+		}
+	}
+	return NewProvider(ProviderOptions{
+		Backend:         cfg.RoutingProvider,
+		BaseURL:         cfg.RoutingBaseURL,
+		APIKey:          cfg.RoutingAPIKey,
+		Profile:         cfg.RoutingProfile,
+		Timeout:         defaultRequestTimeout,
+		CacheTTL:        defaultCacheTTL,
+		CacheSize:       cfg.RoutingCacheSize,
+		SharedCache:     shared,
+		RoadFactor:      cfg.RoutingRoadFactor,
+		AverageSpeedKmh: cfg.RoutingAverageSpeedKmh,
+	})
+}
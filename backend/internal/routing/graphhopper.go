@@ -0,0 +1,146 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultGraphHopperBaseURL = "https://graphhopper.com/api/1"
+
+// GraphHopper instruction signs that close a leg.
+const (
+	graphHopperFinish     = 4
+	graphHopperViaReached = 5
+)
+
+// graphHopperEngine calls the GraphHopper /route API.
+type graphHopperEngine struct {
+	baseURL    string
+	profile    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newGraphHopper(baseURL, profile, apiKey string, timeout time.Duration) *graphHopperEngine {
+	profile = strings.TrimSpace(profile)
+	if profile == "" {
+		profile = "car"
+	}
+	return &graphHopperEngine{
+		baseURL:    normalizeBaseURL(baseURL, defaultGraphHopperBaseURL),
+		profile:    profile,
+		apiKey:     strings.TrimSpace(apiKey),
+		httpClient: newHTTPClient(timeout),
+	}
+}
+
+type graphHopperResponse struct {
+	Paths   []graphHopperPath `json:"paths"`
+	Message string            `json:"message"`
+	Hints   []struct {
+		Details string `json:"details"`
+	} `json:"hints"`
+}
+
+// graphHopperPath distances are in meters and times in milliseconds.
+type graphHopperPath struct {
+	Distance float64 `json:"distance"`
+	Time     float64 `json:"time"`
+	Points   struct {
+		Coordinates [][]float64 `json:"coordinates"`
+	} `json:"points"`
+	Instructions []graphHopperInstruction `json:"instructions"`
+}
+
+type graphHopperInstruction struct {
+	Text       string  `json:"text"`
+	StreetName string  `json:"street_name"`
+	Distance   float64 `json:"distance"`
+	Time       float64 `json:"time"`
+	Interval   []int   `json:"interval"`
+	Sign       int     `json:"sign"`
+}
+
+func (e *graphHopperEngine) route(ctx context.Context, waypoints []Coordinate) (*Route, error) {
+	query := url.Values{}
+	for _, point := range waypoints {
+		query.Add("point", fmt.Sprintf("%f,%f", point.Lat, point.Lng))
+	}
+	query.Set("profile", e.profile)
+	query.Set("points_encoded", "false")
+	query.Set("instructions", "true")
+	if e.apiKey != "" {
+		query.Set("key", e.apiKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.baseURL+"/route?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload graphHopperResponse
+	status, err := fetchJSON(e.httpClient, req, &payload, http.StatusOK, http.StatusBadRequest)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusBadRequest && graphHopperNoRoute(&payload) {
+		return nil, fmt.Errorf("%w: %s", ErrRouteNotFound, strings.TrimSpace(payload.Message))
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("routing upstream returned %d", status)
+	}
+	if len(payload.Paths) == 0 {
+		return nil, ErrRouteNotFound
+	}
+	return mapGraphHopper(&payload.Paths[0]), nil
+}
+
+// graphHopperNoRoute reports whether a rejected request failed because a
+// point could not be snapped to a road or no connection exists between them.
+func graphHopperNoRoute(resp *graphHopperResponse) bool {
+	for _, hint := range resp.Hints {
+		if strings.HasSuffix(hint.Details, "NotFoundException") || strings.HasSuffix(hint.Details, "OutOfBoundsException") {
+			return true
+		}
+	}
+	return false
+}
+
+// mapGraphHopper normalizes a path. GraphHopper has no per-leg totals, so
+// legs are summed from the instructions up to each reached waypoint.
+func mapGraphHopper(path *graphHopperPath) *Route {
+	route := &Route{
+		Distance: path.Distance,
+		Duration: path.Time / 1000,
+	}
+	for _, point := range path.Points.Coordinates {
+		if pair := normalizeLocation(point); pair != nil {
+			route.Coordinates = append(route.Coordinates, pair)
+		}
+	}
+	var leg Leg
+	for _, instruction := range path.Instructions {
+		var location []float64
+		if len(instruction.Interval) > 0 && instruction.Interval[0] >= 0 && instruction.Interval[0] < len(route.Coordinates) {
+			location = route.Coordinates[instruction.Interval[0]]
+		}
+		name := strings.TrimSpace(instruction.StreetName)
+		route.Steps = append(route.Steps, Step{
+			Name:        name,
+			Instruction: buildInstruction(instruction.Text, "", "", name),
+			Location:    location,
+			Distance:    instruction.Distance,
+			Duration:    instruction.Time / 1000,
+		})
+		leg.Distance += instruction.Distance
+		leg.Duration += instruction.Time / 1000
+		if instruction.Sign == graphHopperViaReached || instruction.Sign == graphHopperFinish {
+			route.Legs = append(route.Legs, leg)
+			leg = Leg{}
+		}
+	}
+	return route
+}
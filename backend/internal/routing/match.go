@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	// traceAccuracyMeters is the assumed spread of a raw GPS fix.
+	traceAccuracyMeters = 10.0
+	// traceDriftMS is how fast, in meters per second, the vehicle may move away
+	// from the smoothed position between fixes.
+	traceDriftMS = 5.0
+)
+
+// TracePoint is one raw GPS fix of a trace.
+type TracePoint struct {
+	Coordinate
+	Time time.Time
+}
+
+// Matching is a GPS trace snapped to the road network. Coordinates are
+// [lng, lat] pairs, distances in meters and durations in seconds.
+type Matching struct {
+	Coordinates [][]float64
+	Distance    float64
+	Duration    float64
+	// Snapped is false when no backend could match the trace and it was only
+	// smoothed.
+	Snapped bool
+}
+
+// matchEngine is implemented by engines that snap GPS traces to roads.
+type matchEngine interface {
+	match(ctx context.Context, trace []TracePoint) (*Matching, error)
+}
+
+// Match snaps a GPS trace to the road network. Backends without map matching,
+// and backend failures, fall back to Kalman-smoothing the raw trace.
+func (c *Client) Match(ctx context.Context, trace []TracePoint) (*Matching, error) {
+	if len(trace) < 2 {
+		return nil, errors.New("match needs at least two trace points")
+	}
+	ordered := slices.Clone(trace)
+	slices.SortStableFunc(ordered, func(a, b TracePoint) int {
+		return a.Time.Compare(b.Time)
+	})
+	if matcher, ok := c.engine.(matchEngine); ok {
+		matching, err := matcher.match(ctx, ordered)
+		if err == nil {
+			return matching, nil
+		}
+		log.Printf("map matching unavailable, smoothing the trace: %v", err)
+	}
+	return smoothTrace(ordered), nil
+}
+
+// smoothTrace runs the ordered trace through a Kalman filter that treats each
+// fix as a noisy reading of a position drifting at up to traceDriftMS. It
+// removes jitter but does not follow roads.
+func smoothTrace(trace []TracePoint) *Matching {
+	matching := &Matching{Coordinates: make([][]float64, 0, len(trace))}
+	measurement := traceAccuracyMeters * traceAccuracyMeters
+	position := trace[0].Coordinate
+	variance := measurement
+	for i, point := range trace {
+		if i > 0 {
+			if elapsed := point.Time.Sub(trace[i-1].Time).Seconds(); elapsed > 0 {
+				variance += elapsed * traceDriftMS * traceDriftMS
+			}
+			gain := variance / (variance + measurement)
+			next := Coordinate{
+				Lat: position.Lat + gain*(point.Lat-position.Lat),
+				Lng: position.Lng + gain*(point.Lng-position.Lng),
+			}
+			variance *= 1 - gain
+			matching.Distance += haversineDistance(position, next)
+			position = next
+		}
+		matching.Coordinates = append(matching.Coordinates, []float64{position.Lng, position.Lat})
+	}
+	matching.Duration = trace[len(trace)-1].Time.Sub(trace[0].Time).Seconds()
+	return matching
+}
+
+// appendPath appends coords to path, dropping a leading point that repeats the
+// end of path.
+func appendPath(path, coords [][]float64) [][]float64 {
+	for _, pair := range coords {
+		pair = normalizeLocation(pair)
+		if pair == nil {
+			continue
+		}
+		if n := len(path); n > 0 && path[n-1][0] == pair[0] && path[n-1][1] == pair[1] {
+			continue
+		}
+		path = append(path, pair)
+	}
+	return path
+}
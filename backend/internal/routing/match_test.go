@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// zigzagTrace drives north along one street with fixes alternating a few
+// meters either side of it, ten seconds apart.
+func zigzagTrace(start time.Time, points int) []TracePoint {
+	trace := make([]TracePoint, 0, points)
+	for i := range points {
+		offset := 0.00005
+		if i%2 == 1 {
+			offset = -offset
+		}
+		trace = append(trace, TracePoint{
+			Coordinate: Coordinate{Lat: 10.80 + float64(i)*0.001, Lng: 106.80 + offset},
+			Time:       start.Add(time.Duration(i) * 10 * time.Second),
+		})
+	}
+	return trace
+}
+
+func traceDistance(trace []TracePoint) float64 {
+	total := 0.0
+	for i := 1; i < len(trace); i++ {
+		total += haversineDistance(trace[i-1].Coordinate, trace[i].Coordinate)
+	}
+	return total
+}
+
+func TestMatchSnapsLongTracesInChunks(t *testing.T) {
+	var (
+		down   atomic.Bool
+		chunks = make(chan int, 4)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.True(t, strings.HasPrefix(r.URL.Path, "/match/v1/driving/"), r.URL.Path)
+		query := r.URL.Query()
+		require.Equal(t, "geojson", query.Get("geometries"))
+		points := strings.Split(strings.TrimPrefix(r.URL.Path, "/match/v1/driving/"), ";")
+		require.Len(t, strings.Split(query.Get("timestamps"), ";"), len(points))
+		chunks <- len(points)
+
+		// Snap every fix onto the street at lng 106.80.
+		coords := make([][]float64, 0, len(points))
+		for _, point := range points {
+			var lng, lat float64
+			_, err := fmt.Sscanf(point, "%f,%f", &lng, &lat)
+			require.NoError(t, err)
+			coords = append(coords, []float64{106.80, lat})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"code": "Ok",
+			"matchings": []any{map[string]any{
+				"distance": float64(len(points)-1) * 111, "duration": float64(len(points)-1) * 10,
+				"geometry": map[string]any{"coordinates": coords},
+			}},
+		})
+	}))
+	defer server.Close()
+	provider, err := NewProvider(ProviderOptions{Backend: "osrm", BaseURL: server.URL, Timeout: time.Second})
+	require.NoError(t, err)
+	trace := zigzagTrace(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC), 150)
+
+	matching, err := provider.Match(context.Background(), trace)
+	require.NoError(t, err)
+	require.Equal(t, 100, <-chunks)
+	require.Equal(t, 51, <-chunks, "chunks share their boundary fix")
+	require.True(t, matching.Snapped)
+	require.Len(t, matching.Coordinates, 150)
+	require.InDelta(t, 149*111, matching.Distance, 0.01)
+	require.Equal(t, 1490.0, matching.Duration)
+
+	// With the backend down the trace is only smoothed, which still takes most
+	// of the zigzag out.
+	down.Store(true)
+	matching, err = provider.Match(context.Background(), trace)
+	require.NoError(t, err)
+	require.False(t, matching.Snapped)
+	require.Len(t, matching.Coordinates, 150)
+	straight := haversineDistance(trace[0].Coordinate, trace[len(trace)-1].Coordinate)
+	require.Less(t, matching.Distance-straight, (traceDistance(trace)-straight)/2)
+}
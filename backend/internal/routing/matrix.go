@@ -0,0 +1,143 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+)
+
+// Matrix holds drive distances (meters) and durations (seconds) from each
+// source to each destination, indexed [source][destination]. Nil entries
+// mark pairs with no route.
+type Matrix struct {
+	Distances [][]*float64
+	Durations [][]*float64
+}
+
+func newMatrix(sources, destinations int) *Matrix {
+	matrix := &Matrix{
+		Distances: make([][]*float64, sources),
+		Durations: make([][]*float64, sources),
+	}
+	for i := range sources {
+		matrix.Distances[i] = make([]*float64, destinations)
+		matrix.Durations[i] = make([]*float64, destinations)
+	}
+	return matrix
+}
+
+func (m *Matrix) set(i, j int, distance, duration float64) {
+	m.Distances[i][j] = &distance
+	m.Durations[i][j] = &duration
+}
+
+// Lookup returns the distance and duration from source i to destination j,
+// and whether a route exists between them.
+func (m *Matrix) Lookup(i, j int) (distance, duration float64, ok bool) {
+	if m.Distances[i][j] == nil || m.Durations[i][j] == nil {
+		return 0, 0, false
+	}
+	return *m.Distances[i][j], *m.Durations[i][j], true
+}
+
+// tableEngine is implemented by engines that answer many-to-many lookups in
+// one request.
+type tableEngine interface {
+	table(ctx context.Context, sources, destinations []Coordinate) (*Matrix, error)
+}
+
+// Table returns drive distances and durations from every source to every
+// destination. Pairs are served from the route cache where possible; the rest
+// are looked up in one table request when the backend supports it, or pair
+// by pair otherwise. An unavailable backend falls back to straight-line
+// estimates.
+func (c *Client) Table(ctx context.Context, sources, destinations []Coordinate) (*Matrix, error) {
+	if len(sources) == 0 || len(destinations) == 0 {
+		return nil, errors.New("table needs at least one source and one destination")
+	}
+	matrix := newMatrix(len(sources), len(destinations))
+	missingSources := make(map[int]struct{})
+	missingDestinations := make(map[int]struct{})
+	for i, source := range sources {
+		for j, destination := range destinations {
+			if cacheKey(source) == cacheKey(destination) {
+				matrix.set(i, j, 0, 0)
+				continue
+			}
+			if cached, ok := c.fromCache(ctx, cacheKey(source, destination)); ok {
+				matrix.set(i, j, cached.route.Distance, cached.route.Duration)
+				continue
+			}
+			missingSources[i] = struct{}{}
+			missingDestinations[j] = struct{}{}
+		}
+	}
+	if len(missingSources) == 0 {
+		return matrix, nil
+	}
+
+	// Look up the smallest block covering every missing pair.
+	rows := sortedIndexes(missingSources)
+	cols := sortedIndexes(missingDestinations)
+	subSources := make([]Coordinate, 0, len(rows))
+	for _, i := range rows {
+		subSources = append(subSources, sources[i])
+	}
+	subDestinations := make([]Coordinate, 0, len(cols))
+	for _, j := range cols {
+		subDestinations = append(subDestinations, destinations[j])
+	}
+	block, err := c.lookupTable(ctx, subSources, subDestinations)
+	if err != nil {
+		return nil, err
+	}
+	for a, i := range rows {
+		for b, j := range cols {
+			if matrix.Durations[i][j] != nil {
+				continue
+			}
+			distance, duration, ok := block.Lookup(a, b)
+			if !ok {
+				continue
+			}
+			matrix.set(i, j, distance, duration)
+			c.savePair(ctx, cacheKey(sources[i], destinations[j]), distance, duration)
+		}
+	}
+	return matrix, nil
+}
+
+func (c *Client) lookupTable(ctx context.Context, sources, destinations []Coordinate) (*Matrix, error) {
+	if tables, ok := c.engine.(tableEngine); ok {
+		matrix, err := tables.table(ctx, sources, destinations)
+		if err == nil {
+			return matrix, nil
+		}
+		log.Printf("routing table unavailable, using fallback: %v", err)
+		return c.fallback.table(ctx, sources, destinations)
+	}
+	matrix := newMatrix(len(sources), len(destinations))
+	for i, source := range sources {
+		for j, destination := range destinations {
+			route, err := c.GetRoute(ctx, source, destination)
+			if errors.Is(err, ErrRouteNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			matrix.set(i, j, route.Distance, route.Duration)
+		}
+	}
+	return matrix, nil
+}
+
+func sortedIndexes(set map[int]struct{}) []int {
+	indexes := make([]int, 0, len(set))
+	for index := range set {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	return indexes
+}
@@ -0,0 +1,85 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableUsesOSRMAndCachesPairs(t *testing.T) {
+	var (
+		down     atomic.Bool
+		requests atomic.Int32
+		queries  = make(chan string, 4)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.True(t, strings.HasPrefix(r.URL.Path, "/table/v1/driving/"), r.URL.Path)
+		queries <- r.URL.RawQuery
+		points := strings.Split(strings.TrimPrefix(r.URL.Path, "/table/v1/driving/"), ";")
+		var durations, distances [][]*float64
+		for _, index := range strings.Split(r.URL.Query().Get("sources"), ";") {
+			i, err := strconv.Atoi(index)
+			require.NoError(t, err)
+			// The second contract waypoint cannot reach the pickup.
+			if points[i] == "106.790000,10.876000" {
+				durations = append(durations, []*float64{nil})
+				distances = append(distances, []*float64{nil})
+				continue
+			}
+			duration, distance := 180.0, 1300.0
+			durations = append(durations, []*float64{&duration})
+			distances = append(distances, []*float64{&distance})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"code": "Ok", "durations": durations, "distances": distances})
+	}))
+	defer server.Close()
+	provider, err := NewProvider(ProviderOptions{Backend: "osrm", BaseURL: server.URL, Timeout: time.Second, CacheTTL: time.Minute})
+	require.NoError(t, err)
+	ctx := context.Background()
+	pickup := []Coordinate{contractWaypoints[2]}
+
+	matrix, err := provider.Table(ctx, contractWaypoints[:2], pickup)
+	require.NoError(t, err)
+	require.Equal(t, "annotations=duration%2Cdistance&destinations=2&sources=0%3B1", <-queries)
+	distance, duration, ok := matrix.Lookup(0, 0)
+	require.True(t, ok)
+	require.Equal(t, 1300.0, distance)
+	require.Equal(t, 180.0, duration)
+	_, _, ok = matrix.Lookup(1, 0)
+	require.False(t, ok, "null table entries mean no route")
+
+	// Only the pair without a cached answer is asked for again.
+	_, err = provider.Table(ctx, contractWaypoints[:2], pickup)
+	require.NoError(t, err)
+	require.Equal(t, "annotations=duration%2Cdistance&destinations=1&sources=0", <-queries)
+	require.EqualValues(t, 2, requests.Load())
+
+	// Table pairs never stand in for full routes.
+	down.Store(true)
+	route, err := provider.GetRoute(ctx, contractWaypoints[0], contractWaypoints[2])
+	require.NoError(t, err)
+	require.NotEqual(t, 1300.0, route.Distance)
+
+	matrix, err = provider.Table(ctx, []Coordinate{contractWaypoints[1], contractWaypoints[2]}, pickup)
+	require.NoError(t, err)
+	distance, duration, ok = matrix.Lookup(0, 0)
+	require.True(t, ok, "an unavailable backend falls back to estimates")
+	require.Positive(t, distance)
+	require.GreaterOrEqual(t, duration, float64(minLegDurationSeconds))
+	distance, duration, ok = matrix.Lookup(1, 0)
+	require.True(t, ok)
+	require.Zero(t, distance)
+	require.Zero(t, duration)
+}
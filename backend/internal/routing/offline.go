@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	fallbackAverageSpeedMS   = 11.0 // ~40km/h
+	defaultOfflineRoadFactor = 1.3
+	minLegDurationSeconds    = 60
+)
+
+// offlineEngine estimates routes without a routing backend: the great-circle
+// distance between waypoints stretched by a road factor, driven at an average
+// speed. It serves air-gapped and test environments and is the fallback when
+// a backend is down.
+type offlineEngine struct {
+	roadFactor float64
+	speedMS    float64
+}
+
+func newOffline(roadFactor, speedMS float64) *offlineEngine {
+	if roadFactor < 1 {
+		roadFactor = defaultOfflineRoadFactor
+	}
+	if speedMS <= 0 {
+		speedMS = fallbackAverageSpeedMS
+	}
+	return &offlineEngine{roadFactor: roadFactor, speedMS: speedMS}
+}
+
+func (e *offlineEngine) route(ctx context.Context, waypoints []Coordinate) (*Route, error) {
+	route := &Route{Coordinates: [][]float64{{waypoints[0].Lng, waypoints[0].Lat}}}
+	for i := 1; i < len(waypoints); i++ {
+		from, to := waypoints[i-1], waypoints[i]
+		leg := e.leg(from, to)
+		instruction := "Proceed to your destination"
+		if i < len(waypoints)-1 {
+			instruction = "Proceed to your next stop"
+		}
+		route.Distance += leg.Distance
+		route.Duration += leg.Duration
+		route.Coordinates = append(route.Coordinates, []float64{to.Lng, to.Lat})
+		route.Legs = append(route.Legs, leg)
+		route.Steps = append(route.Steps, Step{
+			Name:        "Direct route",
+			Instruction: instruction,
+			Location:    []float64{from.Lng, from.Lat},
+			Distance:    leg.Distance,
+			Duration:    leg.Duration,
+		})
+	}
+	if route.Distance <= 0 {
+		return nil, fmt.Errorf("%w: waypoints coincide", ErrRouteNotFound)
+	}
+	return route, nil
+}
+
+func (e *offlineEngine) table(ctx context.Context, sources, destinations []Coordinate) (*Matrix, error) {
+	matrix := newMatrix(len(sources), len(destinations))
+	for i, source := range sources {
+		for j, destination := range destinations {
+			leg := e.leg(source, destination)
+			matrix.set(i, j, leg.Distance, leg.Duration)
+		}
+	}
+	return matrix, nil
+}
+
+// leg estimates the drive between two points. Even short hops take a minute.
+func (e *offlineEngine) leg(from, to Coordinate) Leg {
+	distance := haversineDistance(from, to) * e.roadFactor
+	duration := distance / e.speedMS
+	if duration < minLegDurationSeconds {
+		duration = minLegDurationSeconds
+	}
+	return Leg{Distance: distance, Duration: duration}
+}
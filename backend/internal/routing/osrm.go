@@ -2,172 +2,143 @@ package routing
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
-	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
-const (
-	defaultBaseURL         = "https://router.project-osrm.org"
-	defaultRequestTimeout  = 8 * time.Second
-	fallbackAverageSpeedMS = 11.0 // ~40km/h
-)
-
-// ErrRouteNotFound indicates the routing engine could not find a path.
-var ErrRouteNotFound = errors.New("no route found")
-
-// Coordinate represents a lat/lng pair.
-type Coordinate struct {
-	Lat float64
-	Lng float64
-}
-
-// Step represents a single maneuver in the route.
-type Step struct {
-	Name        string
-	Instruction string
-	Location    []float64
-	Distance    float64
-	Duration    float64
-}
+const defaultOSRMBaseURL = "https://router.project-osrm.org"
 
-// Route is the normalized routing response returned to callers.
-type Route struct {
-	Distance    float64
-	Duration    float64
-	Coordinates [][]float64
-	Steps       []Step
-}
-
-// Client calls an OSRM-compatible routing API.
-type Client struct {
+// osrmEngine calls an OSRM-compatible routing API.
+type osrmEngine struct {
 	baseURL    string
+	profile    string
 	httpClient *http.Client
-	cacheTTL   time.Duration
-
-	mu    sync.RWMutex
-	cache map[string]cachedRoute
-}
-
-type cachedRoute struct {
-	route     *Route
-	expiresAt time.Time
 }
 
-// NewClient creates a routing client with optional caching.
-func NewClient(baseURL string, timeout, cacheTTL time.Duration) *Client {
-	baseURL = strings.TrimSpace(baseURL)
-	if baseURL == "" {
-		baseURL = defaultBaseURL
+func newOSRM(baseURL, profile string, timeout time.Duration) *osrmEngine {
+	profile = strings.TrimSpace(profile)
+	if profile == "" {
+		profile = "driving"
 	}
-	baseURL = strings.TrimSuffix(baseURL, "/")
-	if timeout <= 0 {
-		timeout = defaultRequestTimeout
-	}
-	if cacheTTL < 0 {
-		cacheTTL = 0
-	}
-
-	return &Client{
-		baseURL:    baseURL,
-		httpClient: &http.Client{Timeout: timeout},
-		cacheTTL:   cacheTTL,
-		cache:      make(map[string]cachedRoute),
+	return &osrmEngine{
+		baseURL:    normalizeBaseURL(baseURL, defaultOSRMBaseURL),
+		profile:    profile,
+		httpClient: newHTTPClient(timeout),
 	}
 }
 
-// GetRoute fetches a driving route between origin and destination.
-func (c *Client) GetRoute(ctx context.Context, origin, destination Coordinate) (*Route, error) {
-	key := cacheKey(origin, destination)
-	if cached := c.fromCache(key); cached != nil {
-		return cached, nil
-	}
-
-	requestURL, err := c.buildURL(origin, destination)
+func (e *osrmEngine) route(ctx context.Context, waypoints []Coordinate) (*Route, error) {
+	requestURL, err := e.buildURL(waypoints)
 	if err != nil {
 		return nil, err
 	}
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	var payload osrmResponse
+	status, err := fetchJSON(e.httpClient, req, &payload, http.StatusOK, http.StatusBadRequest)
 	if err != nil {
-		return c.syntheticRoute(origin, destination, fmt.Errorf("routing request: %w", err))
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
+	if status == http.StatusNotFound {
 		return nil, ErrRouteNotFound
 	}
-	if resp.StatusCode != http.StatusOK {
-		return c.syntheticRoute(origin, destination, fmt.Errorf("routing upstream returned %d", resp.StatusCode))
-	}
-
-	var payload osrmResponse
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return c.syntheticRoute(origin, destination, fmt.Errorf("decode routing response: %w", err))
+	if status == http.StatusBadRequest && (payload.Code == "NoRoute" || payload.Code == "NoSegment") {
+		return mapOSRM(&payload)
 	}
-
-	route, err := mapOSRM(&payload)
-	if err != nil {
-		return nil, err
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("routing upstream returned %d", status)
 	}
+	return mapOSRM(&payload)
+}
 
-	c.saveCache(key, route)
-	return route, nil
+func (e *osrmEngine) buildURL(waypoints []Coordinate) (string, error) {
+	return e.serviceURL("route", waypoints, url.Values{
+		"overview":   {"full"},
+		"geometries": {"geojson"},
+		"steps":      {"true"},
+	})
 }
 
-func (c *Client) buildURL(origin, destination Coordinate) (string, error) {
-	raw := fmt.Sprintf("%s/route/v1/driving/%f,%f;%f,%f", c.baseURL, origin.Lng, origin.Lat, destination.Lng, destination.Lat)
+func (e *osrmEngine) serviceURL(service string, waypoints []Coordinate, query url.Values) (string, error) {
+	coords := make([]string, 0, len(waypoints))
+	for _, point := range waypoints {
+		coords = append(coords, fmt.Sprintf("%f,%f", point.Lng, point.Lat))
+	}
+	raw := fmt.Sprintf("%s/%s/v1/%s/%s", e.baseURL, service, url.PathEscape(e.profile), strings.Join(coords, ";"))
 	parsed, err := url.Parse(raw)
 	if err != nil {
 		return "", err
 	}
-	query := parsed.Query()
-	query.Set("overview", "full")
-	query.Set("geometries", "geojson")
-	query.Set("steps", "true")
 	parsed.RawQuery = query.Encode()
 	return parsed.String(), nil
 }
 
-func (c *Client) fromCache(key string) *Route {
-	if c.cacheTTL == 0 {
-		return nil
-	}
-	now := time.Now()
-	c.mu.RLock()
-	entry, ok := c.cache[key]
-	c.mu.RUnlock()
-	if !ok || now.After(entry.expiresAt) {
-		return nil
-	}
-	return entry.route
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Durations [][]*float64 `json:"durations"`
+	Distances [][]*float64 `json:"distances"`
 }
 
-func (c *Client) saveCache(key string, route *Route) {
-	if c.cacheTTL == 0 || route == nil {
-		return
-	}
-	expiry := time.Now().Add(c.cacheTTL)
-	c.mu.Lock()
-	c.cache[key] = cachedRoute{route: route, expiresAt: expiry}
-	c.mu.Unlock()
-}
+// table asks the OSRM table service for every source to destination pair.
+// Pairs OSRM cannot connect come back as nulls.
+func (e *osrmEngine) table(ctx context.Context, sources, destinations []Coordinate) (*Matrix, error) {
+	indexes := func(from, count int) string {
+		parts := make([]string, 0, count)
+		for i := range count {
+			parts = append(parts, strconv.Itoa(from+i))
+		}
+		return strings.Join(parts, ";")
+	}
+	waypoints := append(append([]Coordinate(nil), sources...), destinations...)
+	requestURL, err := e.serviceURL("table", waypoints, url.Values{
+		"sources":      {indexes(0, len(sources))},
+		"destinations": {indexes(len(sources), len(destinations))},
+		"annotations":  {"duration,distance"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
 
-func cacheKey(origin, destination Coordinate) string {
-	return fmt.Sprintf("%.5f,%.5f|%.5f,%.5f", origin.Lat, origin.Lng, destination.Lat, destination.Lng)
+	var payload osrmTableResponse
+	status, err := fetchJSON(e.httpClient, req, &payload, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("routing table upstream returned %d", status)
+	}
+	if !strings.EqualFold(payload.Code, "ok") {
+		return nil, fmt.Errorf("routing table upstream answered %s: %s", payload.Code, payload.Message)
+	}
+	if len(payload.Durations) != len(sources) || len(payload.Distances) != len(sources) {
+		return nil, fmt.Errorf("routing table upstream returned %d rows for %d sources", len(payload.Durations), len(sources))
+	}
+	matrix := newMatrix(len(sources), len(destinations))
+	for i := range sources {
+		if len(payload.Durations[i]) != len(destinations) || len(payload.Distances[i]) != len(destinations) {
+			return nil, fmt.Errorf("routing table upstream returned a short row %d", i)
+		}
+		for j := range destinations {
+			duration, distance := payload.Durations[i][j], payload.Distances[i][j]
+			if duration != nil && distance != nil {
+				matrix.set(i, j, *distance, *duration)
+			}
+		}
+	}
+	return matrix, nil
 }
 
 type osrmResponse struct {
@@ -188,7 +159,9 @@ type osrmGeometry struct {
 }
 
 type osrmLeg struct {
-	Steps []osrmStep `json:"steps"`
+	Distance float64    `json:"distance"`
+	Duration float64    `json:"duration"`
+	Steps    []osrmStep `json:"steps"`
 }
 
 type osrmStep struct {
@@ -220,11 +193,16 @@ func mapOSRM(resp *osrmResponse) (*Route, error) {
 	}
 	first := resp.Routes[0]
 	steps := flattenSteps(first.Legs)
+	legs := make([]Leg, 0, len(first.Legs))
+	for _, leg := range first.Legs {
+		legs = append(legs, Leg{Distance: leg.Distance, Duration: leg.Duration})
+	}
 	return &Route{
 		Distance:    first.Distance,
 		Duration:    first.Duration,
 		Coordinates: first.Geometry.Coordinates,
 		Steps:       steps,
+		Legs:        legs,
 	}, nil
 }
 
@@ -248,13 +226,6 @@ func flattenSteps(legs []osrmLeg) []Step {
 	return steps
 }
 
-func normalizeLocation(location []float64) []float64 {
-	if len(location) < 2 {
-		return nil
-	}
-	return []float64{location[0], location[1]}
-}
-
 func buildInstruction(raw, typ, modifier, street string) string {
 	raw = strings.TrimSpace(raw)
 	if raw != "" {
@@ -280,65 +251,88 @@ func buildInstruction(raw, typ, modifier, street string) string {
 	}
 }
 
-func prettify(text string) string {
-	text = strings.ReplaceAll(strings.TrimSpace(text), "_", " ")
-	if text == "" {
-		return ""
-	}
-	lower := strings.ToLower(text)
-	return strings.ToUpper(lower[:1]) + lower[1:]
+// osrmMatchChunk is the most points sent in one match request; OSRM servers
+// reject longer traces by default.
+const osrmMatchChunk = 100
+
+type osrmMatchResponse struct {
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	Matchings []osrmMatchPath `json:"matchings"`
 }
 
-func (c *Client) syntheticRoute(origin, destination Coordinate, reason error) (*Route, error) {
-	if reason != nil {
-		log.Printf("routing upstream unavailable, using fallback: %v", reason)
-	}
-	distance := haversineDistance(origin, destination)
-	if distance <= 0 {
-		return nil, fmt.Errorf("unable to compute fallback route")
+type osrmMatchPath struct {
+	Distance float64      `json:"distance"`
+	Duration float64      `json:"duration"`
+	Geometry osrmGeometry `json:"geometry"`
+}
+
+// match snaps the trace with the OSRM match service. Long traces are sent in
+// chunks that share their boundary point, so the snapped paths join up.
+func (e *osrmEngine) match(ctx context.Context, trace []TracePoint) (*Matching, error) {
+	// OSRM wants strictly increasing timestamps in whole seconds.
+	points := make([]TracePoint, 0, len(trace))
+	for _, point := range trace {
+		if n := len(points); n > 0 && point.Time.Unix() <= points[n-1].Time.Unix() {
+			continue
+		}
+		points = append(points, point)
 	}
-	duration := distance / fallbackAverageSpeedMS
-	if duration < 60 {
-		duration = 60
+	if len(points) < 2 {
+		return nil, errors.New("trace has fewer than two distinct timestamps")
 	}
 
-	coords := [][]float64{
-		{origin.Lng, origin.Lat},
-		{destination.Lng, destination.Lat},
+	matching := &Matching{Snapped: true}
+	for start := 0; start < len(points)-1; start += osrmMatchChunk - 1 {
+		end := min(start+osrmMatchChunk, len(points))
+		chunk, err := e.matchChunk(ctx, points[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range chunk.Matchings {
+			matching.Distance += path.Distance
+			matching.Duration += path.Duration
+			matching.Coordinates = appendPath(matching.Coordinates, path.Geometry.Coordinates)
+		}
 	}
-	step := Step{
-		Name:        "Direct route",
-		Instruction: "Proceed to your destination",
-		Location:    []float64{origin.Lng, origin.Lat},
-		Distance:    distance,
-		Duration:    duration,
+	if len(matching.Coordinates) < 2 {
+		return nil, errors.New("routing match upstream returned no geometry")
 	}
-
-	route := &Route{
-		Distance:    distance,
-		Duration:    duration,
-		Coordinates: coords,
-		Steps:       []Step{step},
-	}
-	c.saveCache(cacheKey(origin, destination), route)
-	return route, nil
+	return matching, nil
 }
 
-func haversineDistance(a, b Coordinate) float64 {
-	const earthRadius = 6371000.0
-	lat1 := toRadians(a.Lat)
-	lat2 := toRadians(b.Lat)
-	deltaLat := toRadians(b.Lat - a.Lat)
-	deltaLng := toRadians(b.Lng - a.Lng)
-
-	sinLat := math.Sin(deltaLat / 2)
-	sinLng := math.Sin(deltaLng / 2)
-
-	h := sinLat*sinLat + math.Cos(lat1)*math.Cos(lat2)*sinLng*sinLng
-	c := 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
-	return earthRadius * c
-}
+func (e *osrmEngine) matchChunk(ctx context.Context, points []TracePoint) (*osrmMatchResponse, error) {
+	waypoints := make([]Coordinate, 0, len(points))
+	timestamps := make([]string, 0, len(points))
+	radiuses := make([]string, 0, len(points))
+	for _, point := range points {
+		waypoints = append(waypoints, point.Coordinate)
+		timestamps = append(timestamps, strconv.FormatInt(point.Time.Unix(), 10))
+		radiuses = append(radiuses, strconv.FormatFloat(traceAccuracyMeters, 'f', -1, 64))
+	}
+	requestURL, err := e.serviceURL("match", waypoints, url.Values{
+		"timestamps": {strings.Join(timestamps, ";")},
+		"radiuses":   {strings.Join(radiuses, ";")},
+		"overview":   {"full"},
+		"geometries": {"geojson"},
+		"gaps":       {"split"},
+		"tidy":       {"true"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
 
-func toRadians(deg float64) float64 {
-	return deg * math.Pi / 180
+	var payload osrmMatchResponse
+	status, err := fetchJSON(e.httpClient, req, &payload, http.StatusOK, http.StatusBadRequest)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || !strings.EqualFold(payload.Code, "ok") {
+		return nil, fmt.Errorf("routing match upstream returned %d %s: %s", status, payload.Code, payload.Message)
+	}
+	return &payload, nil
 }
@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrRouteNotFound indicates the routing engine could not find a path.
+var ErrRouteNotFound = errors.New("no route found")
+
+// Coordinate represents a lat/lng pair.
+type Coordinate struct {
+	Lat float64
+	Lng float64
+}
+
+// Step represents a single maneuver in the route.
+type Step struct {
+	Name        string
+	Instruction string
+	Location    []float64
+	Distance    float64
+	Duration    float64
+}
+
+// Leg is the part of a route between two consecutive waypoints.
+type Leg struct {
+	Distance float64
+	Duration float64
+}
+
+// Route is the normalized routing response returned to callers. Distances are
+// in meters, durations in seconds and coordinates are [lng, lat] pairs.
+type Route struct {
+	Distance    float64
+	Duration    float64
+	Coordinates [][]float64
+	Steps       []Step
+	Legs        []Leg
+}
+
+// engine looks a route up in one routing backend. Errors wrapping
+// ErrRouteNotFound mean the backend answered that no route exists; any other
+// error means it could not be reached or understood.
+type engine interface {
+	route(ctx context.Context, waypoints []Coordinate) (*Route, error)
+}
+
+func haversineDistance(a, b Coordinate) float64 {
+	const earthRadius = 6371000.0
+	lat1 := toRadians(a.Lat)
+	lat2 := toRadians(b.Lat)
+	deltaLat := toRadians(b.Lat - a.Lat)
+	deltaLng := toRadians(b.Lng - a.Lng)
+
+	sinLat := math.Sin(deltaLat / 2)
+	sinLng := math.Sin(deltaLng / 2)
+
+	h := sinLat*sinLat + math.Cos(lat1)*math.Cos(lat2)*sinLng*sinLng
+	c := 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+	return earthRadius * c
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,212 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultValhallaBaseURL = "https://valhalla1.openstreetmap.de"
+
+// valhallaEngine calls the Valhalla /route API.
+type valhallaEngine struct {
+	baseURL    string
+	costing    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newValhalla(baseURL, costing, apiKey string, timeout time.Duration) *valhallaEngine {
+	costing = strings.TrimSpace(costing)
+	if costing == "" {
+		costing = "auto"
+	}
+	return &valhallaEngine{
+		baseURL:    normalizeBaseURL(baseURL, defaultValhallaBaseURL),
+		costing:    costing,
+		apiKey:     strings.TrimSpace(apiKey),
+		httpClient: newHTTPClient(timeout),
+	}
+}
+
+type valhallaRequest struct {
+	Locations         []valhallaLocation `json:"locations"`
+	Costing           string             `json:"costing"`
+	DirectionsOptions map[string]string  `json:"directions_options"`
+}
+
+type valhallaLocation struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	Type string  `json:"type"`
+}
+
+type valhallaResponse struct {
+	Trip      valhallaTrip `json:"trip"`
+	ErrorCode int          `json:"error_code"`
+	Error     string       `json:"error"`
+}
+
+type valhallaTrip struct {
+	Legs    []valhallaLeg   `json:"legs"`
+	Summary valhallaSummary `json:"summary"`
+}
+
+type valhallaLeg struct {
+	Maneuvers []valhallaManeuver `json:"maneuvers"`
+	Summary   valhallaSummary    `json:"summary"`
+	Shape     string             `json:"shape"`
+}
+
+// valhallaSummary lengths are in kilometers.
+type valhallaSummary struct {
+	Length float64 `json:"length"`
+	Time   float64 `json:"time"`
+}
+
+type valhallaManeuver struct {
+	Instruction     string   `json:"instruction"`
+	StreetNames     []string `json:"street_names"`
+	Length          float64  `json:"length"`
+	Time            float64  `json:"time"`
+	BeginShapeIndex int      `json:"begin_shape_index"`
+}
+
+func (e *valhallaEngine) route(ctx context.Context, waypoints []Coordinate) (*Route, error) {
+	body := valhallaRequest{
+		Costing:           e.costing,
+		DirectionsOptions: map[string]string{"units": "kilometers"},
+	}
+	for _, point := range waypoints {
+		body.Locations = append(body.Locations, valhallaLocation{Lat: point.Lat, Lon: point.Lng, Type: "break"})
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/route", bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		query := req.URL.Query()
+		query.Set("api_key", e.apiKey)
+		req.URL.RawQuery = query.Encode()
+	}
+
+	var payload valhallaResponse
+	status, err := fetchJSON(e.httpClient, req, &payload, http.StatusOK, http.StatusBadRequest)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusBadRequest && valhallaNoRoute(payload.ErrorCode) {
+		return nil, fmt.Errorf("%w: %s", ErrRouteNotFound, strings.TrimSpace(payload.Error))
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("routing upstream returned %d", status)
+	}
+	return mapValhalla(&payload.Trip)
+}
+
+// valhallaNoRoute reports whether a Valhalla error code means the locations
+// cannot be routed between, rather than a bad request or server fault.
+func valhallaNoRoute(code int) bool {
+	switch code {
+	case 170, 171, 442, 443:
+		return true
+	}
+	return false
+}
+
+func mapValhalla(trip *valhallaTrip) (*Route, error) {
+	if len(trip.Legs) == 0 {
+		return nil, ErrRouteNotFound
+	}
+	route := &Route{
+		Distance: trip.Summary.Length * 1000,
+		Duration: trip.Summary.Time,
+	}
+	for i, leg := range trip.Legs {
+		shape, err := decodePolyline(leg.Shape, 6)
+		if err != nil {
+			return nil, err
+		}
+		// Each leg starts where the previous one ended.
+		if i > 0 && len(shape) > 0 {
+			route.Coordinates = append(route.Coordinates, shape[1:]...)
+		} else {
+			route.Coordinates = append(route.Coordinates, shape...)
+		}
+		route.Legs = append(route.Legs, Leg{Distance: leg.Summary.Length * 1000, Duration: leg.Summary.Time})
+		for _, maneuver := range leg.Maneuvers {
+			var name string
+			if len(maneuver.StreetNames) > 0 {
+				name = strings.TrimSpace(maneuver.StreetNames[0])
+			}
+			var location []float64
+			if maneuver.BeginShapeIndex >= 0 && maneuver.BeginShapeIndex < len(shape) {
+				location = shape[maneuver.BeginShapeIndex]
+			}
+			route.Steps = append(route.Steps, Step{
+				Name:        name,
+				Instruction: buildInstruction(maneuver.Instruction, "", "", name),
+				Location:    location,
+				Distance:    maneuver.Length * 1000,
+				Duration:    maneuver.Time,
+			})
+		}
+	}
+	return route, nil
+}
+
+// decodePolyline decodes an encoded polyline with the given precision into
+// [lng, lat] pairs.
+func decodePolyline(encoded string, precision int) ([][]float64, error) {
+	factor := 1.0
+	for i := 0; i < precision; i++ {
+		factor *= 10
+	}
+	var (
+		coords   [][]float64
+		lat, lng int
+		index    int
+	)
+	next := func() (int, error) {
+		var result, shift int
+		for {
+			if index >= len(encoded) {
+				return 0, fmt.Errorf("decode polyline: truncated at %d", index)
+			}
+			b := int(encoded[index]) - 63
+			index++
+			result |= (b & 0x1f) << shift
+			shift += 5
+			if b < 0x20 {
+				break
+			}
+		}
+		if result&1 != 0 {
+			return ^(result >> 1), nil
+		}
+		return result >> 1, nil
+	}
+	for index < len(encoded) {
+		dLat, err := next()
+		if err != nil {
+			return nil, err
+		}
+		dLng, err := next()
+		if err != nil {
+			return nil, err
+		}
+		lat += dLat
+		lng += dLng
+		coords = append(coords, []float64{float64(lng) / factor, float64(lat) / factor})
+	}
+	return coords, nil
+}
@@ -0,0 +1,291 @@
+// Package surge prices demand: it raises fares in areas where pending trip
+// requests outnumber the free drivers nearby.
+package surge
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/heatmap"
+)
+
+// maxTrackedAreas bounds the smoothing state kept per area and service.
+const maxTrackedAreas = 10000
+
+// Config tunes the surge engine.
+type Config struct {
+	// Resolution is the geohash precision of a surge area.
+	Resolution int
+	// DemandWindow is how recent a trip request must be to count as pending.
+	DemandWindow time.Duration
+	// Threshold is the pending-requests-per-free-driver ratio surge starts at.
+	Threshold float64
+	// Sensitivity is how much the multiplier rises per unit of ratio above the
+	// threshold.
+	Sensitivity float64
+	// HalfLife is how quickly the multiplier follows a change in the ratio.
+	HalfLife time.Duration
+	// Step rounds multipliers down to a price riders can read, such as 1.3x.
+	Step float64
+	// StaleAfter skips drivers whose last location is older.
+	StaleAfter time.Duration
+	// RefreshInterval is how long demand counts are reused between quotes.
+	RefreshInterval time.Duration
+}
+
+// DefaultConfig returns the baseline surge tuning.
+func DefaultConfig() Config {
+	return Config{
+		Resolution:      6,
+		DemandWindow:    5 * time.Minute,
+		Threshold:       1,
+		Sensitivity:     0.25,
+		HalfLife:        2 * time.Minute,
+		Step:            0.1,
+		StaleAfter:      3 * time.Minute,
+		RefreshInterval: 10 * time.Second,
+	}
+}
+
+// Supply finds drivers around a point.
+type Supply interface {
+	Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int, maxAge time.Duration) ([]*domain.DriverLocation, error)
+}
+
+// Demand lists trip requests made since a time.
+type Demand interface {
+	Since(ctx context.Context, since time.Time) ([]heatmap.Request, error)
+}
+
+// BusyDrivers reports which drivers are already on a trip.
+type BusyDrivers interface {
+	BusyDrivers(driverIDs []string) (map[string]struct{}, error)
+}
+
+// Option customises the engine.
+type Option func(*Engine)
+
+// WithConfig overrides the engine tuning. Zero fields keep their defaults.
+func WithConfig(cfg Config) Option {
+	return func(e *Engine) {
+		defaults := e.cfg
+		e.cfg = cfg
+		if e.cfg.Resolution < 1 || e.cfg.Resolution > heatmap.MaxResolution {
+			e.cfg.Resolution = defaults.Resolution
+		}
+		if e.cfg.DemandWindow <= 0 {
+			e.cfg.DemandWindow = defaults.DemandWindow
+		}
+		if e.cfg.Threshold <= 0 {
+			e.cfg.Threshold = defaults.Threshold
+		}
+		if e.cfg.Sensitivity <= 0 {
+			e.cfg.Sensitivity = defaults.Sensitivity
+		}
+		if e.cfg.HalfLife <= 0 {
+			e.cfg.HalfLife = defaults.HalfLife
+		}
+		if e.cfg.Step <= 0 {
+			e.cfg.Step = defaults.Step
+		}
+		if e.cfg.StaleAfter <= 0 {
+			e.cfg.StaleAfter = defaults.StaleAfter
+		}
+		if e.cfg.RefreshInterval <= 0 {
+			e.cfg.RefreshInterval = defaults.RefreshInterval
+		}
+	}
+}
+
+// WithBusyDrivers leaves drivers already on a trip out of the supply.
+func WithBusyDrivers(busy BusyDrivers) Option {
+	return func(e *Engine) {
+		e.busy = busy
+	}
+}
+
+// WithSettings reads per-service caps and switches from settings instead of
+// surging every service up to defaults.
+func WithSettings(settings *Settings) Option {
+	return func(e *Engine) {
+		e.settings = settings
+	}
+}
+
+// Engine computes smoothed, capped surge multipliers per area and service.
+type Engine struct {
+	supply   Supply
+	demand   Demand
+	busy     BusyDrivers
+	settings *Settings
+	defaults ServiceSettings
+	cfg      Config
+	now      func() time.Time
+
+	mu        sync.Mutex
+	levels    map[string]level
+	pending   map[string]int
+	pendingAt time.Time
+}
+
+// level is the smoothed multiplier of one area and service.
+type level struct {
+	value     float64
+	updatedAt time.Time
+}
+
+var _ domain.SurgePricer = (*Engine)(nil)
+
+// NewEngine prices surge from drivers in supply and requests in demand. Without
+// settings every service surges, capped at defaults.MaxMultiplier.
+func NewEngine(supply Supply, demand Demand, defaults ServiceSettings, opts ...Option) *Engine {
+	engine := &Engine{
+		supply:   supply,
+		demand:   demand,
+		defaults: defaults,
+		cfg:      DefaultConfig(),
+		now:      func() time.Time { return time.Now().UTC() },
+		levels:   make(map[string]level),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(engine)
+		}
+	}
+	return engine
+}
+
+// SurgeMultiplier returns the multiplier for serviceID in the area around the
+// pickup. It is 1 when surge is off for the service or drivers keep up.
+func (e *Engine) SurgeMultiplier(ctx context.Context, serviceID string, lat, lng float64) (float64, error) {
+	settings := e.defaults
+	if e.settings != nil {
+		current, err := e.settings.Get(ctx, serviceID)
+		if err != nil {
+			log.Printf("surge settings for %s, using defaults: %v", serviceID, err)
+		}
+		settings = current
+	}
+	if !settings.Enabled || settings.MaxMultiplier <= 1 {
+		return 1, nil
+	}
+
+	now := e.now()
+	cell := heatmap.Encode(lat, lng, e.cfg.Resolution)
+	service := normalizeService(serviceID)
+	pending, err := e.pendingRequests(ctx, service, cell, now)
+	if err != nil {
+		return 1, err
+	}
+	free, err := e.freeDrivers(ctx, cell)
+	if err != nil {
+		return 1, err
+	}
+
+	target := 1.0
+	if ratio := float64(pending) / math.Max(float64(free), 1); ratio > e.cfg.Threshold {
+		target += e.cfg.Sensitivity * (ratio - e.cfg.Threshold)
+	}
+	multiplier := math.Min(e.smooth(service+":"+cell, target, now), settings.MaxMultiplier)
+	// Round down so the shown multiplier never overstates the price.
+	multiplier = math.Floor(multiplier/e.cfg.Step+1e-9) * e.cfg.Step
+	return math.Max(1, math.Round(multiplier*100)/100), nil
+}
+
+// pendingRequests counts the requests for service in cell within the demand
+// window. Counts for every area are refreshed together at most once per
+// RefreshInterval.
+func (e *Engine) pendingRequests(ctx context.Context, service, cell string, now time.Time) (int, error) {
+	if e.demand == nil {
+		return 0, nil
+	}
+	e.mu.Lock()
+	if e.pending != nil && now.Sub(e.pendingAt) < e.cfg.RefreshInterval {
+		count := e.pending[service+":"+cell]
+		e.mu.Unlock()
+		return count, nil
+	}
+	e.mu.Unlock()
+
+	requests, err := e.demand.Since(ctx, now.Add(-e.cfg.DemandWindow))
+	if err != nil {
+		return 0, err
+	}
+	pending := make(map[string]int)
+	for _, req := range requests {
+		pending[req.ServiceID+":"+heatmap.Encode(req.Lat, req.Lng, e.cfg.Resolution)]++
+	}
+	e.mu.Lock()
+	e.pending, e.pendingAt = pending, now
+	e.mu.Unlock()
+	return pending[service+":"+cell], nil
+}
+
+// freeDrivers counts the drivers with a fresh location inside cell who are not
+// on a trip.
+func (e *Engine) freeDrivers(ctx context.Context, cell string) (int, error) {
+	if e.supply == nil {
+		return 0, nil
+	}
+	bounds, err := heatmap.Decode(cell)
+	if err != nil {
+		return 0, err
+	}
+	centerLat := (bounds.MinLat + bounds.MaxLat) / 2
+	centerLng := (bounds.MinLng + bounds.MaxLng) / 2
+	radius := haversineMeters(centerLat, centerLng, bounds.MaxLat, bounds.MaxLng)
+	nearby, err := e.supply.Nearby(ctx, centerLat, centerLng, radius, 500, e.cfg.StaleAfter)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]string, 0, len(nearby))
+	for _, loc := range nearby {
+		if loc != nil && heatmap.Encode(loc.Latitude, loc.Longitude, e.cfg.Resolution) == cell {
+			ids = append(ids, loc.DriverID)
+		}
+	}
+	if e.busy == nil || len(ids) == 0 {
+		return len(ids), nil
+	}
+	busy, err := e.busy.BusyDrivers(ids)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids) - len(busy), nil
+}
+
+// smooth moves the area's multiplier toward target, halfway per HalfLife, so a
+// burst of requests or a driver logging off does not swing prices at once. An
+// area without history starts at target.
+func (e *Engine) smooth(key string, target float64, now time.Time) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	value := target
+	if current, ok := e.levels[key]; ok {
+		elapsed := now.Sub(current.updatedAt)
+		weight := 1 - math.Exp2(-float64(elapsed)/float64(e.cfg.HalfLife))
+		value = current.value + (target-current.value)*weight
+	}
+	if len(e.levels) >= maxTrackedAreas {
+		for tracked, l := range e.levels {
+			if now.Sub(l.updatedAt) > 10*e.cfg.HalfLife {
+				delete(e.levels, tracked)
+			}
+		}
+	}
+	e.levels[key] = level{value: value, updatedAt: now}
+	return value
+}
+
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
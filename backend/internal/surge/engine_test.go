@@ -0,0 +1,129 @@
+package surge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/heatmap"
+)
+
+type fakeSupply []*domain.DriverLocation
+
+func (f fakeSupply) Nearby(ctx context.Context, lat, lng, radiusMeters float64, limit int, maxAge time.Duration) ([]*domain.DriverLocation, error) {
+	return f, nil
+}
+
+type fakeDemand struct {
+	requests []heatmap.Request
+}
+
+func (f *fakeDemand) Since(ctx context.Context, since time.Time) ([]heatmap.Request, error) {
+	var recent []heatmap.Request
+	for _, req := range f.requests {
+		if !req.At.Before(since) {
+			recent = append(recent, req)
+		}
+	}
+	return recent, nil
+}
+
+type fakeBusy map[string]struct{}
+
+func (f fakeBusy) BusyDrivers(driverIDs []string) (map[string]struct{}, error) {
+	return f, nil
+}
+
+func TestSurgeMultiplierFollowsPendingRequestsPerFreeDriver(t *testing.T) {
+	server := miniredis.RunT(t)
+	settings, err := NewSettings(server.Addr(), "", 0, ServiceSettings{Enabled: true, MaxMultiplier: 2})
+	require.NoError(t, err)
+	t.Cleanup(func() { settings.Close() })
+
+	lat, lng := 10.7769, 106.7009
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	// Two drivers in the pickup area, one of them on a trip, and one elsewhere.
+	supply := fakeSupply{
+		{DriverID: "free", Latitude: lat, Longitude: lng},
+		{DriverID: "busy", Latitude: lat, Longitude: lng},
+		{DriverID: "far", Latitude: 10.90, Longitude: 106.90},
+	}
+	demand := &fakeDemand{}
+	for range 4 {
+		demand.requests = append(demand.requests, heatmap.Request{ServiceID: "uit-bike", Lat: lat, Lng: lng, At: now.Add(-time.Minute)})
+	}
+	demand.requests = append(demand.requests,
+		heatmap.Request{ServiceID: "uit-car", Lat: lat, Lng: lng, At: now.Add(-time.Minute)},
+		heatmap.Request{ServiceID: "uit-bike", Lat: lat, Lng: lng, At: now.Add(-time.Hour)})
+
+	engine := NewEngine(supply, demand, settings.Defaults(),
+		WithSettings(settings),
+		WithBusyDrivers(fakeBusy{"busy": {}}),
+		WithConfig(Config{RefreshInterval: time.Nanosecond}))
+	engine.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Four pending bike requests for one free driver: 1 + 0.25 * (4 - 1).
+	multiplier, err := engine.SurgeMultiplier(ctx, "UIT-Bike", lat, lng)
+	require.NoError(t, err)
+	require.Equal(t, 1.7, multiplier)
+	multiplier, err = engine.SurgeMultiplier(ctx, "uit-car", lat, lng)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, multiplier)
+
+	// Demand clears; the price eases back over the half-life instead of dropping.
+	demand.requests = nil
+	now = now.Add(2 * time.Minute)
+	multiplier, err = engine.SurgeMultiplier(ctx, "uit-bike", lat, lng)
+	require.NoError(t, err)
+	require.Equal(t, 1.3, multiplier)
+
+	// Admins cap or switch off surge per service.
+	now = now.Add(time.Hour)
+	for range 20 {
+		demand.requests = append(demand.requests, heatmap.Request{ServiceID: "uit-bike", Lat: lat, Lng: lng, At: now})
+	}
+	multiplier, err = engine.SurgeMultiplier(ctx, "uit-bike", lat, lng)
+	require.NoError(t, err)
+	require.Equal(t, 2.0, multiplier, "the default cap")
+
+	require.NoError(t, settings.Set(ctx, "uit-bike", ServiceSettings{Enabled: true, MaxMultiplier: 1.5}))
+	multiplier, err = engine.SurgeMultiplier(ctx, "uit-bike", lat, lng)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, multiplier)
+
+	require.NoError(t, settings.Set(ctx, "uit-bike", ServiceSettings{Enabled: false, MaxMultiplier: 1.5}))
+	multiplier, err = engine.SurgeMultiplier(ctx, "uit-bike", lat, lng)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, multiplier)
+
+	require.ErrorIs(t, settings.Set(ctx, "uit-bike", ServiceSettings{Enabled: true, MaxMultiplier: 0.5}), ErrInvalidSettings)
+	overrides, err := settings.Overrides(ctx)
+	require.NoError(t, err)
+	require.Equal(t, ServiceSettings{Enabled: false, MaxMultiplier: 1.5}, overrides["uit-bike"])
+}
+
+func TestQuoteStoreExpiresQuotes(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewQuoteStore(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	ctx := context.Background()
+
+	quote := &domain.LockedQuote{Quote: domain.FareQuote{QuoteID: "q1", Total: 56750, SurgeMultiplier: 1.5}, RiderID: "rider-1"}
+	require.NoError(t, store.SaveQuote(ctx, quote, time.Minute))
+	loaded, err := store.TakeQuote(ctx, "q1")
+	require.NoError(t, err)
+	require.Equal(t, *quote, *loaded)
+	_, err = store.TakeQuote(ctx, "q1")
+	require.ErrorIs(t, err, domain.ErrQuoteExpired, "a quote is taken once")
+
+	require.NoError(t, store.SaveQuote(ctx, quote, time.Minute))
+	server.FastForward(2 * time.Minute)
+	_, err = store.TakeQuote(ctx, "q1")
+	require.ErrorIs(t, err, domain.ErrQuoteExpired)
+}
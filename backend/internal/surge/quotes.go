@@ -0,0 +1,80 @@
+package surge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"uitgo/backend/internal/domain"
+)
+
+const quoteKeyPrefix = "fare:quote:"
+
+// QuoteStore keeps issued fare quotes in Redis until they expire, so a rider can
+// book on any trip-service replica.
+type QuoteStore struct {
+	client *redis.Client
+}
+
+var _ domain.QuoteStore = (*QuoteStore)(nil)
+
+// NewQuoteStore connects to Redis.
+func NewQuoteStore(addr, password string, db int) (*QuoteStore, error) {
+	client, err := connect(addr, password, db)
+	if err != nil {
+		return nil, err
+	}
+	return &QuoteStore{client: client}, nil
+}
+
+// Close releases the Redis connection.
+func (q *QuoteStore) Close() error {
+	return q.client.Close()
+}
+
+// SaveQuote stores the quote for ttl.
+func (q *QuoteStore) SaveQuote(ctx context.Context, quote *domain.LockedQuote, ttl time.Duration) error {
+	payload, err := json.Marshal(quote)
+	if err != nil {
+		return err
+	}
+	if err := q.client.Set(ctx, quoteKeyPrefix+quote.Quote.QuoteID, payload, ttl).Err(); err != nil {
+		return fmt.Errorf("redis save quote: %w", err)
+	}
+	return nil
+}
+
+// TakeQuote reads and deletes the quote with GETDEL, so only one booking gets
+// it. It returns domain.ErrQuoteExpired once the quote is gone.
+func (q *QuoteStore) TakeQuote(ctx context.Context, id string) (*domain.LockedQuote, error) {
+	payload, err := q.client.GetDel(ctx, quoteKeyPrefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ErrQuoteExpired
+		}
+		return nil, fmt.Errorf("redis take quote: %w", err)
+	}
+	var quote domain.LockedQuote
+	if err := json.Unmarshal(payload, &quote); err != nil {
+		return nil, fmt.Errorf("decode quote %s: %w", id, err)
+	}
+	return &quote, nil
+}
+
+func connect(addr, password string, db int) (*redis.Client, error) {
+	if addr == "" {
+		return nil, errors.New("redis address required")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping redis: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,101 @@
+package surge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const settingsKey = "surge:settings"
+
+// ErrInvalidSettings indicates a cap below 1.
+var ErrInvalidSettings = errors.New("max multiplier must be at least 1")
+
+// ServiceSettings controls surge for one service.
+type ServiceSettings struct {
+	Enabled bool `json:"enabled"`
+	// MaxMultiplier caps the multiplier; 1 effectively disables surge.
+	MaxMultiplier float64 `json:"maxMultiplier"`
+}
+
+// Settings holds per-service overrides in a Redis hash so admins can change
+// them on every replica at once. Services without an override use the defaults.
+type Settings struct {
+	client   *redis.Client
+	defaults ServiceSettings
+}
+
+// NewSettings connects to Redis.
+func NewSettings(addr, password string, db int, defaults ServiceSettings) (*Settings, error) {
+	client, err := connect(addr, password, db)
+	if err != nil {
+		return nil, err
+	}
+	return &Settings{client: client, defaults: defaults}, nil
+}
+
+// Close releases the Redis connection.
+func (s *Settings) Close() error {
+	return s.client.Close()
+}
+
+// Defaults returns the settings of services without an override.
+func (s *Settings) Defaults() ServiceSettings {
+	return s.defaults
+}
+
+// Get returns the settings for a service.
+func (s *Settings) Get(ctx context.Context, serviceID string) (ServiceSettings, error) {
+	payload, err := s.client.HGet(ctx, settingsKey, normalizeService(serviceID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return s.defaults, nil
+		}
+		return s.defaults, fmt.Errorf("redis surge settings: %w", err)
+	}
+	settings := s.defaults
+	if err := json.Unmarshal(payload, &settings); err != nil {
+		return s.defaults, fmt.Errorf("decode surge settings: %w", err)
+	}
+	return settings, nil
+}
+
+// Set overrides the settings for a service.
+func (s *Settings) Set(ctx context.Context, serviceID string, settings ServiceSettings) error {
+	if settings.MaxMultiplier < 1 {
+		return ErrInvalidSettings
+	}
+	payload, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := s.client.HSet(ctx, settingsKey, normalizeService(serviceID), payload).Err(); err != nil {
+		return fmt.Errorf("redis save surge settings: %w", err)
+	}
+	return nil
+}
+
+// Overrides lists the services with their own settings.
+func (s *Settings) Overrides(ctx context.Context) (map[string]ServiceSettings, error) {
+	raw, err := s.client.HGetAll(ctx, settingsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis surge settings: %w", err)
+	}
+	overrides := make(map[string]ServiceSettings, len(raw))
+	for serviceID, payload := range raw {
+		settings := s.defaults
+		if err := json.Unmarshal([]byte(payload), &settings); err != nil {
+			continue
+		}
+		overrides[serviceID] = settings
+	}
+	return overrides, nil
+}
+
+func normalizeService(serviceID string) string {
+	return strings.ToLower(strings.TrimSpace(serviceID))
+}
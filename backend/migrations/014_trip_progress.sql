@@ -0,0 +1,7 @@
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS final_fare BIGINT,
+    ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'VND',
+    ADD COLUMN IF NOT EXISTS route_distance_meters DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS route_duration_seconds DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
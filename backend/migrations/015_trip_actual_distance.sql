@@ -0,0 +1,3 @@
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS actual_distance_meters DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS fare_basis TEXT;
@@ -0,0 +1,5 @@
+-- seq keeps events recorded at the same instant in insertion order.
+ALTER TABLE trip_events
+    ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+
+CREATE INDEX IF NOT EXISTS idx_trip_events_trip_seq ON trip_events (trip_id, created_at, seq);
@@ -13,6 +13,7 @@ import (
 	"uitgo/backend/internal/logging"
 	"uitgo/backend/internal/matching"
 	"uitgo/backend/internal/observability"
+	"uitgo/backend/internal/realtime"
 	"uitgo/backend/trip_service/internal/clients"
 	"uitgo/backend/trip_service/internal/server"
 )
@@ -91,6 +92,17 @@ func main() {
 		defer queue.Close()
 	}
 
+	var backplane realtime.Backplane = realtime.NewMemoryBackplane(cfg.HubReplayBuffer)
+	if cfg.HubBackplane == "redis" {
+		redisBackplane, err := realtime.NewRedisBackplane(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, "", cfg.HubReplayBuffer)
+		if err != nil {
+			log.Printf("warn: unable to initialize websocket backplane, broadcasts stay on this replica: %v", err)
+		} else {
+			backplane = redisBackplane
+			defer redisBackplane.Close()
+		}
+	}
+
 	var walletOps domain.WalletOperations
 	if cfg.UserServiceURL != "" {
 		walletOps = clients.NewWalletClient(cfg.UserServiceURL, cfg.InternalAPIKey)
@@ -98,7 +110,7 @@ func main() {
 		log.Printf("warn: user service url not configured; wallet enforcement disabled")
 	}
 
-	srv, err := server.New(cfg, pool, readDB, locationWriter, dispatcher, walletOps)
+	srv, err := server.New(cfg, pool, readDB, locationWriter, dispatcher, walletOps, backplane)
 	if err != nil {
 		log.Fatalf("init server: %v", err)
 	}
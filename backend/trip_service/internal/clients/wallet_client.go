@@ -38,20 +38,24 @@ func NewWalletClient(baseURL, apiKey string) *WalletClient {
 
 var _ domain.WalletOperations = (*WalletClient)(nil)
 
-func (c *WalletClient) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string) (int64, error) {
+func (c *WalletClient) EnsureBalanceForTrip(ctx context.Context, userID, serviceID string, fare int64) (int64, error) {
 	summary, err := c.fetchSummary(ctx, userID)
 	if err != nil {
 		return 0, err
 	}
-	fare := c.fareForService(serviceID)
+	if fare <= 0 {
+		fare = c.fareForService(serviceID)
+	}
 	if summary.Balance < fare {
 		return fare, domain.ErrWalletInsufficientFunds
 	}
 	return fare, nil
 }
 
-func (c *WalletClient) DeductTripFare(ctx context.Context, userID, serviceID string) (*domain.WalletSummary, int64, error) {
-	fare := c.fareForService(serviceID)
+func (c *WalletClient) DeductTripFare(ctx context.Context, userID, serviceID string, fare int64) (*domain.WalletSummary, int64, error) {
+	if fare <= 0 {
+		fare = c.fareForService(serviceID)
+	}
 	summary, err := c.applyTransaction(ctx, userID, fare, domain.WalletTransactionTypeDeduction)
 	return summary, fare, err
 }
@@ -65,6 +69,10 @@ func (c *WalletClient) RewardTripCompletion(ctx context.Context, userID string)
 	return summary, c.cfg.RewardPointsPerTrip, err
 }
 
+func (c *WalletClient) ChargeCancellationFee(ctx context.Context, userID string, fee int64) (*domain.WalletSummary, error) {
+	return c.applyTransaction(ctx, userID, fee, domain.WalletTransactionTypeDeduction)
+}
+
 func (c *WalletClient) fetchSummary(ctx context.Context, userID string) (*domain.WalletSummary, error) {
 	if c == nil || c.baseURL == "" {
 		return nil, errors.New("wallet service url not configured")
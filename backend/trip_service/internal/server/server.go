@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,22 +17,27 @@ import (
 	"uitgo/backend/internal/config"
 	dbrepo "uitgo/backend/internal/db"
 	"uitgo/backend/internal/domain"
+	"uitgo/backend/internal/heatmap"
 	"uitgo/backend/internal/http/handlers"
 	"uitgo/backend/internal/http/middleware"
+	"uitgo/backend/internal/location"
 	"uitgo/backend/internal/matching"
 	"uitgo/backend/internal/notification"
 	"uitgo/backend/internal/observability"
+	"uitgo/backend/internal/realtime"
 	"uitgo/backend/internal/routing"
+	"uitgo/backend/internal/surge"
 )
 
 // Server represents the trip-service HTTP server.
 type Server struct {
-	engine *gin.Engine
-	cfg    *config.Config
+	engine          *gin.Engine
+	cfg             *config.Config
+	schedulerCancel context.CancelFunc
 }
 
 // New constructs the HTTP server with trip routes and internal hooks.
-func New(cfg *config.Config, db *gorm.DB, readDB *gorm.DB, driverLocations handlers.DriverLocationWriter, dispatcher matching.TripDispatcher, wallets domain.WalletOperations) (*Server, error) {
+func New(cfg *config.Config, db *gorm.DB, readDB *gorm.DB, driverLocations handlers.DriverLocationWriter, dispatcher matching.TripDispatcher, wallets domain.WalletOperations, backplane realtime.Backplane) (*Server, error) {
 	const serviceName = "trip-service"
 	router := gin.New()
 	gin.DisableConsoleColor()
@@ -49,7 +55,10 @@ func New(cfg *config.Config, db *gorm.DB, readDB *gorm.DB, driverLocations handl
 	router.Use(middleware.AuditLogger(auditRepo))
 
 	handlers.RegisterHealth(router)
-	routeProvider := routing.NewClient(cfg.RoutingBaseURL, 8*time.Second, 5*time.Minute)
+	routeProvider, err := routing.NewProviderFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init routing provider: %w", err)
+	}
 	handlers.RegisterRouteRoutes(router, routeProvider)
 	// Increased from 10 to 1000 for load testing
 	tripLimiter := middleware.NewTokenBucketRateLimiter(1000, time.Minute)
@@ -62,23 +71,180 @@ func New(cfg *config.Config, db *gorm.DB, readDB *gorm.DB, driverLocations handl
 		log.Printf("warn: unable to initialize FCM: %v", err)
 	}
 	notificationSvc := notification.NewService(notificationRepo, deviceTokenRepo, pushSender)
-	tripService := domain.NewTripService(tripRepo, wallets, notificationSvc)
-	hubManager := handlers.NewHubManager(tripService, driverLocations)
+	settlement := domain.DefaultFareSettlementConfig()
+	settlement.DistanceTolerance = cfg.FareDistanceTolerance
+	surgePricer, quotes, surgeSettings := createSurgePricing(cfg, tripRepo)
+	serviceAreas := domain.NewServiceAreaCatalog(dbrepo.NewServiceAreaRepository(db), cfg.ServiceAreaRefresh)
+	tripService := domain.NewTripService(tripRepo, wallets, notificationSvc,
+		domain.WithFareEstimator(domain.NewFareEstimator(routeProvider, domain.WithFareRules(cfg.FareRules))),
+		domain.WithFareSettlement(settlement),
+		domain.WithCancellationPolicy(domain.CancellationPolicy{GracePeriod: cfg.CancellationGrace, Fee: cfg.CancellationFee}),
+		domain.WithSchedule(domain.ScheduleConfig{MaxAhead: cfg.ScheduleMaxAhead, LeadTime: cfg.ScheduleLeadTime}),
+		domain.WithGeofence(domain.GeofenceConfig{
+			PickupRadiusMeters:      cfg.GeofencePickupRadius,
+			DestinationRadiusMeters: cfg.GeofenceDestRadius,
+			ExitMarginMeters:        cfg.GeofenceExitMargin,
+			ConfirmPings:            cfg.GeofenceConfirmPings,
+		}),
+		domain.WithLiveETA(routeProvider, domain.ETAConfig{MinInterval: cfg.ETARefreshInterval}),
+		domain.WithTrailMatching(routeProvider),
+		domain.WithSurgePricing(surgePricer),
+		domain.WithQuoteStore(quotes, cfg.SurgeQuoteTTL),
+		domain.WithServiceAreas(serviceAreas),
+	)
+	hubManager := handlers.NewHubManager(tripService, driverLocations, handlers.WithBackplane(backplane))
 
 	handlers.RegisterTripRoutes(router, tripService, nil, hubManager, dispatcher, tripLimiter.Middleware("trip_create"))
+	handlers.RegisterFareRoutes(router, tripService)
+	handlers.RegisterTripTrackRoutes(router, tripService, nil, routeProvider)
 	registerInternalRoutes(router, cfg, tripService, hubManager)
 
+	adminGroup := router.Group("/admin")
+	adminGroup.Use(middleware.RequireRoles("admin"))
+	handlers.RegisterSurgeAdminRoutes(adminGroup, surgeSettings)
+	handlers.RegisterServiceAreaAdminRoutes(adminGroup, serviceAreas)
+
 	metrics.Expose(router)
 
-	return &Server{engine: router, cfg: cfg}, nil
+	ctx, cancel := context.WithCancel(context.Background())
+	go releaseScheduledTrips(ctx, tripService, dispatcher, cfg.SchedulerInterval)
+	go chargePendingFares(ctx, tripService, cfg.SchedulerInterval)
+
+	return &Server{engine: router, cfg: cfg, schedulerCancel: cancel}, nil
 }
 
 // Run starts serving HTTP requests.
 func (s *Server) Run() error {
 	addr := fmt.Sprintf(":%s", s.cfg.Port)
+	defer func() {
+		if s.schedulerCancel != nil {
+			s.schedulerCancel()
+		}
+	}()
 	return s.engine.Run(addr)
 }
 
+// createSurgePricing prices surge from the driver GEO index and the trip request
+// log the driver service keeps, and holds fare quotes in Redis so any replica
+// can book them. Whatever cannot reach Redis is left out: quotes fall back to
+// memory and fares to no surge.
+func createSurgePricing(cfg *config.Config, trips domain.TripRepository) (domain.SurgePricer, domain.QuoteStore, *surge.Settings) {
+	var quotes domain.QuoteStore
+	if store, err := surge.NewQuoteStore(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB); err != nil {
+		log.Printf("warn: fare quotes held in memory: %v", err)
+	} else {
+		quotes = store
+	}
+	if !cfg.SurgeEnabled {
+		return nil, quotes, nil
+	}
+	defaults := surge.ServiceSettings{Enabled: true, MaxMultiplier: cfg.SurgeMaxMultiplier}
+	settings, err := surge.NewSettings(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, defaults)
+	if err != nil {
+		log.Printf("warn: surge pricing disabled: %v", err)
+		return nil, quotes, nil
+	}
+	supply, err := location.NewGeoIndex(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, "driver")
+	if err != nil {
+		log.Printf("warn: surge pricing disabled: %v", err)
+		return nil, quotes, nil
+	}
+	demand, err := heatmap.NewDemandLog(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, cfg.HeatmapRetention)
+	if err != nil {
+		log.Printf("warn: surge pricing disabled: %v", err)
+		return nil, quotes, nil
+	}
+	options := []surge.Option{
+		surge.WithSettings(settings),
+		surge.WithConfig(surge.Config{DemandWindow: cfg.SurgeDemandWindow, StaleAfter: cfg.DriverStaleAfter}),
+	}
+	if busy, ok := trips.(surge.BusyDrivers); ok {
+		options = append(options, surge.WithBusyDrivers(busy))
+	}
+	return surge.NewEngine(supply, demand, defaults, options...), quotes, settings
+}
+
+// releaseScheduledTrips periodically hands scheduled trips whose pickup is
+// within the lead time over to dispatch. Trips that cannot be queued go back to
+// scheduled and are retried on the next tick. Without a dispatcher the released
+// trips are left requested, like trips booked for now.
+func releaseScheduledTrips(ctx context.Context, trips *domain.TripService, dispatcher matching.TripDispatcher, interval time.Duration) {
+	if trips == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Println("scheduled trip release loop started")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now().UTC()
+		released, err := trips.ReleaseDueTrips(ctx, now, 100)
+		if err != nil {
+			log.Printf("release scheduled trips failed: %v", err)
+			continue
+		}
+		if dispatcher == nil {
+			continue
+		}
+		for _, trip := range released {
+			event := &matching.TripEvent{
+				TripID:     trip.ID,
+				RiderID:    trip.RiderID,
+				ServiceID:  trip.ServiceID,
+				OriginText: trip.OriginText,
+				DestText:   trip.DestText,
+				Requested:  now,
+				OriginLat:  trip.OriginLat,
+				OriginLng:  trip.OriginLng,
+			}
+			if err := dispatcher.Publish(ctx, event); err != nil {
+				log.Printf("dispatch scheduled trip %s failed: %v", trip.ID, err)
+				// Put it back so the next tick retries the release.
+				if err := trips.UnreleaseTrip(ctx, trip.ID, "dispatch queue unavailable"); err != nil {
+					log.Printf("return trip %s to the schedule failed: %v", trip.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// chargePendingFares periodically retries billing completed trips whose fare
+// charge failed when they were completed.
+func chargePendingFares(ctx context.Context, trips *domain.TripService, interval time.Duration) {
+	if trips == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		charged, err := trips.ChargePendingFares(ctx, 100)
+		if err != nil {
+			log.Printf("charge pending fares failed: %v", err)
+			continue
+		}
+		if charged > 0 {
+			log.Printf("charged %d pending trip fares", charged)
+		}
+	}
+}
+
 func registerInternalRoutes(router gin.IRouter, cfg *config.Config, trips *domain.TripService, hubs *handlers.HubManager) {
 	group := router.Group("/internal")
 	group.Use(middleware.InternalOnly(cfg.InternalAPIKey))
@@ -125,6 +291,69 @@ func registerInternalRoutes(router gin.IRouter, cfg *config.Config, trips *domai
 		c.Status(http.StatusNoContent)
 	})
 
+	group.PATCH("/trips/:id/progress", func(c *gin.Context) {
+		tripID := c.Param("id")
+		var req domain.TripProgress
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := trips.SaveProgress(c.Request.Context(), tripID, req); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, domain.ErrTripNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	group.POST("/trips/:id/events", func(c *gin.Context) {
+		var event domain.TripEvent
+		if err := c.ShouldBindJSON(&event); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		event.TripID = c.Param("id")
+		if err := trips.RecordEvent(c.Request.Context(), event); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	group.POST("/trips/:id/stops/:index/arrive", func(c *gin.Context) {
+		tripID := c.Param("id")
+		index, err := strconv.Atoi(c.Param("index"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stop index must be a number"})
+			return
+		}
+		var req struct {
+			ArrivedAt time.Time `json:"arrivedAt"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		trip, err := trips.ReachStop(c.Request.Context(), tripID, index, req.ArrivedAt)
+		if err != nil {
+			statusCode := http.StatusBadRequest
+			if errors.Is(err, domain.ErrTripNotFound) || errors.Is(err, domain.ErrStopNotFound) {
+				statusCode = http.StatusNotFound
+			} else if errors.Is(err, domain.ErrStopAlreadyReached) || errors.Is(err, domain.ErrStopOutOfOrder) || errors.Is(err, domain.ErrInvalidTransition) {
+				statusCode = http.StatusConflict
+			}
+			c.JSON(statusCode, gin.H{"error": err.Error()})
+			return
+		}
+		if stop, ok := trip.Stop(index); ok && hubs != nil {
+			hubs.BroadcastStopArrived(tripID, *stop)
+		}
+		c.Status(http.StatusNoContent)
+	})
+
 	group.DELETE("/trips", func(c *gin.Context) {
 		if err := trips.PurgeAll(c.Request.Context()); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -136,19 +365,34 @@ func registerInternalRoutes(router gin.IRouter, cfg *config.Config, trips *domai
 	group.POST("/trips/:id/status", func(c *gin.Context) {
 		tripID := c.Param("id")
 		var req struct {
-			Status string `json:"status" binding:"required"`
+			Status  string `json:"status" binding:"required"`
+			From    string `json:"from"`
+			Actor   string `json:"actor"`
+			ActorID string `json:"actorId"`
+			Reason  string `json:"reason"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		status := domain.TripStatus(strings.ToLower(strings.TrimSpace(req.Status)))
-		if err := trips.UpdateStatus(c.Request.Context(), tripID, status); err != nil {
+		change := domain.TripStatusChange{
+			From:    domain.TripStatus(strings.ToLower(strings.TrimSpace(req.From))),
+			To:      status,
+			Actor:   domain.TransitionActor(strings.ToLower(strings.TrimSpace(req.Actor))),
+			ActorID: strings.TrimSpace(req.ActorID),
+			Reason:  strings.TrimSpace(req.Reason),
+		}
+		if _, err := trips.Transition(c.Request.Context(), tripID, change); err != nil {
 			statusCode := http.StatusBadRequest
 			if errors.Is(err, domain.ErrTripNotFound) {
 				statusCode = http.StatusNotFound
 			} else if errors.Is(err, domain.ErrInvalidStatus) {
 				statusCode = http.StatusBadRequest
+			} else if errors.Is(err, domain.ErrInvalidTransition) {
+				statusCode = http.StatusConflict
+			} else if errors.Is(err, domain.ErrTransitionNotPermitted) {
+				statusCode = http.StatusForbidden
 			} else if errors.Is(err, domain.ErrWalletInsufficientFunds) {
 				statusCode = http.StatusPaymentRequired
 			}
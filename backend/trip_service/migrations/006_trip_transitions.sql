@@ -0,0 +1,3 @@
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS cancellation_fee BIGINT;
@@ -0,0 +1,4 @@
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_trips_scheduled ON trips (scheduled_at) WHERE status = 'scheduled';
@@ -0,0 +1,2 @@
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS surge_multiplier DOUBLE PRECISION;
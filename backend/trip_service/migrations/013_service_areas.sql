@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS service_areas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    polygon JSONB NOT NULL,
+    surcharge BIGINT NOT NULL DEFAULT 0,
+    pickup_points JSONB NOT NULL DEFAULT '[]'::jsonb,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_areas_service
+    ON service_areas (service_id);
+
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS zone_surcharge BIGINT;
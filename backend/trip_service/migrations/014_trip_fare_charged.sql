@@ -0,0 +1,9 @@
+ALTER TABLE trips
+    ADD COLUMN IF NOT EXISTS fare_charged_at TIMESTAMPTZ;
+
+-- Trips completed before charges were tracked were billed on completion.
+UPDATE trips
+SET fare_charged_at = COALESCE(completed_at, updated_at)
+WHERE status = 'completed' AND fare_charged_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_trips_fare_uncharged ON trips (completed_at) WHERE status = 'completed' AND fare_charged_at IS NULL;
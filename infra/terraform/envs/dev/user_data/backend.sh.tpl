@@ -317,6 +317,14 @@ server {
     proxy_set_header X-Forwarded-Proto $scheme;
   }
 
+  location ^~ /v1/fares {
+    proxy_pass http://trip_service;
+    proxy_set_header Host $host;
+    proxy_set_header X-Real-IP $remote_addr;
+    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+    proxy_set_header X-Forwarded-Proto $scheme;
+  }
+
   # Internal maintenance endpoints (dev/demo only)
   location ^~ /internal/trips {
     proxy_pass http://trip_service;